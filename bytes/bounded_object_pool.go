@@ -0,0 +1,447 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxbytes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultMaxIdle = 8
+
+var (
+	// ErrObjectPoolClosed is returned by Borrow if the pool is closed
+	ErrObjectPoolClosed = errors.New("object pool: closed")
+	// ErrObjectPoolExhausted is returned by Borrow if no object is returned
+	// within the borrow timeout
+	ErrObjectPoolExhausted = errors.New("object pool: exhausted")
+)
+
+// BoundedObjectPoolOption configures a BoundedObjectPool
+type BoundedObjectPoolOption func(*boundedObjectPoolOptions)
+
+type boundedObjectPoolOptions struct {
+	minIdle          int
+	maxIdle          int
+	maxTotal         int
+	maxLifetime      time.Duration
+	borrowTimeout    time.Duration
+	evictionInterval time.Duration
+	validate         func(PoolObject) bool
+	destroy          func(PoolObject)
+}
+
+// WithMinIdle keeps @n idle objects at least, they are created by
+// NewBoundedObjectPool and the evictor
+func WithMinIdle(n int) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.minIdle = n
+	}
+}
+
+// WithMaxIdle keeps @n idle objects at most, the returned objects beyond it
+// are destroyed. It is 8 by default.
+func WithMaxIdle(n int) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.maxIdle = n
+	}
+}
+
+// WithMaxTotal limits the number of the idle and borrowed objects to @n,
+// Borrow waits if the limit is reached. 0 means no limit.
+func WithMaxTotal(n int) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.maxTotal = n
+	}
+}
+
+// WithMaxLifetime destroys the objects created @d ago instead of lending or
+// keeping them. 0 means no limit.
+func WithMaxLifetime(d time.Duration) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.maxLifetime = d
+	}
+}
+
+// WithBorrowTimeout makes Borrow return ErrObjectPoolExhausted if it waits
+// longer than @d
+func WithBorrowTimeout(d time.Duration) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.borrowTimeout = d
+	}
+}
+
+// WithEvictionInterval runs the evictor every @d, which destroys the expired
+// idle objects and creates the idle objects below the min idle
+func WithEvictionInterval(d time.Duration) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.evictionInterval = d
+	}
+}
+
+// WithValidator checks the idle objects by @validate before lending them,
+// the invalid ones are destroyed
+func WithValidator(validate func(PoolObject) bool) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.validate = validate
+	}
+}
+
+// WithDestroyer releases the resources of the destroyed objects by @destroy
+func WithDestroyer(destroy func(PoolObject)) BoundedObjectPoolOption {
+	return func(o *boundedObjectPoolOptions) {
+		o.destroy = destroy
+	}
+}
+
+// ObjectPoolStats is the statistics of a BoundedObjectPool
+type ObjectPoolStats struct {
+	Idle               int
+	Active             int // the number of the borrowed objects
+	Created            uint64
+	Destroyed          uint64
+	Borrowed           uint64
+	Returned           uint64
+	ValidationFailures uint64
+	Waits              uint64 // the number of Borrow waiting for an object
+	WaitTimeouts       uint64
+	WaitDuration       time.Duration
+}
+
+type pooledObject struct {
+	obj       PoolObject
+	createdAt time.Time
+}
+
+// BoundedObjectPool is an object pool holding the objects until they are
+// destroyed, unlike ObjectPool whose objects may be collected by the GC at
+// any time. It suits the expensive objects like the codecs with large
+// buffers. The objects should be comparable, eg: pointers.
+type BoundedObjectPool struct {
+	new  New
+	opts boundedObjectPoolOptions
+
+	lock     sync.Mutex
+	idle     []*pooledObject // the last one is the most recently returned
+	borrowed map[PoolObject]*pooledObject
+	total    int
+	closed   bool
+	// changed is closed and renewed to wake up the waiters of Borrow once an
+	// object is returned or destroyed
+	changed chan struct{}
+	done    chan struct{}
+	stats   ObjectPoolStats
+}
+
+// NewBoundedObjectPool creates a BoundedObjectPool whose objects are created by @n
+func NewBoundedObjectPool(n New, opts ...BoundedObjectPoolOption) *BoundedObjectPool {
+	options := boundedObjectPoolOptions{maxIdle: defaultMaxIdle}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxIdle < 0 {
+		options.maxIdle = 0
+	}
+	if options.minIdle > options.maxIdle {
+		options.minIdle = options.maxIdle
+	}
+	if options.maxTotal > 0 && options.minIdle > options.maxTotal {
+		options.minIdle = options.maxTotal
+	}
+
+	p := &BoundedObjectPool{
+		new:      n,
+		opts:     options,
+		borrowed: make(map[PoolObject]*pooledObject),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.ensureMinIdle()
+	if options.evictionInterval > 0 {
+		go p.evictLoop()
+	}
+	return p
+}
+
+// notify should be called with the lock held
+func (p *BoundedObjectPool) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *BoundedObjectPool) expired(po *pooledObject, now time.Time) bool {
+	return p.opts.maxLifetime > 0 && now.Sub(po.createdAt) >= p.opts.maxLifetime
+}
+
+// destroy releases @objs, it should be called without the lock
+func (p *BoundedObjectPool) destroy(objs ...PoolObject) {
+	if p.opts.destroy == nil {
+		return
+	}
+	for _, obj := range objs {
+		p.opts.destroy(obj)
+	}
+}
+
+// Borrow gets an object from the pool, it creates a new one if there is no
+// idle object, and waits for an object to be returned if the max total is
+// reached until @ctx is done or the borrow timeout.
+func (p *BoundedObjectPool) Borrow(ctx context.Context) (PoolObject, error) {
+	waitCtx := ctx
+	if p.opts.borrowTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, p.opts.borrowTimeout)
+		defer cancel()
+	}
+
+	var waitStart time.Time
+	p.lock.Lock()
+	for {
+		if p.closed {
+			p.lock.Unlock()
+			return nil, ErrObjectPoolClosed
+		}
+
+		if n := len(p.idle); n > 0 {
+			po := p.idle[n-1]
+			p.idle[n-1] = nil
+			p.idle = p.idle[:n-1]
+			p.borrowed[po.obj] = po
+			p.lock.Unlock()
+
+			valid := !p.expired(po, time.Now())
+			validationFailed := false
+			if valid && p.opts.validate != nil && !p.opts.validate(po.obj) {
+				valid, validationFailed = false, true
+			}
+
+			p.lock.Lock()
+			if valid {
+				p.recordBorrow(waitStart)
+				p.lock.Unlock()
+				return po.obj, nil
+			}
+			if validationFailed {
+				p.stats.ValidationFailures++
+			}
+			p.removeBorrowed(po.obj)
+			p.lock.Unlock()
+			p.destroy(po.obj)
+			p.lock.Lock()
+			continue
+		}
+
+		if p.opts.maxTotal <= 0 || p.total < p.opts.maxTotal {
+			p.total++
+			p.lock.Unlock()
+			po := &pooledObject{obj: p.new(), createdAt: time.Now()}
+			p.lock.Lock()
+			p.stats.Created++
+			p.borrowed[po.obj] = po
+			p.recordBorrow(waitStart)
+			p.lock.Unlock()
+			return po.obj, nil
+		}
+
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+			p.stats.Waits++
+		}
+		changed := p.changed
+		p.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-waitCtx.Done():
+			p.lock.Lock()
+			p.stats.WaitTimeouts++
+			p.stats.WaitDuration += time.Since(waitStart)
+			p.lock.Unlock()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, ErrObjectPoolExhausted
+		}
+		p.lock.Lock()
+	}
+}
+
+// recordBorrow should be called with the lock held
+func (p *BoundedObjectPool) recordBorrow(waitStart time.Time) {
+	p.stats.Borrowed++
+	if !waitStart.IsZero() {
+		p.stats.WaitDuration += time.Since(waitStart)
+	}
+}
+
+// removeBorrowed should be called with the lock held
+func (p *BoundedObjectPool) removeBorrowed(obj PoolObject) {
+	delete(p.borrowed, obj)
+	p.total--
+	p.stats.Destroyed++
+	p.notify()
+}
+
+// Return resets @obj and puts it back to the pool, it is destroyed if the
+// pool is closed, the object is expired or the idle objects reach the max.
+// The object which is not borrowed from the pool is ignored without reset,
+// as it may be used by others, eg: it has been returned.
+func (p *BoundedObjectPool) Return(obj PoolObject) {
+	p.lock.Lock()
+	po, ok := p.borrowed[obj]
+	if !ok {
+		p.lock.Unlock()
+		return
+	}
+	p.stats.Returned++
+	if p.closed || p.expired(po, time.Now()) || len(p.idle) >= p.opts.maxIdle {
+		p.removeBorrowed(obj)
+		p.lock.Unlock()
+		p.destroy(obj)
+		return
+	}
+	// reset it before it can be borrowed again
+	obj.Reset()
+	delete(p.borrowed, obj)
+	p.idle = append(p.idle, po)
+	p.notify()
+	p.lock.Unlock()
+}
+
+// Invalidate destroys the broken object @obj instead of returning it
+func (p *BoundedObjectPool) Invalidate(obj PoolObject) {
+	p.lock.Lock()
+	if _, ok := p.borrowed[obj]; !ok {
+		p.lock.Unlock()
+		return
+	}
+	p.removeBorrowed(obj)
+	p.lock.Unlock()
+	p.destroy(obj)
+}
+
+// Stats returns the statistics of the pool
+func (p *BoundedObjectPool) Stats() ObjectPoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	stats := p.stats
+	stats.Idle = len(p.idle)
+	stats.Active = len(p.borrowed)
+	return stats
+}
+
+// Close destroys the idle objects and wakes up the waiters of Borrow, the
+// borrowed objects are destroyed once they are returned.
+func (p *BoundedObjectPool) Close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	idle := p.idle
+	p.idle = nil
+	p.total -= len(idle)
+	p.stats.Destroyed += uint64(len(idle))
+	p.notify()
+	p.lock.Unlock()
+
+	for _, po := range idle {
+		p.destroy(po.obj)
+	}
+}
+
+func (p *BoundedObjectPool) evictLoop() {
+	ticker := time.NewTicker(p.opts.evictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.evict()
+			p.ensureMinIdle()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// evict destroys the expired idle objects
+func (p *BoundedObjectPool) evict() {
+	if p.opts.maxLifetime <= 0 {
+		return
+	}
+
+	now := time.Now()
+	var expired []PoolObject
+	p.lock.Lock()
+	idle := p.idle[:0]
+	for _, po := range p.idle {
+		if p.expired(po, now) {
+			expired = append(expired, po.obj)
+			continue
+		}
+		idle = append(idle, po)
+	}
+	for i := len(idle); i < len(p.idle); i++ {
+		p.idle[i] = nil
+	}
+	p.idle = idle
+	if len(expired) > 0 {
+		p.total -= len(expired)
+		p.stats.Destroyed += uint64(len(expired))
+		p.notify()
+	}
+	p.lock.Unlock()
+
+	p.destroy(expired...)
+}
+
+// ensureMinIdle creates the idle objects until the min idle is reached
+func (p *BoundedObjectPool) ensureMinIdle() {
+	for {
+		p.lock.Lock()
+		if p.closed || len(p.idle) >= p.opts.minIdle ||
+			(p.opts.maxTotal > 0 && p.total >= p.opts.maxTotal) {
+			p.lock.Unlock()
+			return
+		}
+		p.total++
+		p.lock.Unlock()
+
+		po := &pooledObject{obj: p.new(), createdAt: time.Now()}
+
+		p.lock.Lock()
+		p.stats.Created++
+		if p.closed {
+			p.total--
+			p.stats.Destroyed++
+			p.lock.Unlock()
+			p.destroy(po.obj)
+			return
+		}
+		// the recently returned objects are lent first
+		p.idle = append([]*pooledObject{po}, p.idle...)
+		p.notify()
+		p.lock.Unlock()
+	}
+}
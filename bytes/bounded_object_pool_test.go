@@ -0,0 +1,219 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxbytes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type testObject struct {
+	id     int
+	resets int
+	broken bool
+}
+
+func (o *testObject) Reset() {
+	o.resets++
+}
+
+type testObjectFactory struct {
+	created   int32
+	destroyed int32
+}
+
+func (f *testObjectFactory) new() PoolObject {
+	return &testObject{id: int(atomic.AddInt32(&f.created, 1))}
+}
+
+func (f *testObjectFactory) destroy(PoolObject) {
+	atomic.AddInt32(&f.destroyed, 1)
+}
+
+func TestBoundedObjectPool(t *testing.T) {
+	f := &testObjectFactory{}
+	p := NewBoundedObjectPool(f.new, WithMinIdle(2), WithMaxIdle(2), WithDestroyer(f.destroy))
+	defer p.Close()
+	assert.Equal(t, 2, p.Stats().Idle)
+
+	o1, err := p.Borrow(context.Background())
+	assert.Nil(t, err)
+	o2, _ := p.Borrow(context.Background())
+	o3, _ := p.Borrow(context.Background())
+	assert.Equal(t, 3, o3.(*testObject).id)
+	stats := p.Stats()
+	assert.Equal(t, 0, stats.Idle)
+	assert.Equal(t, 3, stats.Active)
+	assert.Equal(t, uint64(3), stats.Created)
+
+	p.Return(o1)
+	p.Return(o2)
+	// the idle objects reach the max
+	p.Return(o3)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&f.destroyed))
+	assert.Equal(t, 1, o1.(*testObject).resets)
+
+	// the unknown and the returned objects are ignored without reset
+	unknown := &testObject{}
+	p.Return(unknown)
+	assert.Equal(t, 0, unknown.resets)
+	p.Return(o1)
+	assert.Equal(t, 1, o1.(*testObject).resets)
+	stats = p.Stats()
+	assert.Equal(t, 2, stats.Idle)
+	assert.Equal(t, 0, stats.Active)
+	assert.Equal(t, uint64(3), stats.Borrowed)
+	assert.Equal(t, uint64(3), stats.Returned)
+	assert.Equal(t, uint64(1), stats.Destroyed)
+
+	// the recently returned object is lent first
+	o, _ := p.Borrow(context.Background())
+	assert.Equal(t, o2, o)
+	p.Invalidate(o)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&f.destroyed))
+	assert.Equal(t, 1, p.Stats().Idle)
+}
+
+func TestBoundedObjectPoolMaxTotal(t *testing.T) {
+	f := &testObjectFactory{}
+	p := NewBoundedObjectPool(f.new, WithMaxTotal(1), WithBorrowTimeout(20*time.Millisecond))
+
+	o1, err := p.Borrow(context.Background())
+	assert.Nil(t, err)
+	_, err = p.Borrow(context.Background())
+	assert.Equal(t, ErrObjectPoolExhausted, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.Borrow(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	// the waiter gets the returned object
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		p.Return(o1)
+	}()
+	o2, err := p.Borrow(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, o1, o2)
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(3), stats.Waits)
+	assert.Equal(t, uint64(2), stats.WaitTimeouts)
+	assert.True(t, stats.WaitDuration > 0)
+	assert.Equal(t, uint64(1), stats.Created)
+
+	// Close wakes up the waiters
+	p = NewBoundedObjectPool(f.new, WithMaxTotal(1))
+	_, _ = p.Borrow(context.Background())
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		p.Close()
+	}()
+	_, err = p.Borrow(context.Background())
+	assert.Equal(t, ErrObjectPoolClosed, err)
+}
+
+func TestBoundedObjectPoolValidation(t *testing.T) {
+	f := &testObjectFactory{}
+	p := NewBoundedObjectPool(f.new,
+		WithValidator(func(o PoolObject) bool {
+			return !o.(*testObject).broken
+		}),
+		WithDestroyer(f.destroy),
+	)
+	defer p.Close()
+
+	o1, _ := p.Borrow(context.Background())
+	o2, _ := p.Borrow(context.Background())
+	o1.(*testObject).broken = true
+	p.Return(o2)
+	p.Return(o1)
+
+	o, err := p.Borrow(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, o2, o)
+	assert.Equal(t, uint64(1), p.Stats().ValidationFailures)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&f.destroyed))
+}
+
+func TestBoundedObjectPoolLifetime(t *testing.T) {
+	f := &testObjectFactory{}
+	p := NewBoundedObjectPool(f.new,
+		WithMinIdle(2),
+		WithMaxLifetime(30*time.Millisecond),
+		WithEvictionInterval(10*time.Millisecond),
+		WithDestroyer(f.destroy),
+	)
+
+	o, _ := p.Borrow(context.Background())
+	time.Sleep(40 * time.Millisecond)
+	// the expired object is destroyed on return
+	p.Return(o)
+
+	// the evictor replaces the expired idle objects
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&f.destroyed) >= 2 && p.Stats().Idle == 2
+	}, time.Second, 5*time.Millisecond)
+	o, _ = p.Borrow(context.Background())
+	assert.True(t, o.(*testObject).id > 2)
+
+	p.Close()
+	p.Return(o)
+	stats := p.Stats()
+	assert.Equal(t, 0, stats.Idle)
+	assert.Equal(t, 0, stats.Active)
+	assert.Equal(t, stats.Created, stats.Destroyed)
+	assert.Equal(t, int32(stats.Destroyed), atomic.LoadInt32(&f.destroyed))
+	_, err := p.Borrow(context.Background())
+	assert.Equal(t, ErrObjectPoolClosed, err)
+}
+
+func TestBoundedObjectPoolConcurrency(t *testing.T) {
+	f := &testObjectFactory{}
+	p := NewBoundedObjectPool(f.new, WithMaxTotal(4), WithMaxIdle(4))
+	defer p.Close()
+
+	var (
+		wg     sync.WaitGroup
+		active int32
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				o, err := p.Borrow(context.Background())
+				assert.Nil(t, err)
+				assert.True(t, atomic.AddInt32(&active, 1) <= 4)
+				atomic.AddInt32(&active, -1)
+				p.Return(o)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(t, atomic.LoadInt32(&f.created) <= 4)
+	assert.Equal(t, uint64(1600), p.Stats().Borrowed)
+}
@@ -17,6 +17,12 @@
 
 package gxchan
 
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
 import (
 	"go.uber.org/atomic"
 )
@@ -25,6 +31,29 @@ import (
 	"github.com/dubbogo/gost/container/queue"
 )
 
+var (
+	// ErrChanFull is returned by TryPush and PushContext if the chan reaches its quota
+	ErrChanFull = errors.New(`chan: full`)
+	// ErrChanClosed is returned if the chan is closed by Close
+	ErrChanClosed = errors.New(`chan: closed`)
+)
+
+// OverflowPolicy decides what to do with a new element if the chan reaches its quota
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writers until there is an idle space, it is the default policy
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the new element
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest element which is not moved to Out yet
+	// to make room for the new one
+	OverflowDropOldest
+	// OverflowError makes TryPush and PushContext return ErrChanFull, the
+	// elements written to In directly block like OverflowBlock
+	OverflowError
+)
+
 // UnboundedChan is a chan that could grow if the number of elements exceeds the capacity.
 type UnboundedChan struct {
 	in       chan interface{}
@@ -32,6 +61,16 @@ type UnboundedChan struct {
 	queue    *gxqueue.CircularUnboundedQueue
 	queueLen *atomic.Int32
 	queueCap *atomic.Int32
+
+	quota   int
+	policy  OverflowPolicy
+	dropped *atomic.Int64
+
+	// lock prevents Close from closing `ch.in` while it is written by push
+	lock      sync.RWMutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewUnboundedChan creates an instance of UnboundedChan.
@@ -39,7 +78,15 @@ func NewUnboundedChan(capacity int) *UnboundedChan {
 	return NewUnboundedChanWithQuota(capacity, 0)
 }
 
+// NewUnboundedChanWithQuota creates an instance of UnboundedChan, which holds
+// @quota elements at most, and 0 means no limit
 func NewUnboundedChanWithQuota(capacity, quota int) *UnboundedChan {
+	return NewUnboundedChanWithPolicy(capacity, quota, OverflowBlock)
+}
+
+// NewUnboundedChanWithPolicy creates an instance of UnboundedChan with the
+// overflow policy @policy applied once it holds @quota elements
+func NewUnboundedChanWithPolicy(capacity, quota int, policy OverflowPolicy) *UnboundedChan {
 	if capacity <= 0 {
 		panic("capacity should be greater than 0")
 	}
@@ -64,6 +111,11 @@ func NewUnboundedChanWithQuota(capacity, quota int) *UnboundedChan {
 		qquota--
 	}
 
+	// the drop policies never hold the elements in `ch.in` and `block`
+	if quota != 0 && (policy == OverflowDropNewest || policy == OverflowDropOldest) {
+		qquota += incap + 1
+	}
+
 	// address quota if the value is not valid
 	if quota == 0 { // quota == 0 means no limits for queue
 		qquota = 0
@@ -81,6 +133,10 @@ func NewUnboundedChanWithQuota(capacity, quota int) *UnboundedChan {
 		queue:    gxqueue.NewCircularUnboundedQueueWithQuota(qcap, qquota),
 		queueLen: &atomic.Int32{},
 		queueCap: &atomic.Int32{},
+		quota:    quota,
+		policy:   policy,
+		dropped:  &atomic.Int64{},
+		done:     make(chan struct{}),
 	}
 	ch.queueCap.Store(int32(ch.queue.Cap()))
 
@@ -99,6 +155,94 @@ func (ch *UnboundedChan) Out() <-chan interface{} {
 	return ch.out
 }
 
+// TryPush writes @val into the chan without blocking. It returns ErrChanFull
+// if the chan reaches its quota with OverflowBlock or OverflowError, or In is
+// full as the writers are faster than the chan moves the elements, whatever
+// the quota and the policy are.
+func (ch *UnboundedChan) TryPush(val interface{}) error {
+	return ch.push(context.Background(), val, false)
+}
+
+// PushContext writes @val into the chan, it waits for an idle space with
+// OverflowBlock until @ctx is done, and returns ErrChanFull at once with
+// OverflowError.
+func (ch *UnboundedChan) PushContext(ctx context.Context, val interface{}) error {
+	return ch.push(ctx, val, true)
+}
+
+func (ch *UnboundedChan) push(ctx context.Context, val interface{}, wait bool) error {
+	ch.lock.RLock()
+	defer ch.lock.RUnlock()
+	if ch.closed {
+		return ErrChanClosed
+	}
+
+	// the writers may wait for the quota only if the chan is bounded and does not drop elements
+	if ch.quota != 0 && (ch.policy == OverflowBlock || ch.policy == OverflowError) {
+		if ch.Len() >= ch.quota && (!wait || ch.policy == OverflowError) {
+			return ErrChanFull
+		}
+	}
+
+	// `ch.in` is full if the writers are faster than the chan moves the elements
+	if !wait {
+		select {
+		case ch.in <- val:
+			return nil
+		default:
+			return ErrChanFull
+		}
+	}
+
+	select {
+	case ch.in <- val:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch.done:
+		return ErrChanClosed
+	}
+}
+
+// DrainN reads @max elements at most from Out without blocking
+func (ch *UnboundedChan) DrainN(max int) []interface{} {
+	if max <= 0 {
+		return nil
+	}
+	vals := make([]interface{}, 0, minInt(max, ch.Len()))
+	for len(vals) < max {
+		select {
+		case val, ok := <-ch.out:
+			if !ok {
+				return vals
+			}
+			vals = append(vals, val)
+		default:
+			return vals
+		}
+	}
+	return vals
+}
+
+// Close closes In and returns the number of the elements still buffered,
+// which can be read from Out until it is closed. The blocked PushContext
+// returns ErrChanClosed. Do not close In directly if Close is used.
+func (ch *UnboundedChan) Close() int {
+	ch.closeOnce.Do(func() {
+		close(ch.done)
+		ch.lock.Lock()
+		ch.closed = true
+		close(ch.in)
+		ch.lock.Unlock()
+	})
+	return ch.Len()
+}
+
+// Dropped returns the number of the elements dropped by the overflow policy
+func (ch *UnboundedChan) Dropped() int64 {
+	return ch.dropped.Load()
+}
+
 // Len returns the total length of chan
 func (ch *UnboundedChan) Len() int {
 	return len(ch.in) + len(ch.out) + int(ch.queueLen.Load())
@@ -125,7 +269,7 @@ func (ch *UnboundedChan) run() {
 			continue
 		default: // `ch.out` is full, move the data to `ch.queue`
 			if ok := ch.queuePush(val); !ok {
-				ch.block(val)
+				ch.overflow(val)
 			}
 		}
 
@@ -137,7 +281,7 @@ func (ch *UnboundedChan) run() {
 					return
 				}
 				if ok = ch.queuePush(val); !ok { // try to push the value into queue
-					ch.block(val)
+					ch.overflow(val)
 				}
 			case ch.out <- ch.queue.Peek():
 				ch.queuePop()
@@ -157,6 +301,32 @@ func (ch *UnboundedChan) closeWait() {
 	}
 }
 
+// overflow handles `val` by the policy if `ch.queue` is full
+func (ch *UnboundedChan) overflow(val interface{}) {
+	switch ch.policy {
+	case OverflowDropNewest:
+		ch.dropped.Inc()
+	case OverflowDropOldest:
+		ch.dropped.Inc()
+		if !ch.queue.IsEmpty() {
+			ch.queuePop()
+			ch.queuePush(val)
+			return
+		}
+		// the queue holds nothing, so the oldest element is in `ch.out`
+		select {
+		case <-ch.out:
+		default:
+		}
+		select {
+		case ch.out <- val:
+		default: // `ch.out` has no buffer, so `val` is the oldest one
+		}
+	default:
+		ch.block(val)
+	}
+}
+
 // block waits for having an idle space on `ch.out`
 func (ch *UnboundedChan) block(val interface{}) {
 	// `val` is not in `ch.queue` and `ch.in`, but it is stored into `UnboundedChan`
@@ -194,3 +364,10 @@ func (ch *UnboundedChan) queuePop() (t interface{}) {
 	ch.queueLen.Add(-1)
 	return
 }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
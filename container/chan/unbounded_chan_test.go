@@ -18,6 +18,7 @@
 package gxchan
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -25,6 +26,8 @@ import (
 
 import (
 	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/atomic"
 )
 
 func TestUnboundedChan(t *testing.T) {
@@ -217,6 +220,148 @@ func testQuota2(t *testing.T) {
 	}
 }
 
+func TestUnboundedChan_TryPush(t *testing.T) {
+	ch := NewUnboundedChanWithQuota(3, 6)
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, ch.PushContext(context.Background(), i))
+	}
+	assert.Eventually(t, func() bool {
+		return ch.Len() == 6
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, ErrChanFull, ch.TryPush(6))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, ch.PushContext(ctx, 6))
+
+	<-ch.Out()
+	assert.Eventually(t, func() bool {
+		return ch.TryPush(6) == nil
+	}, time.Second, time.Millisecond)
+
+	// Close wakes up the blocked writers
+	done := make(chan error)
+	go func() {
+		done <- ch.PushContext(context.Background(), 7)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 6, ch.Close())
+	assert.Equal(t, ErrChanClosed, <-done)
+	assert.Equal(t, ErrChanClosed, ch.TryPush(8))
+	assert.Equal(t, 6, ch.Close())
+
+	var vals []interface{}
+	for v := range ch.Out() {
+		vals = append(vals, v)
+	}
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6}, vals)
+}
+
+func TestUnboundedChan_TryPushInFull(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowError} {
+		for _, quota := range []int{0, 3} {
+			// nobody moves the elements from `ch.in`
+			ch := &UnboundedChan{
+				in:       make(chan interface{}),
+				queueLen: &atomic.Int32{},
+				quota:    quota,
+				policy:   policy,
+				done:     make(chan struct{}),
+			}
+			done := make(chan error, 1)
+			go func() {
+				done <- ch.TryPush(1)
+			}()
+			select {
+			case err := <-done:
+				assert.Equal(t, ErrChanFull, err)
+			case <-time.After(time.Second):
+				t.Fatalf("TryPush blocks with the policy %d and the quota %d", policy, quota)
+			}
+		}
+	}
+}
+
+func TestUnboundedChan_DrainN(t *testing.T) {
+	ch := NewUnboundedChan(9)
+	assert.Nil(t, ch.DrainN(0))
+	assert.Equal(t, 0, len(ch.DrainN(10)))
+
+	for i := 0; i < 10; i++ {
+		ch.In() <- i
+	}
+	assert.Eventually(t, func() bool {
+		return len(ch.out) == cap(ch.out)
+	}, time.Second, time.Millisecond)
+
+	vals := ch.DrainN(2)
+	assert.Equal(t, []interface{}{0, 1}, vals)
+	for len(vals) < 10 {
+		vals = append(vals, ch.DrainN(10)...)
+	}
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, vals)
+
+	ch.Close()
+	assert.Eventually(t, func() bool {
+		_, ok := <-ch.Out()
+		return !ok
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, len(ch.DrainN(10)))
+}
+
+func TestUnboundedChan_Overflow(t *testing.T) {
+	push := func(ch *UnboundedChan, n int) []interface{} {
+		for i := 0; i < n; i++ {
+			assert.Nil(t, ch.PushContext(context.Background(), i))
+		}
+		assert.Eventually(t, func() bool {
+			return ch.Len()+int(ch.Dropped()) == n
+		}, time.Second, time.Millisecond)
+		ch.Close()
+		var vals []interface{}
+		for v := range ch.Out() {
+			vals = append(vals, v)
+		}
+		return vals
+	}
+
+	// the quota 6 is 2 in `ch.out` and 4 in `ch.queue`
+	ch := NewUnboundedChanWithPolicy(6, 6, OverflowDropNewest)
+	assert.Equal(t, []interface{}{0, 1, 2, 3, 4, 5}, push(ch, 9))
+	assert.Equal(t, int64(3), ch.Dropped())
+
+	ch = NewUnboundedChanWithPolicy(6, 6, OverflowDropOldest)
+	assert.Equal(t, []interface{}{0, 1, 5, 6, 7, 8}, push(ch, 9))
+	assert.Equal(t, int64(3), ch.Dropped())
+
+	// the small quota is held by `ch.queue` only
+	ch = NewUnboundedChanWithPolicy(1, 1, OverflowDropOldest)
+	assert.Equal(t, []interface{}{8}, push(ch, 9))
+	assert.Equal(t, int64(8), ch.Dropped())
+	ch = NewUnboundedChanWithPolicy(2, 2, OverflowDropOldest)
+	assert.Equal(t, []interface{}{7, 8}, push(ch, 9))
+	assert.Equal(t, int64(7), ch.Dropped())
+
+	// the oldest element in `ch.out` is dropped if `ch.queue` has nothing
+	ch = NewUnboundedChanWithPolicy(3, 3, OverflowDropOldest)
+	ch.out <- 0
+	ch.overflow(1)
+	assert.Equal(t, int64(1), ch.Dropped())
+	assert.Equal(t, 1, <-ch.Out())
+	ch.Close()
+
+	ch = NewUnboundedChanWithPolicy(6, 6, OverflowError)
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, ch.PushContext(context.Background(), i))
+	}
+	assert.Eventually(t, func() bool {
+		return ch.Len() == 6
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, ErrChanFull, ch.TryPush(6))
+	assert.Equal(t, ErrChanFull, ch.PushContext(context.Background(), 6))
+	assert.Equal(t, 6, ch.Close())
+}
+
 func BenchmarkUnboundedChan_Fixed(b *testing.B) {
 	ch := NewUnboundedChanWithQuota(1000, 1000)
 
@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxqueue
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// DelayHandle is the handle of an item put into DelayQueue, which is used to cancel it.
+type DelayHandle struct {
+	item    interface{}
+	readyAt time.Time
+	seq     uint64
+	index   int // the index in delayHeap, -1 if it is removed
+}
+
+// Item returns the item of the handle
+func (h *DelayHandle) Item() interface{} {
+	return h.item
+}
+
+// ReadyAt returns the time when the item is ready
+func (h *DelayHandle) ReadyAt() time.Time {
+	return h.readyAt
+}
+
+// delayHeap is a min heap ordered by the ready time, and the items with the
+// same ready time are in the order of Put
+type delayHeap []*DelayHandle
+
+func (h delayHeap) Len() int {
+	return len(h)
+}
+
+func (h delayHeap) Less(i, j int) bool {
+	if h[i].readyAt.Equal(h[j].readyAt) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].readyAt.Before(h[j].readyAt)
+}
+
+func (h delayHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayHeap) Push(x interface{}) {
+	handle := x.(*DelayHandle)
+	handle.index = len(*h)
+	*h = append(*h, handle)
+}
+
+func (h *delayHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	handle := old[n-1]
+	old[n-1] = nil // prevent memory leak
+	handle.index = -1
+	*h = old[:n-1]
+	return handle
+}
+
+// DelayQueue is a queue whose items can only be got after their ready time,
+// eg: the retry-after requests and the delayed reconnections.
+type DelayQueue struct {
+	lock     sync.Mutex
+	items    delayHeap
+	seq      uint64
+	disposed bool
+	// changed is closed and renewed to wake up the waiters once the
+	// earliest ready time changes or the queue is disposed
+	changed chan struct{}
+}
+
+// NewDelayQueue is a constructor for a new threadsafe delay queue.
+func NewDelayQueue() *DelayQueue {
+	return &DelayQueue{changed: make(chan struct{})}
+}
+
+// notify should be called with the lock held
+func (q *DelayQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Put adds @item which is ready at @readyAt, the returned handle can be used
+// to cancel it.
+func (q *DelayQueue) Put(item interface{}, readyAt time.Time) (*DelayHandle, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.disposed {
+		return nil, ErrDisposed
+	}
+
+	q.seq++
+	handle := &DelayHandle{item: item, readyAt: readyAt, seq: q.seq}
+	heap.Push(&q.items, handle)
+	if handle.index == 0 {
+		q.notify()
+	}
+	return handle, nil
+}
+
+// PutAfter adds @item which is ready after @delay.
+func (q *DelayQueue) PutAfter(item interface{}, delay time.Duration) (*DelayHandle, error) {
+	return q.Put(item, time.Now().Add(delay))
+}
+
+// Cancel removes the item of @handle, it returns false if the item has been
+// got, cancelled or disposed.
+func (q *DelayQueue) Cancel(handle *DelayHandle) bool {
+	if handle == nil {
+		return false
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if handle.index < 0 || handle.index >= len(q.items) || q.items[handle.index] != handle {
+		return false
+	}
+	heap.Remove(&q.items, handle.index)
+	return true
+}
+
+// Get retrieves the ready items from the queue.  If there are some ready
+// items, get will return a number UP TO the number passed in as a parameter.
+// If no items are ready, this method will pause until an item is ready.
+func (q *DelayQueue) Get(number int64) ([]interface{}, error) {
+	return q.Poll(number, 0)
+}
+
+// Poll retrieves the ready items from the queue like Get, it pauses until an
+// item is ready or the provided timeout is reached. A non-positive timeout
+// will block until an item is ready. If a timeout occurs, ErrTimeout is
+// returned.
+func (q *DelayQueue) Poll(number int64, timeout time.Duration) ([]interface{}, error) {
+	if number < 1 {
+		return []interface{}{}, nil
+	}
+
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		timeoutC = timer.C
+	}
+
+	for {
+		q.lock.Lock()
+		if q.disposed {
+			q.lock.Unlock()
+			return nil, ErrDisposed
+		}
+
+		now := time.Now()
+		if items := q.popReady(now, number); len(items) > 0 {
+			q.lock.Unlock()
+			return items, nil
+		}
+
+		var (
+			readyC     <-chan time.Time
+			readyTimer *time.Timer
+		)
+		if len(q.items) > 0 {
+			readyTimer = time.NewTimer(q.items[0].readyAt.Sub(now))
+			readyC = readyTimer.C
+		}
+		changed := q.changed
+		q.lock.Unlock()
+
+		var timedOut bool
+		select {
+		case <-readyC:
+		case <-changed:
+		case <-timeoutC:
+			timedOut = true
+		}
+		if readyTimer != nil {
+			readyTimer.Stop()
+		}
+		if timedOut {
+			return nil, ErrTimeout
+		}
+	}
+}
+
+// TryGet retrieves the ready items like Get without waiting, it returns
+// an empty slice if no items are ready.
+func (q *DelayQueue) TryGet(number int64) ([]interface{}, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.disposed {
+		return nil, ErrDisposed
+	}
+	if number < 1 {
+		return []interface{}{}, nil
+	}
+	return q.popReady(time.Now(), number), nil
+}
+
+// popReady should be called with the lock held
+func (q *DelayQueue) popReady(now time.Time, number int64) []interface{} {
+	var items []interface{}
+	for len(q.items) > 0 && int64(len(items)) < number && !q.items[0].readyAt.After(now) {
+		items = append(items, heap.Pop(&q.items).(*DelayHandle).item)
+	}
+	if items == nil {
+		return []interface{}{}
+	}
+	return items
+}
+
+// Peek returns the handle of the earliest item without removing it, the
+// item may be not ready yet.
+func (q *DelayQueue) Peek() (*DelayHandle, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.disposed {
+		return nil, ErrDisposed
+	}
+	if len(q.items) == 0 {
+		return nil, ErrEmptyQueue
+	}
+	return q.items[0], nil
+}
+
+// Empty returns a bool indicating if this queue is empty.
+func (q *DelayQueue) Empty() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.items) == 0
+}
+
+// Len returns the number of items in this queue, including the items not ready.
+func (q *DelayQueue) Len() int64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return int64(len(q.items))
+}
+
+// Disposed returns a bool indicating if this queue
+// has had disposed called on it.
+func (q *DelayQueue) Disposed() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.disposed
+}
+
+// Dispose will dispose of this queue and returns the items disposed in the
+// order of their ready time, ready or not. Any subsequent calls to Get or
+// Put will return an error.
+func (q *DelayQueue) Dispose() []interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.disposed {
+		return nil
+	}
+	q.disposed = true
+	q.notify()
+
+	disposedItems := make([]interface{}, 0, len(q.items))
+	for len(q.items) > 0 {
+		disposedItems = append(disposedItems, heap.Pop(&q.items).(*DelayHandle).item)
+	}
+	q.items = nil
+
+	return disposedItems
+}
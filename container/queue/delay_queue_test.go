@@ -0,0 +1,169 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayQueueGet(t *testing.T) {
+	q := NewDelayQueue()
+	now := time.Now()
+	_, err := q.Put(`c`, now.Add(60*time.Millisecond))
+	assert.Nil(t, err)
+	_, err = q.Put(`a`, now.Add(-time.Millisecond))
+	assert.Nil(t, err)
+	_, err = q.Put(`b`, now.Add(30*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), q.Len())
+
+	peek, err := q.Peek()
+	assert.Nil(t, err)
+	assert.Equal(t, `a`, peek.Item())
+
+	// only the ready items are returned
+	items, err := q.Get(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{`a`}, items)
+	items, err = q.TryGet(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{}, items)
+
+	items, err = q.Get(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{`b`}, items)
+	assert.True(t, time.Since(now) >= 30*time.Millisecond)
+
+	items, err = q.Get(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{`c`}, items)
+	assert.True(t, time.Since(now) >= 60*time.Millisecond)
+	assert.True(t, q.Empty())
+
+	_, err = q.Peek()
+	assert.Equal(t, ErrEmptyQueue, err)
+	items, err = q.Get(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{}, items)
+}
+
+func TestDelayQueueOrder(t *testing.T) {
+	q := NewDelayQueue()
+	readyAt := time.Now().Add(-time.Second)
+	for i := 0; i < 5; i++ {
+		_, err := q.Put(i, readyAt)
+		assert.Nil(t, err)
+	}
+	_, err := q.Put(-1, readyAt.Add(-time.Second))
+	assert.Nil(t, err)
+
+	items, err := q.Get(3)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{-1, 0, 1}, items)
+	items, err = q.Get(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{2, 3, 4}, items)
+}
+
+func TestDelayQueuePoll(t *testing.T) {
+	q := NewDelayQueue()
+	_, err := q.PutAfter(`a`, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = q.Poll(1, 20*time.Millisecond)
+	assert.Equal(t, ErrTimeout, err)
+
+	// an earlier item wakes up the waiter
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = q.PutAfter(`b`, 10*time.Millisecond)
+	}()
+	items, err := q.Poll(1, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{`b`}, items)
+	assert.Equal(t, int64(1), q.Len())
+}
+
+func TestDelayQueueCancel(t *testing.T) {
+	q := NewDelayQueue()
+	ha, _ := q.PutAfter(`a`, 10*time.Millisecond)
+	hb, _ := q.PutAfter(`b`, 20*time.Millisecond)
+	hc, _ := q.PutAfter(`c`, 30*time.Millisecond)
+	assert.Equal(t, `b`, hb.Item())
+
+	assert.True(t, q.Cancel(ha))
+	assert.False(t, q.Cancel(ha))
+	assert.True(t, q.Cancel(hc))
+	assert.False(t, q.Cancel(nil))
+	assert.Equal(t, int64(1), q.Len())
+
+	items, err := q.Get(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{`b`}, items)
+	assert.False(t, q.Cancel(hb))
+
+	// the waiter is not woken up by the cancelled item
+	hd, _ := q.PutAfter(`d`, 10*time.Millisecond)
+	assert.True(t, q.Cancel(hd))
+	_, err = q.Poll(1, 30*time.Millisecond)
+	assert.Equal(t, ErrTimeout, err)
+}
+
+func TestDelayQueueDispose(t *testing.T) {
+	q := NewDelayQueue()
+	_, _ = q.PutAfter(`b`, time.Hour)
+	_, _ = q.PutAfter(`a`, time.Minute)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := q.Get(1)
+		assert.Equal(t, ErrDisposed, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, []interface{}{`a`, `b`}, q.Dispose())
+	wg.Wait()
+	assert.True(t, q.Disposed())
+	assert.Nil(t, q.Dispose())
+
+	_, err := q.PutAfter(`c`, 0)
+	assert.Equal(t, ErrDisposed, err)
+	_, err = q.TryGet(1)
+	assert.Equal(t, ErrDisposed, err)
+	_, err = q.Peek()
+	assert.Equal(t, ErrDisposed, err)
+}
+
+func BenchmarkDelayQueue(b *testing.B) {
+	q := NewDelayQueue()
+	readyAt := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = q.Put(`a`, readyAt)
+			_, _ = q.TryGet(1)
+		}
+	})
+}
@@ -0,0 +1,361 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//refs:https://www.1024cores.net/home/lock-free-algorithms/queues/bounded-mpmc-queue
+package gxqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// MPMCLockFreeQ is a lock-free multi-producer multi-consumer queue.
+type MPMCLockFreeQ interface {
+	// Offer adds val at the tail of the queue, it returns false if the queue is full
+	Offer(val interface{}) bool
+	// Poll removes the item at the head of the queue, it returns false if the
+	// queue is empty or the item at the head is still being offered
+	Poll() (interface{}, bool)
+	// Len returns the number of the items, it is a snapshot under concurrency
+	Len() int
+}
+
+// cacheLinePad prevents false sharing between the indexes
+type cacheLinePad struct {
+	_ [64]byte
+}
+
+type ringCell struct {
+	// seq is the position the cell waits for: pos for the producer and
+	// pos+1 for the consumer
+	seq uint64
+	val interface{}
+}
+
+// mpmcRing is a fixed-size queue of Dmitry Vyukov's bounded MPMC algorithm,
+// each cell has a sequence number telling whether it is ready for the
+// producer or the consumer of a position.
+type mpmcRing struct {
+	_          cacheLinePad
+	enqueuePos uint64
+	_          cacheLinePad
+	dequeuePos uint64
+	_          cacheLinePad
+	mask       uint64
+	cells      []ringCell
+}
+
+// NewMPMCLockFreeQ new a bounded MPMCLockFreeQ instance of size n, which
+// must be a power of 2.
+func NewMPMCLockFreeQ(n int) (MPMCLockFreeQ, error) {
+	if n <= 0 || n&(n-1) != 0 {
+		return nil, errors.New("the size of queue must be a power of 2")
+	}
+	q := &mpmcRing{
+		mask:  uint64(n - 1),
+		cells: make([]ringCell, n),
+	}
+	for i := range q.cells {
+		q.cells[i].seq = uint64(i)
+	}
+	return q, nil
+}
+
+func (q *mpmcRing) Offer(val interface{}) bool {
+	pos := atomic.LoadUint64(&q.enqueuePos)
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := atomic.LoadUint64(&cell.seq)
+		diff := int64(seq) - int64(pos)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&q.enqueuePos, pos, pos+1) {
+				cell.val = val
+				atomic.StoreUint64(&cell.seq, pos+1)
+				return true
+			}
+		} else if diff < 0 {
+			// the cell of the last round is not consumed yet
+			return false
+		}
+		pos = atomic.LoadUint64(&q.enqueuePos)
+	}
+}
+
+func (q *mpmcRing) Poll() (interface{}, bool) {
+	pos := atomic.LoadUint64(&q.dequeuePos)
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := atomic.LoadUint64(&cell.seq)
+		diff := int64(seq) - int64(pos+1)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&q.dequeuePos, pos, pos+1) {
+				val := cell.val
+				cell.val = nil
+				atomic.StoreUint64(&cell.seq, pos+q.mask+1)
+				return val, true
+			}
+		} else if diff < 0 {
+			// the cell is not offered yet
+			return nil, false
+		}
+		pos = atomic.LoadUint64(&q.dequeuePos)
+	}
+}
+
+func (q *mpmcRing) Len() int {
+	dequeuePos := atomic.LoadUint64(&q.dequeuePos)
+	enqueuePos := atomic.LoadUint64(&q.enqueuePos)
+	if enqueuePos <= dequeuePos {
+		return 0
+	}
+	if n := enqueuePos - dequeuePos; n < uint64(len(q.cells)) {
+		return int(n)
+	}
+	return len(q.cells)
+}
+
+// mpmcSegmentSize is the number of the cells of a segment
+const mpmcSegmentSize = 256
+
+type segmentCell struct {
+	ready uint32
+	val   interface{}
+}
+
+type segment struct {
+	next  unsafe.Pointer // *segment
+	enq   uint32         // the next cell to reserve by the producers
+	deq   uint32         // the next cell to consume by the consumers
+	cells [mpmcSegmentSize]segmentCell
+}
+
+// mpmcSegmentQueue is an unbounded queue of linked segments. The producers
+// reserve the cells of the tail segment by an atomic counter and link a new
+// segment once it is full, the consumers drop the head segment once all the
+// cells are consumed.
+type mpmcSegmentQueue struct {
+	_    cacheLinePad
+	head unsafe.Pointer // *segment
+	_    cacheLinePad
+	tail unsafe.Pointer // *segment
+	_    cacheLinePad
+	len  int64
+}
+
+// NewMPMCUnboundedLockFreeQ new an unbounded MPMCLockFreeQ instance, its
+// Offer never returns false.
+func NewMPMCUnboundedLockFreeQ() MPMCLockFreeQ {
+	seg := unsafe.Pointer(&segment{})
+	return &mpmcSegmentQueue{head: seg, tail: seg}
+}
+
+func (q *mpmcSegmentQueue) Offer(val interface{}) bool {
+	for {
+		tail := (*segment)(atomic.LoadPointer(&q.tail))
+		if idx := atomic.AddUint32(&tail.enq, 1) - 1; idx < mpmcSegmentSize {
+			cell := &tail.cells[idx]
+			cell.val = val
+			atomic.StoreUint32(&cell.ready, 1)
+			atomic.AddInt64(&q.len, 1)
+			return true
+		}
+
+		// the tail segment is full, link a new one or help to move the tail
+		next := atomic.LoadPointer(&tail.next)
+		if next == nil {
+			seg := unsafe.Pointer(&segment{})
+			if atomic.CompareAndSwapPointer(&tail.next, nil, seg) {
+				next = seg
+			} else {
+				next = atomic.LoadPointer(&tail.next)
+			}
+		}
+		atomic.CompareAndSwapPointer(&q.tail, unsafe.Pointer(tail), next)
+	}
+}
+
+func (q *mpmcSegmentQueue) Poll() (interface{}, bool) {
+	for {
+		head := (*segment)(atomic.LoadPointer(&q.head))
+		idx := atomic.LoadUint32(&head.deq)
+		if idx >= mpmcSegmentSize {
+			// the head segment is consumed, move to the next one
+			next := atomic.LoadPointer(&head.next)
+			if next == nil {
+				return nil, false
+			}
+			atomic.CompareAndSwapPointer(&q.head, unsafe.Pointer(head), next)
+			continue
+		}
+
+		cell := &head.cells[idx]
+		if atomic.LoadUint32(&cell.ready) == 0 {
+			// the queue is empty, or the cell is reserved but not offered yet
+			return nil, false
+		}
+		if atomic.CompareAndSwapUint32(&head.deq, idx, idx+1) {
+			val := cell.val
+			cell.val = nil
+			atomic.AddInt64(&q.len, -1)
+			return val, true
+		}
+	}
+}
+
+func (q *mpmcSegmentQueue) Len() int {
+	if n := atomic.LoadInt64(&q.len); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// BlockingQueue wraps a MPMCLockFreeQ with the blocking Put and Get.
+type BlockingQueue struct {
+	q MPMCLockFreeQ
+	// notEmpty and notFull hold a token once an item is offered or polled,
+	// the woken waiter passes the token on if it can not consume all the
+	// items or slots
+	notEmpty chan struct{}
+	notFull  chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewBlockingQueue new a BlockingQueue instance of @q
+func NewBlockingQueue(q MPMCLockFreeQ) *BlockingQueue {
+	return &BlockingQueue{
+		q:        q,
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Offer adds val without blocking, it returns false if the queue is full or disposed
+func (bq *BlockingQueue) Offer(val interface{}) bool {
+	if bq.Disposed() || !bq.q.Offer(val) {
+		return false
+	}
+	signal(bq.notEmpty)
+	return true
+}
+
+// Poll removes an item without blocking, it returns false if the queue is empty or disposed
+func (bq *BlockingQueue) Poll() (interface{}, bool) {
+	if bq.Disposed() {
+		return nil, false
+	}
+	val, ok := bq.q.Poll()
+	if ok {
+		signal(bq.notFull)
+	}
+	return val, ok
+}
+
+// Put adds @val, it waits until there is an idle slot, @ctx is done or the
+// queue is disposed, which returns ErrDisposed.
+func (bq *BlockingQueue) Put(ctx context.Context, val interface{}) error {
+	woken := false
+	for {
+		if bq.Disposed() {
+			return ErrDisposed
+		}
+		if bq.q.Offer(val) {
+			signal(bq.notEmpty)
+			if woken {
+				// more slots may be freed for the other waiters
+				signal(bq.notFull)
+			}
+			return nil
+		}
+		select {
+		case <-bq.notFull:
+			woken = true
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-bq.done:
+			return ErrDisposed
+		}
+	}
+}
+
+// Get removes an item, it waits until there is an item, @ctx is done or the
+// queue is disposed, which returns ErrDisposed.
+func (bq *BlockingQueue) Get(ctx context.Context) (interface{}, error) {
+	for {
+		if bq.Disposed() {
+			return nil, ErrDisposed
+		}
+		if val, ok := bq.q.Poll(); ok {
+			signal(bq.notFull)
+			if bq.q.Len() > 0 {
+				signal(bq.notEmpty)
+			}
+			return val, nil
+		}
+		select {
+		case <-bq.notEmpty:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-bq.done:
+			return nil, ErrDisposed
+		}
+	}
+}
+
+// Len returns the number of the items
+func (bq *BlockingQueue) Len() int {
+	return bq.q.Len()
+}
+
+// Disposed returns true if the queue is disposed
+func (bq *BlockingQueue) Disposed() bool {
+	select {
+	case <-bq.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Dispose wakes up all the waiters with ErrDisposed and returns the items
+// left in the queue. The Offer racing with Dispose may leave its item in
+// the queue.
+func (bq *BlockingQueue) Dispose() []interface{} {
+	var items []interface{}
+	bq.once.Do(func() {
+		close(bq.done)
+		for {
+			val, ok := bq.q.Poll()
+			if !ok {
+				break
+			}
+			items = append(items, val)
+		}
+	})
+	return items
+}
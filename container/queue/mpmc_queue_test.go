@@ -0,0 +1,285 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxqueue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMPMCLockFreeQ(t *testing.T) {
+	_, err := NewMPMCLockFreeQ(0)
+	assert.NotNil(t, err)
+	_, err = NewMPMCLockFreeQ(6)
+	assert.NotNil(t, err)
+	q, err := NewMPMCLockFreeQ(8)
+	assert.Nil(t, err)
+	assert.NotNil(t, q)
+}
+
+func TestMPMCLockFreeQ(t *testing.T) {
+	q, err := NewMPMCLockFreeQ(4)
+	assert.Nil(t, err)
+
+	_, ok := q.Poll()
+	assert.False(t, ok)
+	for i := 0; i < 4; i++ {
+		assert.True(t, q.Offer(i))
+	}
+	assert.False(t, q.Offer(4))
+	assert.Equal(t, 4, q.Len())
+
+	// the ring wraps around
+	for round := 0; round < 3; round++ {
+		val, ok := q.Poll()
+		assert.True(t, ok)
+		assert.Equal(t, round, val)
+		assert.True(t, q.Offer(4+round))
+	}
+	for i := 3; i < 7; i++ {
+		val, ok := q.Poll()
+		assert.True(t, ok)
+		assert.Equal(t, i, val)
+	}
+	_, ok = q.Poll()
+	assert.False(t, ok)
+	assert.Equal(t, 0, q.Len())
+
+	assert.True(t, q.Offer(nil))
+	val, ok := q.Poll()
+	assert.True(t, ok)
+	assert.Nil(t, val)
+}
+
+func TestMPMCUnboundedLockFreeQ(t *testing.T) {
+	q := NewMPMCUnboundedLockFreeQ()
+	_, ok := q.Poll()
+	assert.False(t, ok)
+
+	n := 3*mpmcSegmentSize + 10
+	for i := 0; i < n; i++ {
+		assert.True(t, q.Offer(i))
+	}
+	assert.Equal(t, n, q.Len())
+	for i := 0; i < n; i++ {
+		val, ok := q.Poll()
+		assert.True(t, ok)
+		assert.Equal(t, i, val)
+	}
+	_, ok = q.Poll()
+	assert.False(t, ok)
+	assert.Equal(t, 0, q.Len())
+
+	// the consumed segments are dropped
+	sq := q.(*mpmcSegmentQueue)
+	assert.Equal(t, sq.head, sq.tail)
+}
+
+// stressMPMCLockFreeQ checks every item is polled exactly once with many
+// producers and consumers, run it with -race
+func stressMPMCLockFreeQ(t *testing.T, q MPMCLockFreeQ) {
+	const (
+		producers = 8
+		consumers = 8
+		perWorker = 5000
+	)
+	var (
+		wg       sync.WaitGroup
+		polled   int64
+		seen     = make([]int32, producers*perWorker)
+		finished = make(chan struct{})
+	)
+
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				for !q.Offer(p*perWorker + i) {
+					time.Sleep(time.Microsecond)
+				}
+			}
+		}(p)
+	}
+
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			// the items of a producer are polled in order by a consumer
+			last := make([]int, producers)
+			for i := range last {
+				last[i] = -1
+			}
+			for {
+				val, ok := q.Poll()
+				if !ok {
+					select {
+					case <-finished:
+						if q.Len() == 0 {
+							return
+						}
+					default:
+					}
+					time.Sleep(time.Microsecond)
+					continue
+				}
+				item := val.(int)
+				atomic.AddInt32(&seen[item], 1)
+				atomic.AddInt64(&polled, 1)
+				p, i := item/perWorker, item%perWorker
+				assert.True(t, i > last[p])
+				last[p] = i
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(finished)
+	cwg.Wait()
+
+	assert.Equal(t, int64(producers*perWorker), atomic.LoadInt64(&polled))
+	for item, count := range seen {
+		if count != 1 {
+			assert.Failf(t, "the item is polled wrongly", "item %d is polled %d times", item, count)
+			break
+		}
+	}
+}
+
+func TestMPMCLockFreeQStress(t *testing.T) {
+	q, err := NewMPMCLockFreeQ(64)
+	assert.Nil(t, err)
+	stressMPMCLockFreeQ(t, q)
+	stressMPMCLockFreeQ(t, NewMPMCUnboundedLockFreeQ())
+}
+
+func TestBlockingQueue(t *testing.T) {
+	q, err := NewMPMCLockFreeQ(2)
+	assert.Nil(t, err)
+	bq := NewBlockingQueue(q)
+
+	assert.Nil(t, bq.Put(context.Background(), 1))
+	assert.True(t, bq.Offer(2))
+	assert.False(t, bq.Offer(3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, bq.Put(ctx, 3))
+
+	// the blocked Put is woken up by Get
+	done := make(chan error)
+	go func() {
+		done <- bq.Put(context.Background(), 3)
+	}()
+	val, err := bq.Get(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, val)
+	assert.Nil(t, <-done)
+
+	val, ok := bq.Poll()
+	assert.True(t, ok)
+	assert.Equal(t, 2, val)
+	val, err = bq.Get(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, val)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = bq.Get(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the blocked Get is woken up by Put
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = bq.Put(context.Background(), 4)
+	}()
+	val, err = bq.Get(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, val)
+	assert.Equal(t, 0, bq.Len())
+}
+
+func TestBlockingQueueDispose(t *testing.T) {
+	bq := NewBlockingQueue(NewMPMCUnboundedLockFreeQ())
+	assert.Nil(t, bq.Put(context.Background(), 1))
+	assert.Nil(t, bq.Put(context.Background(), 2))
+
+	waiter := NewBlockingQueue(NewMPMCUnboundedLockFreeQ())
+	done := make(chan error)
+	go func() {
+		_, err := waiter.Get(context.Background())
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, waiter.Dispose())
+	assert.Equal(t, ErrDisposed, <-done)
+
+	assert.Equal(t, []interface{}{1, 2}, bq.Dispose())
+	assert.True(t, bq.Disposed())
+	assert.Nil(t, bq.Dispose())
+	assert.Equal(t, ErrDisposed, bq.Put(context.Background(), 3))
+	_, err := bq.Get(context.Background())
+	assert.Equal(t, ErrDisposed, err)
+	assert.False(t, bq.Offer(3))
+	_, ok := bq.Poll()
+	assert.False(t, ok)
+}
+
+func TestBlockingQueueStress(t *testing.T) {
+	q, err := NewMPMCLockFreeQ(16)
+	assert.Nil(t, err)
+	bq := NewBlockingQueue(q)
+
+	const (
+		workers   = 8
+		perWorker = 2000
+	)
+	var (
+		wg  sync.WaitGroup
+		sum int64
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= perWorker; i++ {
+				assert.Nil(t, bq.Put(context.Background(), i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				val, err := bq.Get(context.Background())
+				assert.Nil(t, err)
+				atomic.AddInt64(&sum, int64(val.(int)))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(workers*perWorker*(perWorker+1)/2), sum)
+	assert.Equal(t, 0, bq.Len())
+}
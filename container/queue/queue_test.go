@@ -18,6 +18,7 @@
 package gxqueue
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -363,6 +364,70 @@ func BenchmarkChannel(b *testing.B) {
 	wg.Wait()
 }
 
+func BenchmarkParallelQueue(b *testing.B) {
+	q := New(1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = q.Put(`a`)
+			_, _ = q.Get(1)
+		}
+	})
+}
+
+func BenchmarkParallelChannel(b *testing.B) {
+	ch := make(chan interface{}, 1024)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch <- `a`
+			<-ch
+		}
+	})
+}
+
+func BenchmarkParallelMPMCLockFreeQ(b *testing.B) {
+	q, _ := NewMPMCLockFreeQ(1024)
+	bq := NewBlockingQueue(q)
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = bq.Put(ctx, `a`)
+			_, _ = bq.Get(ctx)
+		}
+	})
+}
+
+func BenchmarkParallelMPMCUnboundedLockFreeQ(b *testing.B) {
+	bq := NewBlockingQueue(NewMPMCUnboundedLockFreeQ())
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = bq.Put(ctx, `a`)
+			_, _ = bq.Get(ctx)
+		}
+	})
+}
+
+func BenchmarkMPMCLockFreeQ(b *testing.B) {
+	q, _ := NewMPMCLockFreeQ(1024)
+	bq := NewBlockingQueue(q)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < b.N; i++ {
+			_, _ = bq.Get(ctx)
+		}
+	}()
+
+	for i := 0; i < b.N; i++ {
+		_ = bq.Put(ctx, `a`)
+	}
+
+	wg.Wait()
+}
+
 func TestPeek(t *testing.T) {
 	q := New(10)
 	err := q.Put(`a`)
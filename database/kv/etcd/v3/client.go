@@ -33,6 +33,10 @@ import (
 	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
 var (
 	// ErrNilETCDV3Client raw client nil
 	ErrNilETCDV3Client = perrors.New("etcd raw client is nil") // full describe the ERR
@@ -44,8 +48,19 @@ var (
 	ErrCompareFail = perrors.New("txn compare fail")
 	// ErrRevision revision when error
 	ErrRevision int64 = -1
+
+	// clientRegistry holds the shared clients by name
+	clientRegistry = kv.NewSharedRegistry()
 )
 
+// ConfigureSharedClients configures the idle timeout and health check of the shared clients
+func ConfigureSharedClients(opts ...kv.SharedOption) {
+	clientRegistry.Configure(opts...)
+}
+
+// dialFunc creates the raw client with @ctx
+type dialFunc func(ctx context.Context) (*clientv3.Client, error)
+
 // NewConfigClient create new Client
 func NewConfigClient(opts ...Option) *Client {
 	newClient, err := NewConfigClientWithErr(opts...)
@@ -76,8 +91,7 @@ func NewConfigClientWithErr(opts ...Option) (*Client, error) {
 
 // Client represents etcd client Configuration
 type Client struct {
-	lock     sync.RWMutex
-	quitOnce sync.Once
+	lock sync.RWMutex
 
 	// these properties are only set once when they are started.
 	name      string
@@ -87,25 +101,36 @@ type Client struct {
 	username  string
 	password  string
 	tls       *tls.Config
+	share     bool
+	parent    context.Context // the parent of ctx
+	dial      dialFunc        // recreates the raw client when reconnecting
 
+	// these properties are replaced when reconnecting, so they should be accessed with the lock
 	ctx       context.Context    // if etcd server connection lose, the ctx.Done will be sent msg
 	cancel    context.CancelFunc // cancel the ctx, all watcher will stopped
 	rawClient *clientv3.Client
+	session   *concurrency.Session // keep alive with heartbeat, shared by the coordination primitives
 
 	exit chan struct{}
 	Wait sync.WaitGroup
+
+	closeOnce sync.Once
+	closed    chan struct{} // closed when the client is closed, unlike exit it is not renewed by reconnecting
 }
 
 // NewClient create a client instance with name, endpoints etc.
 func NewClient(name string, endpoints []string, timeout time.Duration, heartbeat int) (*Client, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	rawClient, err := clientv3.New(clientv3.Config{
-		Context:     ctx,
-		Endpoints:   endpoints,
-		DialTimeout: timeout,
-		DialOptions: []grpc.DialOption{grpc.WithBlock()},
-	})
+	dial := func(ctx context.Context) (*clientv3.Client, error) {
+		return clientv3.New(clientv3.Config{
+			Context:     ctx,
+			Endpoints:   endpoints,
+			DialTimeout: timeout,
+			DialOptions: []grpc.DialOption{grpc.WithBlock()},
+		})
+	}
+	rawClient, err := dial(ctx)
 	if err != nil {
 		cancel()
 		return nil, perrors.WithMessage(err, fmt.Sprintf("failed to create new raw client of endpoint %v", endpoints))
@@ -116,12 +141,15 @@ func NewClient(name string, endpoints []string, timeout time.Duration, heartbeat
 		timeout:   timeout,
 		endpoints: endpoints,
 		heartbeat: heartbeat,
+		parent:    context.Background(),
+		dial:      dial,
 
 		ctx:       ctx,
 		cancel:    cancel,
 		rawClient: rawClient,
 
-		exit: make(chan struct{}),
+		exit:   make(chan struct{}),
+		closed: make(chan struct{}),
 	}
 
 	if err := c.keepSession(); err != nil {
@@ -131,19 +159,41 @@ func NewClient(name string, endpoints []string, timeout time.Duration, heartbeat
 	return c, nil
 }
 
-// NewClientWithOptions create a client instance from Options.
+// NewClientWithOptions create a client instance from Options. If Options.Share
+// is set, the client is shared with the ones of the same name.
 func NewClientWithOptions(ctx context.Context, opts *Options) (*Client, error) {
+	if !opts.Share {
+		return newClientWithOptions(ctx, opts)
+	}
+	client, err := clientRegistry.Acquire(opts.Name, func() (kv.SharedClient, error) {
+		c, err := newClientWithOptions(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		c.share = true
+		return sharedClient{c}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client.(sharedClient).Client, nil
+}
+
+func newClientWithOptions(ctx context.Context, opts *Options) (*Client, error) {
 	nctx, cancel := context.WithCancel(ctx)
 
-	rawClient, err := clientv3.New(clientv3.Config{
-		Context:     nctx,
-		Endpoints:   opts.Endpoints,
-		DialTimeout: opts.Timeout,
-		TLS:         opts.TLS,
-		Username:    opts.Username,
-		Password:    opts.Password,
-		DialOptions: []grpc.DialOption{grpc.WithBlock()},
-	})
+	dial := func(ctx context.Context) (*clientv3.Client, error) {
+		return clientv3.New(clientv3.Config{
+			Context:     ctx,
+			Endpoints:   opts.Endpoints,
+			DialTimeout: opts.Timeout,
+			TLS:         opts.TLS,
+			Username:    opts.Username,
+			Password:    opts.Password,
+			DialOptions: []grpc.DialOption{grpc.WithBlock()},
+		})
+	}
+	rawClient, err := dial(nctx)
 	if err != nil {
 		cancel()
 		return nil, perrors.WithMessage(err, fmt.Sprintf("failed to create new raw client of endpoint %v", opts.Endpoints))
@@ -157,12 +207,15 @@ func NewClientWithOptions(ctx context.Context, opts *Options) (*Client, error) {
 		username:  opts.Username,
 		password:  opts.Password,
 		tls:       opts.TLS,
+		parent:    ctx,
+		dial:      dial,
 
 		ctx:       nctx,
 		cancel:    cancel,
 		rawClient: rawClient,
 
-		exit: make(chan struct{}),
+		exit:   make(chan struct{}),
+		closed: make(chan struct{}),
 	}
 
 	if err := c.keepSession(); err != nil {
@@ -186,29 +239,40 @@ func (c *Client) clean() {
 }
 
 func (c *Client) stop() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	select {
 	case <-c.exit:
 		return false
 	default:
-		ret := false
-		c.quitOnce.Do(func() {
-			ret = true
-			close(c.exit)
-		})
-		return ret
+		close(c.exit)
+		return true
 	}
 }
 
 // GetCtx return client context
 func (c *Client) GetCtx() context.Context {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return c.ctx
 }
 
-// Close close client
+// Close close client, the shared client is closed when all the holders close it
 func (c *Client) Close() {
 	if c == nil {
 		return
 	}
+	if c.share {
+		clientRegistry.Release(c.name, sharedClient{c})
+		return
+	}
+	c.destroy()
+}
+
+func (c *Client) destroy() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
 
 	// stop the client
 	if ret := c.stop(); !ret {
@@ -226,11 +290,69 @@ func (c *Client) Close() {
 	log.Printf("etcd client{Name:%s, Endpoints:%s} exit now.", c.name, c.endpoints)
 }
 
+// reconnect recreates the raw client and the session of the client which is
+// stopped by the loss of the session
+func (c *Client) reconnect() error {
+	// wait the previous keep session goroutine to exit
+	c.Wait.Wait()
+
+	ctx, cancel := context.WithCancel(c.parent)
+	rawClient, err := c.dial(ctx)
+	if err != nil {
+		cancel()
+		return perrors.WithMessage(err, fmt.Sprintf("failed to create new raw client of endpoint %v", c.endpoints))
+	}
+
+	c.lock.Lock()
+	if c.rawClient != nil {
+		c.clean()
+	}
+	c.ctx, c.cancel, c.rawClient = ctx, cancel, rawClient
+	select {
+	case <-c.exit:
+		c.exit = make(chan struct{})
+	default:
+	}
+	c.lock.Unlock()
+
+	if err := c.keepSession(); err != nil {
+		c.lock.Lock()
+		c.clean()
+		c.lock.Unlock()
+		c.stop()
+		return perrors.WithMessage(err, "client keep session")
+	}
+	return nil
+}
+
+// sharedClient makes Client a kv.SharedClient
+type sharedClient struct {
+	*Client
+}
+
+// Healthy reports whether the client is valid
+func (s sharedClient) Healthy() bool {
+	return s.Valid()
+}
+
+// Reconnect recreates the raw client and the session
+func (s sharedClient) Reconnect() error {
+	return s.reconnect()
+}
+
+// Destroy closes the client
+func (s sharedClient) Destroy() {
+	s.destroy()
+}
+
 func (c *Client) keepSession() error {
-	s, err := concurrency.NewSession(c.rawClient, concurrency.WithTTL(c.heartbeat))
+	s, err := concurrency.NewSession(c.GetRawClient(), concurrency.WithTTL(c.heartbeat))
 	if err != nil {
 		return perrors.WithMessage(err, "new session with server")
 	}
+	c.lock.Lock()
+	c.session = s
+	c.lock.Unlock()
 
 	// must add wg before go keep session goroutine
 	c.Wait.Add(1)
@@ -244,9 +366,10 @@ func (c *Client) keepSessionLoop(s *concurrency.Session) {
 		log.Printf("etcd client {Endpoints:%v, Name:%s} keep goroutine game over.", c.endpoints, c.name)
 	}()
 
+	exit := c.Done()
 	for {
 		select {
-		case <-c.Done():
+		case <-exit:
 			// Client be stopped, will clean the client hold resources
 			return
 		case <-s.Done():
@@ -254,9 +377,9 @@ func (c *Client) keepSessionLoop(s *concurrency.Session) {
 			c.lock.Lock()
 			// when etcd server stopped, cancel ctx, stop all watchers
 			c.clean()
+			c.lock.Unlock()
 			// when connection lose, stop client, trigger reconnect to etcd
 			c.stop()
-			c.lock.Unlock()
 			return
 		}
 	}
@@ -270,6 +393,14 @@ func (c *Client) GetRawClient() *clientv3.Client {
 	return c.rawClient
 }
 
+// GetSession return the session kept alive by client
+func (c *Client) GetSession() *concurrency.Session {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.session
+}
+
 // GetEndPoints return etcd endpoints
 func (c *Client) GetEndPoints() []string {
 	return c.endpoints
@@ -282,7 +413,7 @@ func (c *Client) create(k string, v string, opts ...clientv3.OpOption) error {
 		return ErrNilETCDV3Client
 	}
 
-	resp, err := rawClient.Txn(c.ctx).
+	resp, err := rawClient.Txn(c.GetCtx()).
 		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
 		Then(clientv3.OpPut(k, v, opts...)).
 		Commit()
@@ -318,7 +449,7 @@ func (c *Client) batchCreate(kList []string, vList []string, opts ...clientv3.Op
 		ops = append(ops, clientv3.OpPut(k, v, opts...))
 	}
 
-	resp, err := rawClient.Txn(c.ctx).
+	resp, err := rawClient.Txn(c.GetCtx()).
 		If(cs...).
 		Then(ops...).
 		Commit()
@@ -339,7 +470,7 @@ func (c *Client) put(k string, v string, opts ...clientv3.OpOption) error {
 		return ErrNilETCDV3Client
 	}
 
-	_, err := rawClient.Put(c.ctx, k, v, opts...)
+	_, err := rawClient.Put(c.GetCtx(), k, v, opts...)
 	return err
 }
 
@@ -350,7 +481,7 @@ func (c *Client) updateWithRev(k string, v string, rev int64, opts ...clientv3.O
 		return ErrNilETCDV3Client
 	}
 
-	resp, err := rawClient.Txn(c.ctx).
+	resp, err := rawClient.Txn(c.GetCtx()).
 		If(clientv3.Compare(clientv3.ModRevision(k), "=", rev)).
 		Then(clientv3.OpPut(k, v, opts...)).
 		Commit()
@@ -370,7 +501,7 @@ func (c *Client) delete(k string) error {
 		return ErrNilETCDV3Client
 	}
 
-	_, err := rawClient.Delete(c.ctx, k)
+	_, err := rawClient.Delete(c.GetCtx(), k)
 	return err
 }
 
@@ -381,7 +512,7 @@ func (c *Client) getValAndRev(k string) (string, int64, error) {
 		return "", ErrRevision, ErrNilETCDV3Client
 	}
 
-	resp, err := rawClient.Get(c.ctx, k)
+	resp, err := rawClient.Get(c.GetCtx(), k)
 	if err != nil {
 		return "", ErrRevision, err
 	}
@@ -400,7 +531,7 @@ func (c *Client) CleanKV() error {
 		return ErrNilETCDV3Client
 	}
 
-	_, err := rawClient.Delete(c.ctx, "", clientv3.WithPrefix())
+	_, err := rawClient.Delete(c.GetCtx(), "", clientv3.WithPrefix())
 	return err
 }
 
@@ -415,7 +546,7 @@ func (c *Client) GetChildren(k string) ([]string, []string, error) {
 		k += "/"
 	}
 
-	resp, err := rawClient.Get(c.ctx, k, clientv3.WithPrefix())
+	resp, err := rawClient.Get(c.GetCtx(), k, clientv3.WithPrefix())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -440,7 +571,7 @@ func (c *Client) watchWithOption(k string, opts ...clientv3.OpOption) (clientv3.
 		return nil, ErrNilETCDV3Client
 	}
 
-	return rawClient.Watch(c.ctx, k, opts...), nil
+	return rawClient.Watch(c.GetCtx(), k, opts...), nil
 }
 
 func (c *Client) keepAliveKV(k string, v string) error {
@@ -449,16 +580,17 @@ func (c *Client) keepAliveKV(k string, v string) error {
 		return ErrNilETCDV3Client
 	}
 
+	ctx := c.GetCtx()
 	// make lease time longer, since 1 second is too short
-	lease, err := rawClient.Grant(c.ctx, int64(30*time.Second.Seconds()))
+	lease, err := rawClient.Grant(ctx, int64(30*time.Second.Seconds()))
 	if err != nil {
 		return perrors.WithMessage(err, "grant lease")
 	}
 
-	keepAlive, err := rawClient.KeepAlive(c.ctx, lease.ID)
+	keepAlive, err := rawClient.KeepAlive(ctx, lease.ID)
 	if err != nil || keepAlive == nil {
 		// prioritize returning keepalive failure
-		_, _ = rawClient.Revoke(c.ctx, lease.ID)
+		_, _ = rawClient.Revoke(ctx, lease.ID)
 		if err != nil {
 			return perrors.WithMessage(err, "keep alive lease")
 		}
@@ -467,18 +599,18 @@ func (c *Client) keepAliveKV(k string, v string) error {
 
 	// listen keepAlive to avoid useless warning:
 	//    'lease keepalive response queue is full; dropping response send'
-	go c.listenKeepAliveRsp(k, keepAlive)
+	go c.listenKeepAliveRsp(ctx, k, keepAlive)
 
-	_, err = rawClient.Put(c.ctx, k, v, clientv3.WithLease(lease.ID))
+	_, err = rawClient.Put(ctx, k, v, clientv3.WithLease(lease.ID))
 	return perrors.WithMessage(err, "put k/v with lease")
 }
 
 // listenKeepAliveRsp listens to `keepAliveRspCh` channel.
-func (c *Client) listenKeepAliveRsp(k string, keepAliveRspCh <-chan *clientv3.LeaseKeepAliveResponse) {
+func (c *Client) listenKeepAliveRsp(ctx context.Context, k string, keepAliveRspCh <-chan *clientv3.LeaseKeepAliveResponse) {
 	for {
 		select {
-		case <-c.ctx.Done():
-			log.Printf("listenKeepAliveRsp canceled: %v: %s", c.ctx.Err(), k)
+		case <-ctx.Done():
+			log.Printf("listenKeepAliveRsp canceled: %v: %s", ctx.Err(), k)
 			return
 		case _, ok := <-keepAliveRspCh:
 			if !ok {
@@ -491,19 +623,20 @@ func (c *Client) listenKeepAliveRsp(k string, keepAliveRspCh <-chan *clientv3.Le
 
 // Done return exit chan
 func (c *Client) Done() <-chan struct{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	return c.exit
 }
 
 // Valid check client
 func (c *Client) Valid() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	select {
 	case <-c.exit:
 		return false
 	default:
 	}
-
-	c.lock.RLock()
-	defer c.lock.RUnlock()
 	return c.rawClient != nil
 }
 
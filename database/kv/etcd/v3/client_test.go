@@ -164,6 +164,29 @@ func (suite *ClientTestSuite) TestClientValid() {
 	}
 }
 
+func (suite *ClientTestSuite) TestClientShare() {
+	t := suite.T()
+	opts := []Option{
+		WithName("shared"),
+		WithEndpoints(suite.etcdConfig.endpoints...),
+		WithTimeout(suite.etcdConfig.timeout),
+		WithHeartbeat(suite.etcdConfig.heartbeat),
+		WithShare(true),
+	}
+	c1, err := NewConfigClientWithErr(opts...)
+	assert.Nil(t, err)
+	c2, err := NewConfigClientWithErr(opts...)
+	assert.Nil(t, err)
+	assert.True(t, c1 == c2)
+	assert.Equal(t, 2, clientRegistry.Refs("shared"))
+
+	c1.Close()
+	assert.True(t, c2.Valid())
+	c2.Close()
+	assert.False(t, c2.Valid())
+	assert.Equal(t, 0, clientRegistry.Refs("shared"))
+}
+
 func (suite *ClientTestSuite) TestClientDone() {
 	c := suite.client
 
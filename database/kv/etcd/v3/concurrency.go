@@ -0,0 +1,494 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var (
+	// ErrSemaphoreNotAcquired semaphore is released without acquiring
+	ErrSemaphoreNotAcquired = perrors.New("semaphore is not acquired")
+	// ErrSemaphoreAcquired semaphore is acquired again without releasing
+	ErrSemaphoreAcquired = perrors.New("semaphore is already acquired")
+	// ErrSessionDone the session of primitive is closed or expired
+	ErrSessionDone = perrors.New("etcd session is done")
+
+	semaphoreSeq uint64
+)
+
+// newSession returns the session of client if @ttl is not positive,
+// otherwise returns a new session whose lease ttl is @ttl seconds.
+func (c *Client) newSession(ttl int) (*concurrency.Session, bool, error) {
+	rawClient := c.GetRawClient()
+	if rawClient == nil {
+		return nil, false, ErrNilETCDV3Client
+	}
+	if ttl <= 0 {
+		s := c.GetSession()
+		if s == nil {
+			return nil, false, ErrNilETCDV3Client
+		}
+		return s, false, nil
+	}
+
+	s, err := concurrency.NewSession(rawClient, concurrency.WithTTL(ttl), concurrency.WithContext(c.GetCtx()))
+	if err != nil {
+		return nil, false, perrors.WithMessage(err, "new session with server")
+	}
+	return s, true, nil
+}
+
+// aliveSession returns the current session of client, which is replaced
+// when the client reconnects. ErrSessionDone is returned if the session
+// is lost and the client has not reconnected yet.
+func (c *Client) aliveSession() (*concurrency.Session, error) {
+	s := c.GetSession()
+	if s == nil {
+		return nil, ErrNilETCDV3Client
+	}
+	select {
+	case <-s.Done():
+		return nil, ErrSessionDone
+	default:
+		return s, nil
+	}
+}
+
+// Mutex is a distributed lock, the lock is released when its session expires
+type Mutex struct {
+	client *Client
+	key    string
+	owned  bool // whether the session is created for the mutex
+
+	lock    sync.Mutex // for session and mutex
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+// NewMutex creates a Mutex on @key with the session of client, so the
+// lock will be released when the client loses its session. Lock uses the
+// new session of client after it reconnects.
+// The Mutexes on the same @key of one client share the lock, use
+// NewMutexWithTTL to exclude each other in the same process.
+func (c *Client) NewMutex(key string) (*Mutex, error) {
+	return c.NewMutexWithTTL(key, 0)
+}
+
+// NewMutexWithTTL creates a Mutex on @key with a dedicated session whose
+// ttl is @ttl seconds, the lock will be released in @ttl seconds after the
+// holder crashes. The Mutex should be closed when it is no longer used, and
+// ErrSessionDone is returned by Lock once the dedicated session expires.
+func (c *Client) NewMutexWithTTL(key string, ttl int) (*Mutex, error) {
+	s, owned, err := c.newSession(ttl)
+	if err != nil {
+		return nil, perrors.WithMessagef(err, "new mutex (key %s)", key)
+	}
+	return &Mutex{
+		client:  c,
+		key:     key,
+		owned:   owned,
+		session: s,
+		mutex:   concurrency.NewMutex(s, key),
+	}, nil
+}
+
+// resolve returns the mutex on the alive session
+func (m *Mutex) resolve() (*concurrency.Mutex, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.owned {
+		select {
+		case <-m.session.Done():
+			return nil, ErrSessionDone
+		default:
+			return m.mutex, nil
+		}
+	}
+	s, err := m.client.aliveSession()
+	if err != nil {
+		return nil, err
+	}
+	if s != m.session {
+		m.session, m.mutex = s, concurrency.NewMutex(s, m.key)
+	}
+	return m.mutex, nil
+}
+
+func (m *Mutex) current() *concurrency.Mutex {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.mutex
+}
+
+// Lock blocks until the lock is acquired or @ctx is done
+func (m *Mutex) Lock(ctx context.Context) error {
+	mutex, err := m.resolve()
+	if err != nil {
+		return perrors.WithMessagef(err, "lock (key %s)", m.key)
+	}
+	err = mutex.Lock(ctx)
+	return perrors.WithMessagef(err, "lock (key %s)", mutex.Key())
+}
+
+// TryLock acquires the lock without blocking, it returns concurrency.ErrLocked
+// if the lock is held by others
+func (m *Mutex) TryLock(ctx context.Context) error {
+	mutex, err := m.resolve()
+	if err != nil {
+		return perrors.WithMessagef(err, "try lock (key %s)", m.key)
+	}
+	err = mutex.TryLock(ctx)
+	if err == concurrency.ErrLocked {
+		return err
+	}
+	return perrors.WithMessagef(err, "try lock (key %s)", mutex.Key())
+}
+
+// Unlock releases the lock
+func (m *Mutex) Unlock(ctx context.Context) error {
+	mutex := m.current()
+	err := mutex.Unlock(ctx)
+	return perrors.WithMessagef(err, "unlock (key %s)", mutex.Key())
+}
+
+// Key returns the key of the lock holder, it is empty before locking
+func (m *Mutex) Key() string {
+	return m.current().Key()
+}
+
+// Close closes the dedicated session of the mutex, and the lock is released.
+func (m *Mutex) Close() error {
+	if !m.owned {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.session.Close()
+}
+
+// Election is a leader election on a key prefix
+type Election struct {
+	client *Client
+	prefix string
+
+	lock     sync.RWMutex // for session, election and leader
+	session  *concurrency.Session
+	election *concurrency.Election
+	leader   bool
+}
+
+// NewElection creates an Election on @prefix with the session of client.
+// One client should take part in an election only once. The leadership is
+// lost with the session, and Campaign uses the new session of client after
+// it reconnects.
+func (c *Client) NewElection(prefix string) (*Election, error) {
+	s, _, err := c.newSession(0)
+	if err != nil {
+		return nil, perrors.WithMessagef(err, "new election (prefix %s)", prefix)
+	}
+	return &Election{
+		client:   c,
+		prefix:   prefix,
+		session:  s,
+		election: concurrency.NewElection(s, prefix),
+	}, nil
+}
+
+// resolve returns the election on the alive session
+func (e *Election) resolve() (*concurrency.Election, error) {
+	s, err := e.client.aliveSession()
+	if err != nil {
+		return nil, err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if s != e.session {
+		e.session, e.election = s, concurrency.NewElection(s, e.prefix)
+		e.leader = false
+	}
+	return e.election, nil
+}
+
+func (e *Election) current() *concurrency.Election {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.election
+}
+
+// Campaign blocks until it is elected as leader with @val, an error is returned
+// if @ctx is done
+func (e *Election) Campaign(ctx context.Context, val string) error {
+	election, err := e.resolve()
+	if err == nil {
+		err = election.Campaign(ctx, val)
+	}
+	if err != nil {
+		return perrors.WithMessagef(err, "campaign (value %s)", val)
+	}
+	e.lock.Lock()
+	e.leader = e.election == election
+	e.lock.Unlock()
+	return nil
+}
+
+// Proclaim updates the value of the leader without another election
+func (e *Election) Proclaim(ctx context.Context, val string) error {
+	err := e.current().Proclaim(ctx, val)
+	return perrors.WithMessagef(err, "proclaim (value %s)", val)
+}
+
+// Resign gives up the leadership
+func (e *Election) Resign(ctx context.Context) error {
+	e.lock.Lock()
+	e.leader = false
+	election := e.election
+	e.lock.Unlock()
+	return perrors.WithMessage(election.Resign(ctx), "resign")
+}
+
+// IsLeader returns true if it is the leader and its session is alive
+func (e *Election) IsLeader() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	select {
+	case <-e.session.Done():
+		return false
+	default:
+		return e.leader
+	}
+}
+
+// Leader returns the value of the current leader, ErrKVPairNotFound is
+// returned if there is no leader
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	election, err := e.resolve()
+	if err != nil {
+		return "", perrors.WithMessage(err, "get leader")
+	}
+	resp, err := election.Leader(ctx)
+	if err == concurrency.ErrElectionNoLeader {
+		return "", ErrKVPairNotFound
+	}
+	if err != nil {
+		return "", perrors.WithMessage(err, "get leader")
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Observe returns a channel which receives the value of the leader when
+// it changes. The channel is closed when @ctx is done or the session of
+// client is lost.
+func (e *Election) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	election, err := e.resolve()
+	if err != nil {
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer close(ch)
+		for resp := range election.Observe(ctx) {
+			if len(resp.Kvs) == 0 {
+				continue
+			}
+			select {
+			case ch <- string(resp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Semaphore is a distributed counting semaphore which allows at most @permits
+// holders at the same time. The holders are ordered by the create revision of
+// their keys under the prefix.
+type Semaphore struct {
+	client  *Client
+	session *concurrency.Session
+	prefix  string
+	permits int
+
+	myKey string
+}
+
+// NewSemaphore creates a Semaphore on @prefix with the session of client,
+// Acquire uses the new session of client after it reconnects.
+func (c *Client) NewSemaphore(prefix string, permits int) (*Semaphore, error) {
+	if permits <= 0 {
+		return nil, perrors.Errorf("new semaphore (prefix %s): illegal permits %d", prefix, permits)
+	}
+	s, _, err := c.newSession(0)
+	if err != nil {
+		return nil, perrors.WithMessagef(err, "new semaphore (prefix %s)", prefix)
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Semaphore{
+		client:  c,
+		session: s,
+		prefix:  prefix,
+		permits: permits,
+	}, nil
+}
+
+// Acquire blocks until a permit is acquired or @ctx is done, it returns
+// ErrSemaphoreAcquired if the permit has been acquired and not released.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	acquired, rev, err := s.tryAcquire(ctx)
+	for err == nil && !acquired {
+		if err = s.waitRelease(ctx, rev); err != nil {
+			break
+		}
+		acquired, rev, err = s.check(ctx)
+	}
+	if err != nil {
+		// the permit held before is kept
+		if err != ErrSemaphoreAcquired {
+			s.release()
+		}
+		return perrors.WithMessagef(err, "acquire semaphore (prefix %s)", s.prefix)
+	}
+	return nil
+}
+
+// TryAcquire acquires a permit without blocking, it returns false if
+// there is no permit left.
+func (s *Semaphore) TryAcquire(ctx context.Context) (bool, error) {
+	acquired, _, err := s.tryAcquire(ctx)
+	if (err != nil || !acquired) && err != ErrSemaphoreAcquired {
+		s.release()
+	}
+	return acquired, perrors.WithMessagef(err, "try acquire semaphore (prefix %s)", s.prefix)
+}
+
+// Release gives back the permit
+func (s *Semaphore) Release(ctx context.Context) error {
+	if s.myKey == "" {
+		return ErrSemaphoreNotAcquired
+	}
+	rawClient := s.client.GetRawClient()
+	if rawClient == nil {
+		return ErrNilETCDV3Client
+	}
+	if _, err := rawClient.Delete(ctx, s.myKey); err != nil {
+		return perrors.WithMessagef(err, "release semaphore (key %s)", s.myKey)
+	}
+	s.myKey = ""
+	return nil
+}
+
+func (s *Semaphore) tryAcquire(ctx context.Context) (bool, int64, error) {
+	if s.myKey != "" {
+		return false, 0, ErrSemaphoreAcquired
+	}
+	session, err := s.client.aliveSession()
+	if err != nil {
+		return false, 0, err
+	}
+	s.session = session
+	rawClient := s.client.GetRawClient()
+	if rawClient == nil {
+		return false, 0, ErrNilETCDV3Client
+	}
+
+	s.myKey = fmt.Sprintf("%s%x-%d", s.prefix, s.session.Lease(), atomic.AddUint64(&semaphoreSeq, 1))
+	if _, err := rawClient.Put(ctx, s.myKey, "", clientv3.WithLease(s.session.Lease())); err != nil {
+		return false, 0, err
+	}
+	return s.check(ctx)
+}
+
+// check returns whether the key is one of the first @permits keys, and the
+// revision of the check.
+func (s *Semaphore) check(ctx context.Context) (bool, int64, error) {
+	rawClient := s.client.GetRawClient()
+	if rawClient == nil {
+		return false, 0, ErrNilETCDV3Client
+	}
+	resp, err := rawClient.Get(ctx, s.prefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend),
+		clientv3.WithLimit(int64(s.permits)), clientv3.WithKeysOnly())
+	if err != nil {
+		return false, 0, err
+	}
+	for _, kv := range resp.Kvs {
+		if string(kv.Key) == s.myKey {
+			return true, resp.Header.Revision, nil
+		}
+	}
+	return false, resp.Header.Revision, nil
+}
+
+// waitRelease blocks until any key is deleted after @rev
+func (s *Semaphore) waitRelease(ctx context.Context, rev int64) error {
+	rawClient := s.client.GetRawClient()
+	if rawClient == nil {
+		return ErrNilETCDV3Client
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wc := rawClient.Watch(wctx, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1), clientv3.WithFilterPut())
+	for {
+		select {
+		case wr, ok := <-wc:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return ErrSessionDone
+			}
+			if err := wr.Err(); err != nil {
+				return err
+			}
+			if len(wr.Events) > 0 {
+				return nil
+			}
+		case <-s.session.Done():
+			return ErrSessionDone
+		}
+	}
+}
+
+// release deletes the key created by a failed acquisition
+func (s *Semaphore) release() {
+	if s.myKey == "" {
+		return
+	}
+	if rawClient := s.client.GetRawClient(); rawClient != nil {
+		_, _ = rawClient.Delete(s.client.GetCtx(), s.myKey)
+	}
+	s.myKey = ""
+}
@@ -0,0 +1,190 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func (suite *ClientTestSuite) TestMutex() {
+	t := suite.T()
+	c1 := suite.client
+	c2 := suite.setUpClient()
+	defer c1.Close()
+	defer c2.Close()
+
+	m1, err := c1.NewMutex("lock/job")
+	assert.NoError(t, err)
+	m2, err := c2.NewMutexWithTTL("lock/job", 5)
+	assert.NoError(t, err)
+	defer m2.Close()
+
+	assert.NoError(t, m1.Lock(context.Background()))
+	assert.Equal(t, concurrency.ErrLocked, m2.TryLock(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, m2.Lock(ctx))
+
+	assert.NoError(t, m1.Unlock(context.Background()))
+	assert.NoError(t, m2.Lock(context.Background()))
+	assert.NotEmpty(t, m2.Key())
+	assert.NoError(t, m2.Unlock(context.Background()))
+}
+
+func (suite *ClientTestSuite) TestElection() {
+	t := suite.T()
+	c1 := suite.client
+	c2 := suite.setUpClient()
+	defer c1.Close()
+	defer c2.Close()
+
+	e1, err := c1.NewElection("election/job")
+	assert.NoError(t, err)
+	e2, err := c2.NewElection("election/job")
+	assert.NoError(t, err)
+
+	_, err = e1.Leader(context.Background())
+	assert.Equal(t, ErrKVPairNotFound, err)
+
+	assert.NoError(t, e1.Campaign(context.Background(), "node1"))
+	assert.True(t, e1.IsLeader())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observe := e2.Observe(ctx)
+	assert.Equal(t, "node1", <-observe)
+
+	elected := make(chan struct{})
+	go func() {
+		assert.NoError(t, e2.Campaign(context.Background(), "node2"))
+		close(elected)
+	}()
+	assert.NoError(t, e1.Resign(context.Background()))
+	assert.False(t, e1.IsLeader())
+	<-elected
+	assert.True(t, e2.IsLeader())
+	assert.Equal(t, "node2", <-observe)
+
+	leader, err := e1.Leader(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "node2", leader)
+}
+
+func (suite *ClientTestSuite) TestSemaphore() {
+	t := suite.T()
+	c := suite.client
+	defer c.Close()
+
+	_, err := c.NewSemaphore("semaphore/job", 0)
+	assert.Error(t, err)
+
+	s1, err := c.NewSemaphore("semaphore/job", 2)
+	assert.NoError(t, err)
+	s2, err := c.NewSemaphore("semaphore/job", 2)
+	assert.NoError(t, err)
+	s3, err := c.NewSemaphore("semaphore/job", 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrSemaphoreNotAcquired, s1.Release(context.Background()))
+	assert.NoError(t, s1.Acquire(context.Background()))
+	// acquiring again keeps the permit held
+	assert.Equal(t, ErrSemaphoreAcquired, perrors.Cause(s1.Acquire(context.Background())))
+	ok, err := s1.TryAcquire(context.Background())
+	assert.Equal(t, ErrSemaphoreAcquired, perrors.Cause(err))
+	assert.False(t, ok)
+	ok, err = s2.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = s3.TryAcquire(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	acquired := make(chan struct{})
+	go func() {
+		assert.NoError(t, s3.Acquire(context.Background()))
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("s3 should wait for a permit")
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.NoError(t, s1.Release(context.Background()))
+	<-acquired
+	assert.NoError(t, s2.Release(context.Background()))
+	assert.NoError(t, s3.Release(context.Background()))
+}
+
+func TestPrimitivesAfterReconnect(t *testing.T) {
+	kv := NewMockKV()
+	client, err := NewMockClient("mock", kv)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	m, err := client.NewMutex("lock/job")
+	assert.Nil(t, err)
+	e, err := client.NewElection("election/job")
+	assert.Nil(t, err)
+	s, err := client.NewSemaphore("semaphore/job", 1)
+	assert.Nil(t, err)
+	assert.Nil(t, e.Campaign(context.Background(), "node1"))
+	assert.True(t, e.IsLeader())
+
+	// the session is lost
+	lease := client.GetSession().Lease()
+	_, err = kv.Revoke(context.Background(), lease)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return !client.Valid()
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, e.IsLeader())
+	assert.Equal(t, ErrSessionDone, perrors.Cause(m.Lock(context.Background())))
+	assert.Equal(t, ErrSessionDone, perrors.Cause(e.Campaign(context.Background(), "node1")))
+	assert.Equal(t, ErrSessionDone, perrors.Cause(s.Acquire(context.Background())))
+
+	// the primitives use the new session after the client reconnects
+	assert.Nil(t, sharedClient{client}.Reconnect())
+	newLease := client.GetSession().Lease()
+	assert.NotEqual(t, lease, newLease)
+
+	assert.Nil(t, m.Lock(context.Background()))
+	resp, err := kv.Get(context.Background(), m.Key())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(newLease), resp.Kvs[0].Lease)
+	assert.Nil(t, m.Unlock(context.Background()))
+
+	assert.Nil(t, e.Campaign(context.Background(), "node2"))
+	assert.True(t, e.IsLeader())
+	leader, err := e.Leader(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "node2", leader)
+
+	assert.Nil(t, s.Acquire(context.Background()))
+	assert.Nil(t, s.Release(context.Background()))
+}
@@ -0,0 +1,915 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+import (
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"google.golang.org/grpc"
+)
+
+// MockKV is an in-memory etcd server for tests. It implements clientv3.KV,
+// clientv3.Watcher and clientv3.Lease with the revision, compaction, transaction
+// and watch semantics of etcd. The KV requests are served at the level of the
+// etcd protocol by clientv3.NewKVFromKVClient. The leases never expire by time, they are only
+// removed by Revoke or by closing the raw client which granted them.
+type MockKV struct {
+	lock       sync.Mutex
+	rev        int64
+	compactRev int64
+	kvs        map[string]*mvccpb.KeyValue
+	// history keeps the events since compactRev, and PrevKv of them is always set
+	history   []*clientv3.Event
+	leases    map[clientv3.LeaseID]*mockLease
+	lastLease clientv3.LeaseID
+	watchers  map[*mockWatcher]struct{}
+	done      chan struct{} // closed by Close to stop all the watchers and keep alives
+	// api converts the clientv3.Op into the etcd requests served by mockKVServer,
+	// so all the options of the Op are read from the exported request fields
+	api clientv3.KV
+}
+
+type mockLease struct {
+	ttl     int64
+	keys    map[string]struct{}
+	revoked chan struct{}
+}
+
+type mockWatcher struct {
+	key          []byte
+	end          []byte
+	startRev     int64
+	prevKV       bool
+	filterPut    bool
+	filterDelete bool
+	pending      []clientv3.WatchResponse // guarded by MockKV.lock
+	notify       chan struct{}
+	ch           chan clientv3.WatchResponse
+}
+
+var (
+	_ clientv3.KV      = (*MockKV)(nil)
+	_ clientv3.Watcher = (*MockKV)(nil)
+	_ clientv3.Lease   = (*MockKV)(nil)
+)
+
+// NewMockKV returns an empty MockKV whose revision is 1 like a new etcd server
+func NewMockKV() *MockKV {
+	m := &MockKV{
+		rev:      1,
+		kvs:      make(map[string]*mvccpb.KeyValue),
+		leases:   make(map[clientv3.LeaseID]*mockLease),
+		watchers: make(map[*mockWatcher]struct{}),
+		done:     make(chan struct{}),
+	}
+	m.api = clientv3.NewKVFromKVClient(mockKVServer{m}, nil)
+	return m
+}
+
+// NewMockClient returns a Client backed by @kv instead of the etcd server,
+// the session of the client is also kept by @kv.
+func NewMockClient(name string, kv *MockKV) (*Client, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		name:      name,
+		heartbeat: 1,
+		parent:    context.Background(),
+		dial: func(context.Context) (*clientv3.Client, error) {
+			return kv.NewRawClient(), nil
+		},
+
+		ctx:       ctx,
+		cancel:    cancel,
+		rawClient: kv.NewRawClient(),
+
+		exit:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	if err := c.keepSession(); err != nil {
+		cancel()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewRawClient returns a clientv3.Client backed by the MockKV. Closing the raw
+// client stops its watchers and revokes the leases granted by it, as if they
+// are expired.
+func (m *MockKV) NewRawClient() *clientv3.Client {
+	rawClient := clientv3.NewCtxClient(context.Background())
+	c := &mockRawClient{MockKV: m, stop: make(chan struct{})}
+	rawClient.KV = m
+	rawClient.Watcher = c
+	rawClient.Lease = c
+	return rawClient
+}
+
+// Revision returns the current revision
+func (m *MockKV) Revision() int64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.rev
+}
+
+// Put puts @key with @val
+func (m *MockKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	return m.api.Put(ctx, key, val, opts...)
+}
+
+// Get gets @key
+func (m *MockKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return m.api.Get(ctx, key, opts...)
+}
+
+// Delete deletes @key
+func (m *MockKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	return m.api.Delete(ctx, key, opts...)
+}
+
+// Compact drops the history before @rev
+func (m *MockKV) Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return m.api.Compact(ctx, rev, opts...)
+}
+
+// Txn creates a transaction
+func (m *MockKV) Txn(ctx context.Context) clientv3.Txn {
+	return m.api.Txn(ctx)
+}
+
+// Do applies @op, all the writes of @op share one revision
+func (m *MockKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	return m.api.Do(ctx, op)
+}
+
+// mockKVServer serves the etcd KV requests of MockKV
+type mockKVServer struct {
+	m *MockKV
+}
+
+var _ pb.KVClient = mockKVServer{}
+
+func (s mockKVServer) Range(ctx context.Context, r *pb.RangeRequest, _ ...grpc.CallOption) (*pb.RangeResponse, error) {
+	resp, err := s.m.do(ctx, &pb.RequestOp{Request: &pb.RequestOp_RequestRange{RequestRange: r}})
+	return resp.GetResponseRange(), err
+}
+
+func (s mockKVServer) Put(ctx context.Context, r *pb.PutRequest, _ ...grpc.CallOption) (*pb.PutResponse, error) {
+	resp, err := s.m.do(ctx, &pb.RequestOp{Request: &pb.RequestOp_RequestPut{RequestPut: r}})
+	return resp.GetResponsePut(), err
+}
+
+func (s mockKVServer) DeleteRange(ctx context.Context, r *pb.DeleteRangeRequest, _ ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	resp, err := s.m.do(ctx, &pb.RequestOp{Request: &pb.RequestOp_RequestDeleteRange{RequestDeleteRange: r}})
+	return resp.GetResponseDeleteRange(), err
+}
+
+func (s mockKVServer) Txn(ctx context.Context, r *pb.TxnRequest, _ ...grpc.CallOption) (*pb.TxnResponse, error) {
+	resp, err := s.m.do(ctx, &pb.RequestOp{Request: &pb.RequestOp_RequestTxn{RequestTxn: r}})
+	return resp.GetResponseTxn(), err
+}
+
+func (s mockKVServer) Compact(ctx context.Context, r *pb.CompactionRequest, _ ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m := s.m
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if r.Revision <= m.compactRev {
+		return nil, rpctypes.ErrCompacted
+	}
+	if r.Revision > m.rev {
+		return nil, rpctypes.ErrFutureRev
+	}
+	m.compactRev = r.Revision
+	i := sort.Search(len(m.history), func(i int) bool {
+		return m.history[i].Kv.ModRevision >= r.Revision
+	})
+	m.history = append([]*clientv3.Event(nil), m.history[i:]...)
+	return &pb.CompactionResponse{Header: &pb.ResponseHeader{Revision: m.rev}}, nil
+}
+
+// do applies @req, all the writes of @req share one revision
+func (m *MockKV) do(ctx context.Context, req *pb.RequestOp) (*pb.ResponseOp, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	header := &pb.ResponseHeader{}
+	var events []*clientv3.Event
+	resp, err := m.apply(req, header, &events)
+	if err != nil {
+		m.undo(events)
+		return nil, err
+	}
+	m.commit(events)
+	header.Revision = m.rev
+	return resp, nil
+}
+
+// commit moves the revision forward and notifies the watchers, it should be called with the lock held
+func (m *MockKV) commit(events []*clientv3.Event) {
+	if len(events) == 0 {
+		return
+	}
+	m.rev++
+	m.history = append(m.history, events...)
+	m.notify(events)
+}
+
+// undo rolls back @events in reverse order, it should be called with the lock held
+func (m *MockKV) undo(events []*clientv3.Event) {
+	for i := len(events) - 1; i >= 0; i-- {
+		m.setKV(string(events[i].Kv.Key), events[i].PrevKv)
+	}
+}
+
+// setKV replaces the value of @key and maintains the keys of leases, @kv is nil for deletion
+func (m *MockKV) setKV(key string, kv *mvccpb.KeyValue) {
+	if prev, ok := m.kvs[key]; ok && prev.Lease != 0 {
+		if l, ok := m.leases[clientv3.LeaseID(prev.Lease)]; ok {
+			delete(l.keys, key)
+		}
+	}
+	if kv == nil {
+		delete(m.kvs, key)
+		return
+	}
+	m.kvs[key] = kv
+	if kv.Lease != 0 {
+		if l, ok := m.leases[clientv3.LeaseID(kv.Lease)]; ok {
+			l.keys[key] = struct{}{}
+		}
+	}
+}
+
+func (m *MockKV) apply(req *pb.RequestOp, header *pb.ResponseHeader, events *[]*clientv3.Event) (*pb.ResponseOp, error) {
+	switch r := req.Request.(type) {
+	case *pb.RequestOp_RequestRange:
+		resp, err := m.get(r.RequestRange, header)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: resp}}, nil
+	case *pb.RequestOp_RequestPut:
+		resp, err := m.put(r.RequestPut, header, events)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: resp}}, nil
+	case *pb.RequestOp_RequestDeleteRange:
+		resp := m.deleteRange(r.RequestDeleteRange, header, events)
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: resp}}, nil
+	case *pb.RequestOp_RequestTxn:
+		resp, err := m.txn(r.RequestTxn, header, events)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: resp}}, nil
+	default:
+		return nil, rpctypes.ErrEmptyKey
+	}
+}
+
+func (m *MockKV) get(r *pb.RangeRequest, header *pb.ResponseHeader) (*pb.RangeResponse, error) {
+	kvs := m.kvs
+	if rev := r.Revision; rev > 0 {
+		if rev < m.compactRev {
+			return nil, rpctypes.ErrCompacted
+		}
+		if rev > m.rev {
+			return nil, rpctypes.ErrFutureRev
+		}
+		kvs = m.kvsAt(rev)
+	}
+
+	var matched []*mvccpb.KeyValue
+	for k, kv := range kvs {
+		if !inRange([]byte(k), r.Key, r.RangeEnd) {
+			continue
+		}
+		if (r.MinModRevision > 0 && kv.ModRevision < r.MinModRevision) ||
+			(r.MaxModRevision > 0 && kv.ModRevision > r.MaxModRevision) ||
+			(r.MinCreateRevision > 0 && kv.CreateRevision < r.MinCreateRevision) ||
+			(r.MaxCreateRevision > 0 && kv.CreateRevision > r.MaxCreateRevision) {
+			continue
+		}
+		matched = append(matched, kv)
+	}
+	sortKVs(matched, r.SortTarget, r.SortOrder)
+
+	resp := &pb.RangeResponse{Header: header, Count: int64(len(matched))}
+	if r.CountOnly {
+		return resp, nil
+	}
+	if r.Limit > 0 && int64(len(matched)) > r.Limit {
+		matched = matched[:r.Limit]
+		resp.More = true
+	}
+	for _, kv := range matched {
+		clone := *kv
+		if r.KeysOnly {
+			clone.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, &clone)
+	}
+	return resp, nil
+}
+
+// kvsAt returns the key/values at @rev by undoing the later history
+func (m *MockKV) kvsAt(rev int64) map[string]*mvccpb.KeyValue {
+	kvs := make(map[string]*mvccpb.KeyValue, len(m.kvs))
+	for k, kv := range m.kvs {
+		kvs[k] = kv
+	}
+	for i := len(m.history) - 1; i >= 0 && m.history[i].Kv.ModRevision > rev; i-- {
+		e := m.history[i]
+		if e.PrevKv == nil {
+			delete(kvs, string(e.Kv.Key))
+		} else {
+			kvs[string(e.Kv.Key)] = e.PrevKv
+		}
+	}
+	return kvs
+}
+
+func (m *MockKV) put(r *pb.PutRequest, header *pb.ResponseHeader, events *[]*clientv3.Event) (*pb.PutResponse, error) {
+	key := string(r.Key)
+	prev := m.kvs[key]
+	val := r.Value
+	lease := clientv3.LeaseID(r.Lease)
+	if r.IgnoreValue || r.IgnoreLease {
+		if prev == nil {
+			return nil, rpctypes.ErrKeyNotFound
+		}
+		if r.IgnoreValue {
+			val = prev.Value
+		}
+		if r.IgnoreLease {
+			lease = clientv3.LeaseID(prev.Lease)
+		}
+	}
+	if _, ok := m.leases[lease]; lease != clientv3.NoLease && !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+
+	rev := m.rev + 1
+	kv := &mvccpb.KeyValue{
+		Key:            []byte(key),
+		Value:          append([]byte(nil), val...),
+		CreateRevision: rev,
+		ModRevision:    rev,
+		Version:        1,
+		Lease:          int64(lease),
+	}
+	if prev != nil {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+	}
+	m.setKV(key, kv)
+	*events = append(*events, &clientv3.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+
+	resp := &pb.PutResponse{Header: header}
+	if r.PrevKv && prev != nil {
+		clone := *prev
+		resp.PrevKv = &clone
+	}
+	return resp, nil
+}
+
+func (m *MockKV) deleteRange(r *pb.DeleteRangeRequest, header *pb.ResponseHeader, events *[]*clientv3.Event) *pb.DeleteRangeResponse {
+	var keys []string
+	for k := range m.kvs {
+		if inRange([]byte(k), r.Key, r.RangeEnd) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	resp := &pb.DeleteRangeResponse{Header: header, Deleted: int64(len(keys))}
+	rev := m.rev + 1
+	for _, k := range keys {
+		prev := m.kvs[k]
+		m.setKV(k, nil)
+		*events = append(*events, &clientv3.Event{
+			Type:   mvccpb.DELETE,
+			Kv:     &mvccpb.KeyValue{Key: []byte(k), ModRevision: rev},
+			PrevKv: prev,
+		})
+		if r.PrevKv {
+			clone := *prev
+			resp.PrevKvs = append(resp.PrevKvs, &clone)
+		}
+	}
+	return resp
+}
+
+func (m *MockKV) txn(r *pb.TxnRequest, header *pb.ResponseHeader, events *[]*clientv3.Event) (*pb.TxnResponse, error) {
+	succeeded := true
+	for _, cmp := range r.Compare {
+		if !m.compare(cmp) {
+			succeeded = false
+			break
+		}
+	}
+	reqs := r.Success
+	if !succeeded {
+		reqs = r.Failure
+	}
+
+	resp := &pb.TxnResponse{Header: header, Succeeded: succeeded}
+	for _, req := range reqs {
+		respOp, err := m.apply(req, header, events)
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, respOp)
+	}
+	return resp, nil
+}
+
+// compare checks @cmp against all the keys in its range, the absent key is
+// compared as a zero key/value except the value comparison which always fails.
+func (m *MockKV) compare(cmp *pb.Compare) bool {
+	var kvs []*mvccpb.KeyValue
+	if len(cmp.RangeEnd) == 0 {
+		if kv, ok := m.kvs[string(cmp.Key)]; ok {
+			kvs = append(kvs, kv)
+		}
+	} else {
+		for k, kv := range m.kvs {
+			if inRange([]byte(k), cmp.Key, cmp.RangeEnd) {
+				kvs = append(kvs, kv)
+			}
+		}
+	}
+	if len(kvs) == 0 {
+		if cmp.Target == pb.Compare_VALUE {
+			return false
+		}
+		kvs = append(kvs, &mvccpb.KeyValue{})
+	}
+
+	for _, kv := range kvs {
+		var r int
+		switch u := cmp.TargetUnion.(type) {
+		case *pb.Compare_Value:
+			r = bytes.Compare(kv.Value, u.Value)
+		case *pb.Compare_Version:
+			r = compareInt64(kv.Version, u.Version)
+		case *pb.Compare_CreateRevision:
+			r = compareInt64(kv.CreateRevision, u.CreateRevision)
+		case *pb.Compare_ModRevision:
+			r = compareInt64(kv.ModRevision, u.ModRevision)
+		case *pb.Compare_Lease:
+			r = compareInt64(kv.Lease, u.Lease)
+		default:
+			return false
+		}
+		var ok bool
+		switch cmp.Result {
+		case pb.Compare_EQUAL:
+			ok = r == 0
+		case pb.Compare_NOT_EQUAL:
+			ok = r != 0
+		case pb.Compare_GREATER:
+			ok = r > 0
+		case pb.Compare_LESS:
+			ok = r < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch watches @key, the history since the revision of clientv3.WithRev is
+// sent first.
+func (m *MockKV) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return m.watch(ctx, nil, key, opts...)
+}
+
+func (m *MockKV) watch(ctx context.Context, stop <-chan struct{}, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	w := &mockWatcher{
+		key:          op.KeyBytes(),
+		end:          op.RangeBytes(),
+		startRev:     op.Rev(),
+		prevKV:       hasOption(opts, clientv3.WithPrevKV()),
+		filterPut:    hasOption(opts, clientv3.WithFilterPut()),
+		filterDelete: hasOption(opts, clientv3.WithFilterDelete()),
+		notify:       make(chan struct{}, 1),
+		ch:           make(chan clientv3.WatchResponse),
+	}
+
+	m.lock.Lock()
+	done := m.done
+	if hasOption(opts, clientv3.WithCreatedNotify()) {
+		w.pending = append(w.pending, clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: m.rev}, Created: true})
+	}
+	if rev := op.Rev(); rev > 0 && rev < m.compactRev {
+		w.pending = append(w.pending, clientv3.WatchResponse{
+			Header:          pb.ResponseHeader{Revision: m.rev},
+			CompactRevision: m.compactRev,
+			Canceled:        true,
+		})
+	} else {
+		if rev > 0 {
+			i := sort.Search(len(m.history), func(i int) bool {
+				return m.history[i].Kv.ModRevision >= rev
+			})
+			for i < len(m.history) {
+				j := i
+				for j < len(m.history) && m.history[j].Kv.ModRevision == m.history[i].Kv.ModRevision {
+					j++
+				}
+				w.push(m.history[i:j], m.rev)
+				i = j
+			}
+		}
+		m.watchers[w] = struct{}{}
+	}
+	m.lock.Unlock()
+
+	go m.serve(ctx, stop, done, w)
+	return w.ch
+}
+
+// serve sends the pending responses of @w until it is canceled
+func (m *MockKV) serve(ctx context.Context, stop, done <-chan struct{}, w *mockWatcher) {
+	defer func() {
+		m.lock.Lock()
+		delete(m.watchers, w)
+		m.lock.Unlock()
+		close(w.ch)
+	}()
+
+	for {
+		m.lock.Lock()
+		var (
+			resp clientv3.WatchResponse
+			ok   bool
+		)
+		if len(w.pending) > 0 {
+			resp, ok = w.pending[0], true
+			w.pending = w.pending[1:]
+		}
+		m.lock.Unlock()
+
+		if !ok {
+			select {
+			case <-w.notify:
+				continue
+			case <-ctx.Done():
+			case <-stop:
+			case <-done:
+			}
+			return
+		}
+		select {
+		case w.ch <- resp:
+			if resp.Canceled {
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// notify dispatches @events to the watchers, it should be called with the lock held
+func (m *MockKV) notify(events []*clientv3.Event) {
+	for w := range m.watchers {
+		w.push(events, m.rev)
+	}
+}
+
+// push appends the events in the range of @w as a response
+func (w *mockWatcher) push(events []*clientv3.Event, rev int64) {
+	var matched []*clientv3.Event
+	for _, e := range events {
+		if e.Kv.ModRevision < w.startRev || !inRange(e.Kv.Key, w.key, w.end) ||
+			(w.filterPut && e.Type == mvccpb.PUT) ||
+			(w.filterDelete && e.Type == mvccpb.DELETE) {
+			continue
+		}
+		clone := *e
+		if !w.prevKV {
+			clone.PrevKv = nil
+		}
+		matched = append(matched, &clone)
+	}
+	if len(matched) == 0 {
+		return
+	}
+	w.pending = append(w.pending, clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: rev}, Events: matched})
+	w.wakeup()
+}
+
+func (w *mockWatcher) wakeup() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// RequestProgress sends a progress notification to all the watchers
+func (m *MockKV) RequestProgress(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for w := range m.watchers {
+		w.pending = append(w.pending, clientv3.WatchResponse{Header: pb.ResponseHeader{Revision: m.rev}})
+		w.wakeup()
+	}
+	return nil
+}
+
+// Close stops all the watchers and keep alives, the data is kept
+func (m *MockKV) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	close(m.done)
+	m.done = make(chan struct{})
+	return nil
+}
+
+// Grant creates a lease which lives until it is revoked
+func (m *MockKV) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lastLease++
+	m.leases[m.lastLease] = &mockLease{
+		ttl:     ttl,
+		keys:    make(map[string]struct{}),
+		revoked: make(chan struct{}),
+	}
+	return &clientv3.LeaseGrantResponse{
+		ResponseHeader: &pb.ResponseHeader{Revision: m.rev},
+		ID:             m.lastLease,
+		TTL:            ttl,
+	}, nil
+}
+
+// Revoke revokes the lease @id and deletes its keys
+func (m *MockKV) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	l, ok := m.leases[id]
+	if !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+
+	header := &pb.ResponseHeader{}
+	var events []*clientv3.Event
+	keys := make([]string, 0, len(l.keys))
+	for k := range l.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		m.deleteRange(&pb.DeleteRangeRequest{Key: []byte(k)}, header, &events)
+	}
+	delete(m.leases, id)
+	close(l.revoked)
+	m.commit(events)
+	header.Revision = m.rev
+	return &clientv3.LeaseRevokeResponse{Header: header}, nil
+}
+
+// TimeToLive returns the ttl and the keys of lease @id, the ttl is -1 if it is absent
+func (m *MockKV) TimeToLive(ctx context.Context, id clientv3.LeaseID, _ ...clientv3.LeaseOption) (*clientv3.LeaseTimeToLiveResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	resp := &clientv3.LeaseTimeToLiveResponse{
+		ResponseHeader: &pb.ResponseHeader{Revision: m.rev},
+		ID:             id,
+		TTL:            -1,
+	}
+	if l, ok := m.leases[id]; ok {
+		resp.TTL = l.ttl
+		resp.GrantedTTL = l.ttl
+		for k := range l.keys {
+			resp.Keys = append(resp.Keys, []byte(k))
+		}
+	}
+	return resp, nil
+}
+
+// Leases lists all the leases
+func (m *MockKV) Leases(ctx context.Context) (*clientv3.LeaseLeasesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	resp := &clientv3.LeaseLeasesResponse{ResponseHeader: &pb.ResponseHeader{Revision: m.rev}}
+	for id := range m.leases {
+		resp.Leases = append(resp.Leases, clientv3.LeaseStatus{ID: id})
+	}
+	sort.Slice(resp.Leases, func(i, j int) bool {
+		return resp.Leases[i].ID < resp.Leases[j].ID
+	})
+	return resp, nil
+}
+
+// KeepAlive returns a channel which is closed when the lease is revoked or @ctx is done
+func (m *MockKV) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	return m.keepAlive(ctx, nil, id)
+}
+
+func (m *MockKV) keepAlive(ctx context.Context, stop <-chan struct{}, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	resp, err := m.KeepAliveOnce(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	l, ok := m.leases[id]
+	done := m.done
+	m.lock.Unlock()
+	if !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+
+	ch := make(chan *clientv3.LeaseKeepAliveResponse, 1)
+	ch <- resp
+	go func() {
+		defer close(ch)
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		case <-done:
+		case <-l.revoked:
+		}
+	}()
+	return ch, nil
+}
+
+// KeepAliveOnce returns the ttl of the lease @id
+func (m *MockKV) KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	l, ok := m.leases[id]
+	if !ok {
+		return nil, rpctypes.ErrLeaseNotFound
+	}
+	return &clientv3.LeaseKeepAliveResponse{
+		ResponseHeader: &pb.ResponseHeader{Revision: m.rev},
+		ID:             id,
+		TTL:            l.ttl,
+	}, nil
+}
+
+// mockRawClient is the Watcher and Lease of a raw client created by MockKV.NewRawClient
+type mockRawClient struct {
+	*MockKV
+	stop      chan struct{}
+	closeOnce sync.Once
+	leaseLock sync.Mutex
+	leases    []clientv3.LeaseID
+}
+
+func (c *mockRawClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	return c.watch(ctx, c.stop, key, opts...)
+}
+
+func (c *mockRawClient) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	resp, err := c.MockKV.Grant(ctx, ttl)
+	if err == nil {
+		c.leaseLock.Lock()
+		c.leases = append(c.leases, resp.ID)
+		c.leaseLock.Unlock()
+	}
+	return resp, err
+}
+
+func (c *mockRawClient) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	return c.keepAlive(ctx, c.stop, id)
+}
+
+// Close is called twice by clientv3.Client.Close, as both the Watcher and the Lease
+func (c *mockRawClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.leaseLock.Lock()
+		leases := c.leases
+		c.leases = nil
+		c.leaseLock.Unlock()
+		for _, id := range leases {
+			_, _ = c.MockKV.Revoke(context.Background(), id)
+		}
+	})
+	return nil
+}
+
+// hasOption reports whether @opts contains @opt. The watch options are not in
+// the exported fields of clientv3.Op, and the ones without parameter like
+// clientv3.WithPrevKV are always the same function, so they are compared by
+// their function pointers.
+func hasOption(opts []clientv3.OpOption, opt clientv3.OpOption) bool {
+	p := reflect.ValueOf(opt).Pointer()
+	for _, o := range opts {
+		if o != nil && reflect.ValueOf(o).Pointer() == p {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKVs sorts @kvs by @target in @order like etcd, the order is ascending
+// if it is not specified
+func sortKVs(kvs []*mvccpb.KeyValue, target pb.RangeRequest_SortTarget, order pb.RangeRequest_SortOrder) {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		var r int
+		switch target {
+		case pb.RangeRequest_VERSION:
+			r = compareInt64(kvs[i].Version, kvs[j].Version)
+		case pb.RangeRequest_CREATE:
+			r = compareInt64(kvs[i].CreateRevision, kvs[j].CreateRevision)
+		case pb.RangeRequest_MOD:
+			r = compareInt64(kvs[i].ModRevision, kvs[j].ModRevision)
+		case pb.RangeRequest_VALUE:
+			r = bytes.Compare(kvs[i].Value, kvs[j].Value)
+		}
+		if r == 0 {
+			r = bytes.Compare(kvs[i].Key, kvs[j].Key)
+		}
+		if order == pb.RangeRequest_DESCEND {
+			return r > 0
+		}
+		return r < 0
+	})
+}
+
+// inRange checks whether @key is in [begin, end), and only @begin itself matches
+// if @end is empty, all the keys not less than @begin match if @end is "\x00".
+func inRange(key, begin, end []byte) bool {
+	switch {
+	case len(end) == 0:
+		return bytes.Equal(key, begin)
+	case len(end) == 1 && end[0] == 0:
+		return bytes.Compare(key, begin) >= 0
+	default:
+		return bytes.Compare(key, begin) >= 0 && bytes.Compare(key, end) < 0
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
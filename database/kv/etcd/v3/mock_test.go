@@ -0,0 +1,266 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestMockKVPutGetDelete(t *testing.T) {
+	kv := NewMockKV()
+	ctx := context.Background()
+
+	resp, err := kv.Put(ctx, "/a/1", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), resp.Header.Revision)
+	_, err = kv.Put(ctx, "/a/2", "v2")
+	assert.Nil(t, err)
+	resp, err = kv.Put(ctx, "/a/1", "v11", clientv3.WithPrevKV())
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(resp.PrevKv.Value))
+
+	getResp, err := kv.Get(ctx, "/a/1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(getResp.Kvs))
+	assert.Equal(t, "v11", string(getResp.Kvs[0].Value))
+	assert.Equal(t, int64(2), getResp.Kvs[0].Version)
+	assert.Equal(t, int64(2), getResp.Kvs[0].CreateRevision)
+	assert.Equal(t, int64(4), getResp.Kvs[0].ModRevision)
+
+	getResp, err = kv.Get(ctx, "/a/", clientv3.WithPrefix())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), getResp.Count)
+	assert.Equal(t, "/a/1", string(getResp.Kvs[0].Key))
+
+	getResp, err = kv.Get(ctx, "/a/", append(clientv3.WithLastCreate(), clientv3.WithPrefix())...)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(getResp.Kvs))
+	assert.True(t, getResp.More)
+	assert.Equal(t, "/a/2", string(getResp.Kvs[0].Key))
+
+	// read the history
+	getResp, err = kv.Get(ctx, "/a/1", clientv3.WithRev(2))
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(getResp.Kvs[0].Value))
+
+	delResp, err := kv.Delete(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithPrevKV())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), delResp.Deleted)
+	assert.Equal(t, 2, len(delResp.PrevKvs))
+	assert.Equal(t, int64(5), kv.Revision())
+
+	getResp, err = kv.Get(ctx, "/a/1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(getResp.Kvs))
+
+	_, err = kv.Compact(ctx, 4)
+	assert.Nil(t, err)
+	_, err = kv.Get(ctx, "/a/1", clientv3.WithRev(3))
+	assert.Equal(t, rpctypes.ErrCompacted, err)
+	_, err = kv.Get(ctx, "/a/1", clientv3.WithRev(6))
+	assert.Equal(t, rpctypes.ErrFutureRev, err)
+}
+
+func TestMockKVTxn(t *testing.T) {
+	kv := NewMockKV()
+	ctx := context.Background()
+
+	create := func(val string) (*clientv3.TxnResponse, error) {
+		return kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision("k"), "=", 0)).
+			Then(clientv3.OpPut("k", val), clientv3.OpPut("k2", val)).
+			Else(clientv3.OpGet("k")).
+			Commit()
+	}
+	resp, err := create("v1")
+	assert.Nil(t, err)
+	assert.True(t, resp.Succeeded)
+	// all the writes of a txn share one revision
+	assert.Equal(t, int64(2), kv.Revision())
+
+	resp, err = create("v2")
+	assert.Nil(t, err)
+	assert.False(t, resp.Succeeded)
+	assert.Equal(t, "v1", string(resp.Responses[0].GetResponseRange().Kvs[0].Value))
+
+	resp, err = kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value("k"), "=", "v1")).
+		Then(clientv3.OpDelete("k")).
+		Commit()
+	assert.Nil(t, err)
+	assert.True(t, resp.Succeeded)
+	assert.Equal(t, int64(1), resp.Responses[0].GetResponseDeleteRange().Deleted)
+
+	// the failed txn is rolled back
+	_, err = kv.Txn(ctx).
+		Then(clientv3.OpPut("k3", "v"), clientv3.OpPut("k4", "v", clientv3.WithLease(100))).
+		Commit()
+	assert.Equal(t, rpctypes.ErrLeaseNotFound, err)
+	getResp, err := kv.Get(ctx, "k3")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(getResp.Kvs))
+	assert.Equal(t, int64(3), kv.Revision())
+}
+
+func TestMockKVWatch(t *testing.T) {
+	kv := NewMockKV()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, _ = kv.Put(ctx, "/w/1", "v1")
+	wc := kv.Watch(ctx, "/w/", clientv3.WithPrefix(), clientv3.WithPrevKV())
+	_, _ = kv.Put(ctx, "/w/1", "v2")
+	_, _ = kv.Put(ctx, "/x", "v")
+	_, _ = kv.Delete(ctx, "/w/1")
+
+	resp := <-wc
+	assert.Equal(t, 1, len(resp.Events))
+	assert.Equal(t, mvccpb.PUT, resp.Events[0].Type)
+	assert.Equal(t, "v2", string(resp.Events[0].Kv.Value))
+	assert.Equal(t, "v1", string(resp.Events[0].PrevKv.Value))
+	resp = <-wc
+	assert.Equal(t, mvccpb.DELETE, resp.Events[0].Type)
+	assert.Equal(t, int64(5), resp.Events[0].Kv.ModRevision)
+
+	// replay the history
+	replay := kv.Watch(ctx, "/w/1", clientv3.WithRev(2))
+	for _, val := range []string{"v1", "v2", ""} {
+		resp = <-replay
+		assert.Equal(t, val, string(resp.Events[0].Kv.Value))
+	}
+
+	assert.Nil(t, kv.RequestProgress(ctx))
+	resp = <-wc
+	assert.True(t, resp.IsProgressNotify())
+	assert.Equal(t, int64(5), resp.Header.Revision)
+
+	_, err := kv.Compact(ctx, 4)
+	assert.Nil(t, err)
+	compacted := kv.Watch(ctx, "/w/1", clientv3.WithRev(2))
+	resp = <-compacted
+	assert.Equal(t, rpctypes.ErrCompacted, resp.Err())
+	_, ok := <-compacted
+	assert.False(t, ok)
+
+	cancel()
+	_, ok = <-wc
+	assert.False(t, ok)
+}
+
+func TestMockKVLease(t *testing.T) {
+	kv := NewMockKV()
+	ctx := context.Background()
+	rawClient := kv.NewRawClient()
+
+	lease, err := rawClient.Grant(ctx, 10)
+	assert.Nil(t, err)
+	_, err = rawClient.Put(ctx, "tmp", "v", clientv3.WithLease(lease.ID))
+	assert.Nil(t, err)
+	ttlResp, err := rawClient.TimeToLive(ctx, lease.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), ttlResp.TTL)
+	assert.Equal(t, [][]byte{[]byte("tmp")}, ttlResp.Keys)
+
+	keepAlive, err := rawClient.KeepAlive(ctx, lease.ID)
+	assert.Nil(t, err)
+	<-keepAlive
+
+	// closing the client revokes its leases
+	_ = rawClient.Close()
+	_, ok := <-keepAlive
+	assert.False(t, ok)
+	getResp, err := kv.Get(ctx, "tmp")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(getResp.Kvs))
+	leases, err := kv.Leases(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(leases.Leases))
+}
+
+func TestMockClient(t *testing.T) {
+	kv := NewMockKV()
+	client, err := NewMockClient("mock", kv)
+	assert.Nil(t, err)
+	assert.True(t, client.Valid())
+
+	assert.Nil(t, client.Create("/mock/k", "v"))
+	assert.Equal(t, ErrCompareFail, perrors.Cause(client.Create("/mock/k", "v")))
+	val, err := client.Get("/mock/k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", val)
+	assert.Nil(t, client.RegisterTemp("/mock/tmp", "v"))
+	kList, _, err := client.GetChildrenKVList("/mock/")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/mock/k", "/mock/tmp"}, kList)
+
+	m, err := client.NewMutex("/mock/lock")
+	assert.Nil(t, err)
+	assert.Nil(t, m.Lock(context.Background()))
+	// the mutex with another session excludes the former one
+	m2, err := client.NewMutexWithTTL("/mock/lock", 10)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, m2.Lock(ctx))
+	assert.Nil(t, m.Unlock(context.Background()))
+	assert.Nil(t, m2.TryLock(context.Background()))
+	assert.Nil(t, m2.Close())
+
+	client.Close()
+	getResp, err := kv.Get(context.Background(), "/mock/tmp")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(getResp.Kvs))
+	getResp, err = kv.Get(context.Background(), "/mock/k")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(getResp.Kvs))
+}
+
+func TestMockClientReconnect(t *testing.T) {
+	kv := NewMockKV()
+	client, err := NewMockClient("mock", kv)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	// the session is lost
+	_, err = kv.Revoke(context.Background(), client.GetSession().Lease())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return !client.Valid()
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, ErrNilETCDV3Client, perrors.Cause(client.Put("/mock/k", "v")))
+
+	assert.Nil(t, sharedClient{client}.Reconnect())
+	assert.True(t, client.Valid())
+	assert.Nil(t, client.Put("/mock/k", "v"))
+	val, err := client.Get("/mock/k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", val)
+}
@@ -51,6 +51,8 @@ type Options struct {
 	Password string
 	// TLS holds the client secure credentials, if any.
 	TLS *tls.Config
+	// Share shares the client with the ones of the same Name
+	Share bool
 }
 
 // Option will define a function of handling Options
@@ -98,3 +100,10 @@ func WithTLS(tls *tls.Config) Option {
 		opt.TLS = tls
 	}
 }
+
+// WithShare shares the client with the ones of the same name
+func WithShare(share bool) Option {
+	return func(opt *Options) {
+		opt.Share = share
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
+// Store adapts Client to kv.Store
+type Store struct {
+	client *Client
+}
+
+var _ kv.Store = (*Store)(nil)
+
+// NewStore returns a kv.Store backed by @client, the client will be closed
+// when the store is closed.
+func NewStore(client *Client) *Store {
+	return &Store{client: client}
+}
+
+// Client returns the etcd client of the store
+func (s *Store) Client() *Client {
+	return s.client
+}
+
+// Get gets the value of @key
+func (s *Store) Get(key string) (string, error) {
+	v, err := s.client.Get(key)
+	if perrors.Cause(err) == ErrKVPairNotFound {
+		return "", kv.ErrKeyNotFound
+	}
+	return v, err
+}
+
+// Put creates or updates @key with @value
+func (s *Store) Put(key string, value string) error {
+	return s.client.Put(key, value)
+}
+
+// CreateEphemeral creates @key with @value bound to a lease kept alive by the client
+func (s *Store) CreateEphemeral(key string, value string) error {
+	return s.client.RegisterTemp(key, value)
+}
+
+// Delete deletes @key
+func (s *Store) Delete(key string) error {
+	return s.client.Delete(key)
+}
+
+// Children returns the names of the direct children of @key
+func (s *Store) Children(key string) ([]string, error) {
+	kList, _, err := s.client.GetChildrenKVList(key)
+	if perrors.Cause(err) == ErrKVPairNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kv.ChildNames(key, kList), nil
+}
+
+// WatchPrefix sends the existing keys under @prefix and their changes
+func (s *Store) WatchPrefix(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	w, err := s.client.newPrefixWatcher(prefix, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan kv.Event, watcherEventBufferSize)
+	go func() {
+		defer func() {
+			w.Close()
+			close(ch)
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-w.Events():
+				if !ok {
+					return
+				}
+				select {
+				case ch <- toKVEvent(e):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func toKVEvent(e Event) kv.Event {
+	switch e.Type {
+	case EventAdd:
+		return kv.Event{Type: kv.EventAdd, Key: e.Key, Value: e.Value}
+	case EventUpdate:
+		return kv.Event{Type: kv.EventUpdate, Key: e.Key, Value: e.Value}
+	default:
+		return kv.Event{Type: kv.EventDelete, Key: e.Key}
+	}
+}
+
+// Close closes the client
+func (s *Store) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
+func (suite *ClientTestSuite) TestStore() {
+	t := suite.T()
+	s := NewStore(suite.client)
+	defer s.Close()
+
+	// the existing keys are sent first
+	assert.NoError(t, s.Put("/dubbo/c", "3"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := s.WatchPrefix(ctx, "/dubbo/")
+	assert.NoError(t, err)
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/c", Value: "3"}, <-events)
+
+	_, err = s.Get("/dubbo/a")
+	assert.Equal(t, kv.ErrKeyNotFound, err)
+	assert.NoError(t, s.Put("/dubbo/a", "1"))
+	assert.NoError(t, s.Put("/dubbo/a", "2"))
+	assert.NoError(t, s.CreateEphemeral("/dubbo/b/p1", "p1"))
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/a", Value: "1"}, <-events)
+	assert.Equal(t, kv.Event{Type: kv.EventUpdate, Key: "/dubbo/a", Value: "2"}, <-events)
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/b/p1", Value: "p1"}, <-events)
+
+	v, err := s.Get("/dubbo/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", v)
+	children, err := s.Children("/dubbo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, children)
+	children, err = s.Children("/absent")
+	assert.NoError(t, err)
+	assert.Empty(t, children)
+
+	assert.NoError(t, s.Delete("/dubbo/b/p1"))
+	assert.Equal(t, kv.Event{Type: kv.EventDelete, Key: "/dubbo/b/p1"}, <-events)
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,403 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// watcherEventBufferSize is the buffer size of PrefixWatcher events
+	watcherEventBufferSize = 64
+	// watcherRetryInterval is the wait time before resuming a broken watch
+	watcherRetryInterval = time.Second
+)
+
+// EventType is the type of the PrefixWatcher event
+type EventType int
+
+const (
+	// EventAdd the key is created
+	EventAdd EventType = iota
+	// EventUpdate the value of the key is changed
+	EventUpdate
+	// EventDelete the key is deleted
+	EventDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdd:
+		return "add"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a key under the watched prefix
+type Event struct {
+	Type      EventType
+	Key       string
+	Value     string
+	PrevValue string
+	// Revision is the revision of the change, or the revision of the re-list
+	// if the change is found by comparing with the cache
+	Revision int64
+}
+
+// PrefixWatcher lists a prefix and watches it from the next revision of the
+// list. The broken watch is resumed from the last seen revision, and if the
+// revision has been compacted, the prefix is listed again and the differences
+// with the local cache are sent as events. The watch is also resumed with the
+// new raw client after the client reconnects. The cache is updated whether the
+// events are received or not, and the events not received yet are queued in
+// memory, so the caller only using the cache should not watch a busy prefix.
+type PrefixWatcher struct {
+	client *Client
+	prefix string
+
+	lock  sync.RWMutex // for cache & rev
+	cache map[string]string
+	rev   int64
+
+	// initial makes the watcher send the listed keys as EventAdd before watching
+	initial bool
+
+	queueLock sync.Mutex
+	queue     []Event       // the events waiting to be sent to events
+	queued    chan struct{} // wakes up deliver after an event is queued
+	events    chan Event
+	delivered chan struct{} // closed when deliver exits
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPrefixWatcher lists @prefix and starts watching it, the listed keys are
+// in the cache and are not sent as events
+func (c *Client) NewPrefixWatcher(prefix string) (*PrefixWatcher, error) {
+	return c.newPrefixWatcher(prefix, false)
+}
+
+func (c *Client) newPrefixWatcher(prefix string, initial bool) (*PrefixWatcher, error) {
+	rawClient := c.GetRawClient()
+	if rawClient == nil {
+		return nil, ErrNilETCDV3Client
+	}
+
+	// the ctx of client is cancelled once the session is lost, so the watcher
+	// lives with the parent of it to resume after the client reconnects
+	ctx, cancel := context.WithCancel(c.parent)
+	w := &PrefixWatcher{
+		client:    c,
+		prefix:    prefix,
+		cache:     make(map[string]string),
+		initial:   initial,
+		queued:    make(chan struct{}, 1),
+		events:    make(chan Event, watcherEventBufferSize),
+		delivered: make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	kvs, rev, err := w.list()
+	if err != nil {
+		cancel()
+		return nil, perrors.WithMessagef(err, "list prefix (key %s)", prefix)
+	}
+	w.cache, w.rev = kvs, rev
+
+	go w.deliver()
+	go w.run()
+	return w, nil
+}
+
+// Events returns the channel of the changes, it is closed when the watcher
+// or the client is closed
+func (w *PrefixWatcher) Events() <-chan Event {
+	return w.events
+}
+
+// Get gets the value of @key from the local cache
+func (w *PrefixWatcher) Get(key string) (string, bool) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	v, ok := w.cache[key]
+	return v, ok
+}
+
+// Snapshot returns a copy of the local cache
+func (w *PrefixWatcher) Snapshot() map[string]string {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	kvs := make(map[string]string, len(w.cache))
+	for k, v := range w.cache {
+		kvs[k] = v
+	}
+	return kvs
+}
+
+// Revision returns the last revision seen by the watcher
+func (w *PrefixWatcher) Revision() int64 {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.rev
+}
+
+// Close stops the watcher
+func (w *PrefixWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *PrefixWatcher) list() (map[string]string, int64, error) {
+	rawClient := w.client.GetRawClient()
+	if rawClient == nil {
+		return nil, ErrRevision, ErrNilETCDV3Client
+	}
+	ctx, cancel := w.clientCtx()
+	defer cancel()
+	resp, err := rawClient.Get(ctx, w.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, ErrRevision, err
+	}
+	kvs := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = string(kv.Value)
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+func (w *PrefixWatcher) run() {
+	defer func() {
+		// the events not sent are dropped once the watcher or the client is closed
+		w.cancel()
+		<-w.delivered
+		close(w.done)
+	}()
+
+	if w.initial {
+		w.lock.RLock()
+		events := diffKVs(nil, w.cache, w.rev)
+		w.lock.RUnlock()
+		w.send(events...)
+	}
+
+	for {
+		err := w.watch()
+		if w.ctx.Err() != nil {
+			return
+		}
+		if perrors.Cause(err) == rpctypes.ErrCompacted {
+			log.Printf("etcd watcher (prefix %s) is compacted at revision %d, re-list it", w.prefix, w.Revision())
+			err = w.relist()
+		}
+		if err != nil {
+			log.Printf("etcd watcher (prefix %s) = error{%v}, resume it from revision %d", w.prefix, err, w.Revision()+1)
+		}
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.client.closed:
+			return
+		case <-time.After(watcherRetryInterval):
+		}
+	}
+}
+
+// clientCtx returns a ctx which is done when the watcher is closed or the
+// current raw client is stopped by the loss of the session
+func (w *PrefixWatcher) clientCtx() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	clientCtx := w.client.GetCtx()
+	stop := context.AfterFunc(clientCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// watch watches from the next revision of the last seen one, and returns
+// when the watch is broken
+func (w *PrefixWatcher) watch() error {
+	rawClient := w.client.GetRawClient()
+	if rawClient == nil {
+		return ErrNilETCDV3Client
+	}
+
+	ctx, cancel := w.clientCtx()
+	defer cancel()
+	ctx = clientv3.WithRequireLeader(ctx)
+	wc := rawClient.Watch(ctx, w.prefix, clientv3.WithPrefix(), clientv3.WithPrevKV(),
+		clientv3.WithRev(w.Revision()+1))
+	for resp := range wc {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			w.send(w.apply(ev))
+		}
+		// all the events before the revision of progress notify have been received
+		if resp.IsProgressNotify() {
+			w.lock.Lock()
+			if resp.Header.Revision > w.rev {
+				w.rev = resp.Header.Revision
+			}
+			w.lock.Unlock()
+		}
+	}
+	return perrors.New("watch channel is closed")
+}
+
+// apply updates the cache with @ev and returns the corresponding Event
+func (w *PrefixWatcher) apply(ev *clientv3.Event) Event {
+	e := Event{
+		Key:      string(ev.Kv.Key),
+		Value:    string(ev.Kv.Value),
+		Revision: ev.Kv.ModRevision,
+	}
+	if ev.PrevKv != nil {
+		e.PrevValue = string(ev.PrevKv.Value)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	switch {
+	case ev.Type == clientv3.EventTypeDelete:
+		e.Type = EventDelete
+		e.Value = ""
+		if e.PrevValue == "" {
+			e.PrevValue = w.cache[e.Key]
+		}
+		delete(w.cache, e.Key)
+	case ev.IsCreate():
+		e.Type = EventAdd
+		w.cache[e.Key] = e.Value
+	default:
+		e.Type = EventUpdate
+		w.cache[e.Key] = e.Value
+	}
+	if e.Revision > w.rev {
+		w.rev = e.Revision
+	}
+	return e
+}
+
+// relist lists the prefix again, replaces the cache and sends the differences
+func (w *PrefixWatcher) relist() error {
+	kvs, rev, err := w.list()
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	events := diffKVs(w.cache, kvs, rev)
+	w.cache, w.rev = kvs, rev
+	w.lock.Unlock()
+
+	w.send(events...)
+	return nil
+}
+
+// diffKVs returns the events which change @oldKVs into @newKVs in the order of key
+func diffKVs(oldKVs, newKVs map[string]string, rev int64) []Event {
+	var events []Event
+	for k, v := range newKVs {
+		prev, ok := oldKVs[k]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventAdd, Key: k, Value: v, Revision: rev})
+		case prev != v:
+			events = append(events, Event{Type: EventUpdate, Key: k, Value: v, PrevValue: prev, Revision: rev})
+		}
+	}
+	for k, prev := range oldKVs {
+		if _, ok := newKVs[k]; !ok {
+			events = append(events, Event{Type: EventDelete, Key: k, PrevValue: prev, Revision: rev})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
+
+// send queues @events without blocking the watch
+func (w *PrefixWatcher) send(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	w.queueLock.Lock()
+	w.queue = append(w.queue, events...)
+	w.queueLock.Unlock()
+	select {
+	case w.queued <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued events to the events channel until the watcher is closed
+func (w *PrefixWatcher) deliver() {
+	defer func() {
+		close(w.events)
+		close(w.delivered)
+	}()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.queued:
+		}
+		for {
+			w.queueLock.Lock()
+			if len(w.queue) == 0 {
+				w.queue = nil
+				w.queueLock.Unlock()
+				break
+			}
+			e := w.queue[0]
+			w.queue[0] = Event{}
+			w.queue = w.queue[1:]
+			w.queueLock.Unlock()
+
+			select {
+			case w.events <- e:
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxetcd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffKVs(t *testing.T) {
+	oldKVs := map[string]string{"a": "1", "b": "2", "c": "3"}
+	newKVs := map[string]string{"a": "1", "b": "22", "d": "4"}
+	events := diffKVs(oldKVs, newKVs, 10)
+	assert.Equal(t, []Event{
+		{Type: EventUpdate, Key: "b", Value: "22", PrevValue: "2", Revision: 10},
+		{Type: EventDelete, Key: "c", PrevValue: "3", Revision: 10},
+		{Type: EventAdd, Key: "d", Value: "4", Revision: 10},
+	}, events)
+}
+
+func (suite *ClientTestSuite) TestPrefixWatcher() {
+	t := suite.T()
+	c := suite.client
+	defer c.Close()
+
+	assert.NoError(t, c.Put("watcher/a", "1"))
+	w, err := c.NewPrefixWatcher("watcher/")
+	assert.NoError(t, err)
+	defer w.Close()
+	v, ok := w.Get("watcher/a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+
+	assert.NoError(t, c.Put("watcher/b", "2"))
+	assert.NoError(t, c.Put("watcher/a", "11"))
+	assert.NoError(t, c.Delete("watcher/b"))
+
+	e := <-w.Events()
+	assert.Equal(t, EventAdd, e.Type)
+	assert.Equal(t, "watcher/b", e.Key)
+	e = <-w.Events()
+	assert.Equal(t, EventUpdate, e.Type)
+	assert.Equal(t, "11", e.Value)
+	assert.Equal(t, "1", e.PrevValue)
+	e = <-w.Events()
+	assert.Equal(t, EventDelete, e.Type)
+	assert.Equal(t, "2", e.PrevValue)
+	assert.Equal(t, e.Revision, w.Revision())
+	assert.Equal(t, map[string]string{"watcher/a": "11"}, w.Snapshot())
+}
+
+func (suite *ClientTestSuite) TestPrefixWatcherNotReceived() {
+	t := suite.T()
+	c := suite.client
+	defer c.Close()
+
+	w, err := c.NewPrefixWatcher("watcher/")
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// the cache is updated though the events are not received
+	n := 2 * watcherEventBufferSize
+	for i := 0; i < n; i++ {
+		assert.NoError(t, c.Put(fmt.Sprintf("watcher/%03d", i), "1"))
+	}
+	assert.Eventually(t, func() bool {
+		return len(w.Snapshot()) == n
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < n; i++ {
+		e := <-w.Events()
+		assert.Equal(t, fmt.Sprintf("watcher/%03d", i), e.Key)
+	}
+}
+
+func (suite *ClientTestSuite) TestPrefixWatcherCompacted() {
+	t := suite.T()
+	c := suite.client
+	defer c.Close()
+
+	assert.NoError(t, c.Put("compacted/a", "1"))
+	assert.NoError(t, c.Put("compacted/b", "2"))
+	_, rev, err := c.GetValAndRev("compacted/b")
+	assert.NoError(t, err)
+	_, err = c.GetRawClient().Compact(context.Background(), rev)
+	assert.NoError(t, err)
+
+	// a watcher which has missed the events before the compaction
+	ctx, cancel := context.WithCancel(c.ctx)
+	w := &PrefixWatcher{
+		client:    c,
+		prefix:    "compacted/",
+		cache:     map[string]string{"compacted/a": "0", "compacted/c": "3"},
+		rev:       1,
+		queued:    make(chan struct{}, 1),
+		events:    make(chan Event, watcherEventBufferSize),
+		delivered: make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go w.deliver()
+	go w.run()
+	defer w.Close()
+
+	// the differences are sent in the order of key
+	var types []EventType
+	for _, key := range []string{"compacted/a", "compacted/b", "compacted/c"} {
+		e := <-w.Events()
+		assert.Equal(t, key, e.Key)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{EventUpdate, EventAdd, EventDelete}, types)
+	assert.Equal(t, map[string]string{"compacted/a": "1", "compacted/b": "2"}, w.Snapshot())
+	assert.True(t, w.Revision() >= rev)
+}
+
+func TestPrefixWatcherReconnect(t *testing.T) {
+	kv := NewMockKV()
+	client, err := NewMockClient("mock", kv)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	w, err := client.NewPrefixWatcher("/watch/")
+	assert.Nil(t, err)
+	defer w.Close()
+
+	assert.Nil(t, client.Put("/watch/a", "1"))
+	e := <-w.Events()
+	assert.Equal(t, EventAdd, e.Type)
+	assert.Equal(t, "/watch/a", e.Key)
+
+	// the session is lost, which cancels the ctx of client
+	_, err = kv.Revoke(context.Background(), client.GetSession().Lease())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return !client.Valid()
+	}, time.Second, 10*time.Millisecond)
+
+	// the changes during the loss are sent after the client reconnects
+	_, err = kv.Put(context.Background(), "/watch/b", "2")
+	assert.Nil(t, err)
+	assert.Nil(t, sharedClient{client}.Reconnect())
+	assert.Nil(t, client.Put("/watch/a", "11"))
+
+	select {
+	case e = <-w.Events():
+		assert.Equal(t, Event{Type: EventAdd, Key: "/watch/b", Value: "2", Revision: e.Revision}, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher is not resumed")
+	}
+	e = <-w.Events()
+	assert.Equal(t, EventUpdate, e.Type)
+	assert.Equal(t, "11", e.Value)
+	assert.Equal(t, "1", e.PrevValue)
+	assert.Equal(t, map[string]string{"/watch/a": "11", "/watch/b": "2"}, w.Snapshot())
+
+	// the watcher is closed with the client
+	client.Close()
+	for range w.Events() {
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kv
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+const (
+	memoryWatchBufferSize = 64
+)
+
+// MemoryStore is an in-memory Store for tests
+type MemoryStore struct {
+	lock      sync.RWMutex
+	kvs       map[string]string
+	ephemeral map[string]struct{}
+	watchers  map[*memoryWatcher]struct{}
+	closed    bool
+	done      chan struct{} // closed by Close
+}
+
+type memoryWatcher struct {
+	prefix string
+	ch     chan Event
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		kvs:       make(map[string]string),
+		ephemeral: make(map[string]struct{}),
+		watchers:  make(map[*memoryWatcher]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Get gets the value of @key
+func (m *MemoryStore) Get(key string) (string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.closed {
+		return "", ErrStoreClosed
+	}
+	v, ok := m.kvs[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return v, nil
+}
+
+// Put creates or updates @key with @value
+func (m *MemoryStore) Put(key string, value string) error {
+	return m.put(key, value, false)
+}
+
+// CreateEphemeral creates @key with @value, the key is removed by Close or ExpireSession
+func (m *MemoryStore) CreateEphemeral(key string, value string) error {
+	return m.put(key, value, true)
+}
+
+func (m *MemoryStore) put(key string, value string, ephemeral bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrStoreClosed
+	}
+	_, ok := m.kvs[key]
+	m.kvs[key] = value
+	if ephemeral {
+		m.ephemeral[key] = struct{}{}
+	} else {
+		delete(m.ephemeral, key)
+	}
+	e := Event{Type: EventAdd, Key: key, Value: value}
+	if ok {
+		e.Type = EventUpdate
+	}
+	m.notify(e)
+	return nil
+}
+
+// Delete deletes @key
+func (m *MemoryStore) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrStoreClosed
+	}
+	if _, ok := m.kvs[key]; ok {
+		m.delete(key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) delete(key string) {
+	delete(m.kvs, key)
+	delete(m.ephemeral, key)
+	m.notify(Event{Type: EventDelete, Key: key})
+}
+
+// Children returns the names of the direct children of @key
+func (m *MemoryStore) Children(key string) ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.closed {
+		return nil, ErrStoreClosed
+	}
+	keys := make([]string, 0, len(m.kvs))
+	for k := range m.kvs {
+		keys = append(keys, k)
+	}
+	return ChildNames(key, keys), nil
+}
+
+// WatchPrefix sends the existing keys under @prefix and their changes
+func (m *MemoryStore) WatchPrefix(ctx context.Context, prefix string) (<-chan Event, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return nil, ErrStoreClosed
+	}
+	kvs := make(map[string]string)
+	for k, v := range m.kvs {
+		if strings.HasPrefix(k, prefix) {
+			kvs[k] = v
+		}
+	}
+	w := &memoryWatcher{
+		prefix: prefix,
+		ch:     make(chan Event, len(kvs)+memoryWatchBufferSize),
+	}
+	for _, e := range Diff(nil, kvs) {
+		w.ch <- e
+	}
+	m.watchers[w] = struct{}{}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-m.done:
+			// Close has closed the channel
+			return
+		}
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if _, ok := m.watchers[w]; ok {
+			delete(m.watchers, w)
+			close(w.ch)
+		}
+	}()
+	return w.ch, nil
+}
+
+// notify should be called with the lock held. The event is dropped if the
+// watcher is too slow to receive it.
+func (m *MemoryStore) notify(e Event) {
+	for w := range m.watchers {
+		if !strings.HasPrefix(e.Key, w.prefix) {
+			continue
+		}
+		select {
+		case w.ch <- e:
+		default:
+		}
+	}
+}
+
+// ExpireSession removes all the ephemeral keys as if the session is lost
+func (m *MemoryStore) ExpireSession() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for k := range m.ephemeral {
+		m.delete(k)
+	}
+}
+
+// Close removes the ephemeral keys and stops all the watchers
+func (m *MemoryStore) Close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return
+	}
+	for k := range m.ephemeral {
+		m.delete(k)
+	}
+	for w := range m.watchers {
+		close(w.ch)
+	}
+	m.watchers = make(map[*memoryWatcher]struct{})
+	m.closed = true
+	close(m.done)
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kv
+
+import (
+	"context"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	m := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := m.WatchPrefix(ctx, "/dubbo/")
+	assert.NoError(t, err)
+
+	_, err = m.Get("/dubbo/a")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.NoError(t, m.Put("/dubbo/a", "1"))
+	assert.NoError(t, m.Put("/dubbo/a", "2"))
+	assert.NoError(t, m.CreateEphemeral("/dubbo/b/providers/p1", "p1"))
+	assert.NoError(t, m.Put("/other", "x"))
+	v, err := m.Get("/dubbo/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", v)
+
+	children, err := m.Children("/dubbo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, children)
+	children, err = m.Children("/absent")
+	assert.NoError(t, err)
+	assert.Empty(t, children)
+
+	m.ExpireSession()
+	_, err = m.Get("/dubbo/b/providers/p1")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.NoError(t, m.Delete("/dubbo/a"))
+	assert.NoError(t, m.Delete("/dubbo/a"))
+
+	expected := []Event{
+		{Type: EventAdd, Key: "/dubbo/a", Value: "1"},
+		{Type: EventUpdate, Key: "/dubbo/a", Value: "2"},
+		{Type: EventAdd, Key: "/dubbo/b/providers/p1", Value: "p1"},
+		{Type: EventDelete, Key: "/dubbo/b/providers/p1"},
+		{Type: EventDelete, Key: "/dubbo/a"},
+	}
+	for _, e := range expected {
+		assert.Equal(t, e, <-events)
+	}
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+
+	m.Close()
+	assert.Equal(t, ErrStoreClosed, m.Put("/dubbo/a", "1"))
+}
+
+func TestMemoryStoreWatchExisting(t *testing.T) {
+	m := NewMemoryStore()
+	assert.NoError(t, m.Put("/dubbo/b", "2"))
+	assert.NoError(t, m.Put("/dubbo/a", "1"))
+	assert.NoError(t, m.Put("/other", "x"))
+
+	// the channel is closed by Close even if the ctx is never done
+	events, err := m.WatchPrefix(context.Background(), "/dubbo/")
+	assert.NoError(t, err)
+	assert.Equal(t, Event{Type: EventAdd, Key: "/dubbo/a", Value: "1"}, <-events)
+	assert.Equal(t, Event{Type: EventAdd, Key: "/dubbo/b", Value: "2"}, <-events)
+	assert.NoError(t, m.Put("/dubbo/c", "3"))
+	assert.Equal(t, Event{Type: EventAdd, Key: "/dubbo/c", Value: "3"}, <-events)
+
+	m.Close()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestDiff(t *testing.T) {
+	events := Diff(map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "11", "c": "3"})
+	assert.Equal(t, []Event{
+		{Type: EventUpdate, Key: "a", Value: "11"},
+		{Type: EventDelete, Key: "b"},
+		{Type: EventAdd, Key: "c", Value: "3"},
+	}, events)
+}
+
+func TestChildNames(t *testing.T) {
+	keys := []string{"/a/b", "/a/b/c", "/a/d", "/ab", "/a/"}
+	assert.Equal(t, []string{"b", "d"}, ChildNames("/a", keys))
+	assert.Equal(t, []string{"b", "d"}, ChildNames("/a/", keys))
+}
@@ -0,0 +1,408 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	defaultNamespace = "public"
+)
+
+var (
+	// ErrConfigNotFound the config does not exist
+	ErrConfigNotFound = perrors.New("nacos config not found")
+	// ErrInvalidConfigKey the dataId or group can not be used as a file name
+	ErrInvalidConfigKey = perrors.New("invalid nacos config dataId or group")
+)
+
+// ConfigListener receives the content of the config, it is empty if the config is deleted
+type ConfigListener func(content string)
+
+// TypedListener receives the config decoded into a new value, or the error of decoding
+type TypedListener func(value interface{}, err error)
+
+// ConfigCenterOption configures ConfigCenter
+type ConfigCenterOption func(*ConfigCenter)
+
+// WithConfigCenterGroup sets the group used when the group of a call is empty,
+// it is constant.DEFAULT_GROUP by default.
+func WithConfigCenterGroup(group string) ConfigCenterOption {
+	return func(c *ConfigCenter) {
+		c.group = group
+	}
+}
+
+// WithSnapshotDir saves the configs got from the server in @dir, and they are
+// served when the server is unreachable.
+func WithSnapshotDir(dir string) ConfigCenterOption {
+	return func(c *ConfigCenter) {
+		c.snapshotDir = dir
+	}
+}
+
+// ConfigCenter is the config center API on NacosConfigClient. The client is
+// not closed by ConfigCenter, as it may be shared.
+type ConfigCenter struct {
+	client      *NacosConfigClient
+	group       string
+	snapshotDir string
+
+	lock    sync.Mutex
+	watches map[string][]*configWatch // group@@dataId -> listeners
+}
+
+// configWatch is a listener of the config, which skips the content it has
+// just received, as the sdk may push the current config which has been sent
+// by Listen.
+type configWatch struct {
+	listener ConfigListener
+
+	lock     sync.Mutex
+	notified bool
+	md5      string
+}
+
+// notify sends @content to the listener unless it is the same as the last
+// one, and the @initial content is dropped if any content has been sent.
+func (w *configWatch) notify(content string, initial bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	md5 := util.Md5(content)
+	if w.notified && (initial || w.md5 == md5) {
+		return
+	}
+	w.notified, w.md5 = true, md5
+	w.listener(content)
+}
+
+// NewConfigCenter returns a ConfigCenter on @client
+func NewConfigCenter(client *NacosConfigClient, opts ...ConfigCenterOption) *ConfigCenter {
+	c := &ConfigCenter{
+		client:  client,
+		group:   constant.DEFAULT_GROUP,
+		watches: make(map[string][]*configWatch),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *ConfigCenter) checkKey(dataID, group string) (string, error) {
+	if group == "" {
+		group = c.group
+	}
+	for _, s := range []string{dataID, group} {
+		if s == "" || s == "." || s == ".." || strings.ContainsAny(s, `/\`) {
+			return "", perrors.WithMessagef(ErrInvalidConfigKey, "dataId %q, group %q", dataID, group)
+		}
+	}
+	return group, nil
+}
+
+// Get gets the content of the config, the snapshot is returned if the server
+// is unreachable, and ErrConfigNotFound is returned if the config is absent.
+func (c *ConfigCenter) Get(dataID, group string) (string, error) {
+	group, err := c.checkKey(dataID, group)
+	if err != nil {
+		return "", err
+	}
+
+	client := c.client.Client()
+	if client == nil {
+		err = ErrNilConfigClient
+	} else {
+		var content string
+		content, err = client.GetConfig(vo.ConfigParam{DataId: dataID, Group: group})
+		if err == nil {
+			c.saveSnapshot(dataID, group, content)
+			if content == "" {
+				return "", ErrConfigNotFound
+			}
+			return content, nil
+		}
+	}
+
+	if content, serr := c.readSnapshot(dataID, group); serr == nil {
+		return content, nil
+	}
+	return "", perrors.WithMessagef(err, "get config (dataId %s, group %s)", dataID, group)
+}
+
+// GetDecoded gets the config and decodes it into @out by @decode
+func (c *ConfigCenter) GetDecoded(dataID, group string, decode Decoder, out interface{}) error {
+	content, err := c.Get(dataID, group)
+	if err != nil {
+		return err
+	}
+	return perrors.WithMessagef(decode([]byte(content), out), "decode config (dataId %s, group %s)", dataID, group)
+}
+
+// GetGray gets the gray config published with @tag, it falls back to the
+// normal config if the gray one is absent.
+func (c *ConfigCenter) GetGray(dataID, group, tag string) (string, error) {
+	group, err := c.checkKey(dataID, group)
+	if err != nil {
+		return "", err
+	}
+	if client := c.client.Client(); client != nil && tag != "" {
+		content, err := client.GetConfig(vo.ConfigParam{DataId: dataID, Group: group, Tag: tag})
+		if err == nil && content != "" {
+			return content, nil
+		}
+	}
+	return c.Get(dataID, group)
+}
+
+// Publish creates or updates the config with @content
+func (c *ConfigCenter) Publish(dataID, group, content string) error {
+	return c.publish(vo.ConfigParam{DataId: dataID, Group: group, Content: content})
+}
+
+// PublishBeta publishes @content as the beta config, which is only pushed
+// to the clients whose ip is in @betaIPs.
+func (c *ConfigCenter) PublishBeta(dataID, group, content string, betaIPs []string) error {
+	if len(betaIPs) == 0 {
+		return perrors.New("beta ips can not be empty")
+	}
+	return c.publish(vo.ConfigParam{DataId: dataID, Group: group, Content: content, BetaIps: strings.Join(betaIPs, ",")})
+}
+
+// PublishGray publishes @content as the gray config of @tag, see GetGray
+func (c *ConfigCenter) PublishGray(dataID, group, tag, content string) error {
+	if tag == "" {
+		return perrors.New("gray tag can not be empty")
+	}
+	return c.publish(vo.ConfigParam{DataId: dataID, Group: group, Content: content, Tag: tag})
+}
+
+func (c *ConfigCenter) publish(param vo.ConfigParam) error {
+	group, err := c.checkKey(param.DataId, param.Group)
+	if err != nil {
+		return err
+	}
+	param.Group = group
+	client := c.client.Client()
+	if client == nil {
+		return ErrNilConfigClient
+	}
+	ok, err := client.PublishConfig(param)
+	if err != nil {
+		return perrors.WithMessagef(err, "publish config (dataId %s, group %s)", param.DataId, group)
+	}
+	if !ok {
+		return perrors.Errorf("publish config (dataId %s, group %s) fail", param.DataId, group)
+	}
+	return nil
+}
+
+// Delete deletes the config and its snapshot
+func (c *ConfigCenter) Delete(dataID, group string) error {
+	return c.delete(vo.ConfigParam{DataId: dataID, Group: group})
+}
+
+// DeleteGray deletes the gray config of @tag
+func (c *ConfigCenter) DeleteGray(dataID, group, tag string) error {
+	if tag == "" {
+		return perrors.New("gray tag can not be empty")
+	}
+	return c.delete(vo.ConfigParam{DataId: dataID, Group: group, Tag: tag})
+}
+
+func (c *ConfigCenter) delete(param vo.ConfigParam) error {
+	group, err := c.checkKey(param.DataId, param.Group)
+	if err != nil {
+		return err
+	}
+	param.Group = group
+	client := c.client.Client()
+	if client == nil {
+		return ErrNilConfigClient
+	}
+	if _, err = client.DeleteConfig(param); err != nil {
+		return perrors.WithMessagef(err, "delete config (dataId %s, group %s)", param.DataId, group)
+	}
+	if param.Tag == "" {
+		c.saveSnapshot(param.DataId, group, "")
+	}
+	return nil
+}
+
+// Listen adds @listener of the config, and the current config is sent to it at
+// first if it exists. The listeners of a config share one listener of the
+// sdk, because the sdk only keeps the first listener of a config. A content
+// same as the last one received by @listener is not sent again.
+func (c *ConfigCenter) Listen(dataID, group string, listener ConfigListener) error {
+	group, err := c.checkKey(dataID, group)
+	if err != nil {
+		return err
+	}
+
+	key := util.GetGroupName(dataID, group)
+	c.lock.Lock()
+	if _, ok := c.watches[key]; !ok {
+		client := c.client.Client()
+		if client == nil {
+			c.lock.Unlock()
+			return ErrNilConfigClient
+		}
+		err = client.ListenConfig(vo.ConfigParam{
+			DataId: dataID,
+			Group:  group,
+			OnChange: func(_, group, dataId, data string) {
+				c.onChange(dataId, group, data)
+			},
+		})
+		if err != nil {
+			c.lock.Unlock()
+			return perrors.WithMessagef(err, "listen config (dataId %s, group %s)", dataID, group)
+		}
+	}
+	w := &configWatch{listener: listener}
+	c.watches[key] = append(c.watches[key], w)
+	c.lock.Unlock()
+
+	if content, err := c.Get(dataID, group); err == nil {
+		w.notify(content, true)
+	}
+	return nil
+}
+
+// ListenTyped adds @listener which receives the config decoded by @decode into
+// a new value of the type of @sample, which should be a pointer, eg: &Config{}.
+// The listener receives ErrConfigNotFound if the config is deleted.
+func (c *ConfigCenter) ListenTyped(dataID, group string, decode Decoder, sample interface{}, listener TypedListener) error {
+	typ := reflect.TypeOf(sample)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return perrors.Errorf("sample should be a pointer, but got %T", sample)
+	}
+	return c.Listen(dataID, group, func(content string) {
+		if content == "" {
+			listener(nil, ErrConfigNotFound)
+			return
+		}
+		value := reflect.New(typ.Elem()).Interface()
+		if err := decode([]byte(content), value); err != nil {
+			listener(nil, perrors.WithMessagef(err, "decode config (dataId %s, group %s)", dataID, group))
+			return
+		}
+		listener(value, nil)
+	})
+}
+
+func (c *ConfigCenter) onChange(dataID, group, content string) {
+	c.saveSnapshot(dataID, group, content)
+	c.lock.Lock()
+	watches := c.watches[util.GetGroupName(dataID, group)]
+	c.lock.Unlock()
+	for _, w := range watches {
+		w.notify(content, false)
+	}
+}
+
+// Unlisten removes all the listeners of the config
+func (c *ConfigCenter) Unlisten(dataID, group string) error {
+	group, err := c.checkKey(dataID, group)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	delete(c.watches, util.GetGroupName(dataID, group))
+	c.lock.Unlock()
+	if client := c.client.Client(); client != nil {
+		return client.CancelListenConfig(vo.ConfigParam{DataId: dataID, Group: group})
+	}
+	return nil
+}
+
+// Close removes all the listeners
+func (c *ConfigCenter) Close() {
+	c.lock.Lock()
+	watches := c.watches
+	c.watches = make(map[string][]*configWatch)
+	c.lock.Unlock()
+
+	client := c.client.Client()
+	if client == nil {
+		return
+	}
+	for key := range watches {
+		// key is group@@dataId
+		idx := strings.Index(key, constant.SERVICE_INFO_SPLITER)
+		_ = client.CancelListenConfig(vo.ConfigParam{
+			DataId: key[idx+len(constant.SERVICE_INFO_SPLITER):],
+			Group:  key[:idx],
+		})
+	}
+}
+
+func (c *ConfigCenter) snapshotPath(dataID, group string) string {
+	namespace := defaultNamespace
+	if cc := c.client.config.ClientConfig; cc != nil && cc.NamespaceId != "" {
+		namespace = cc.NamespaceId
+	}
+	return filepath.Join(c.snapshotDir, namespace, group, dataID)
+}
+
+// saveSnapshot saves @content of the config, or removes the snapshot if it is
+// empty. The snapshot is best effort, so the error is ignored.
+func (c *ConfigCenter) saveSnapshot(dataID, group, content string) {
+	if c.snapshotDir == "" {
+		return
+	}
+	file := c.snapshotPath(dataID, group)
+	if content == "" {
+		_ = os.Remove(file)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return
+	}
+	// write a temp file and rename it, so a broken snapshot is never read
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, file)
+}
+
+func (c *ConfigCenter) readSnapshot(dataID, group string) (string, error) {
+	if c.snapshotDir == "" {
+		return "", ErrConfigNotFound
+	}
+	content, err := os.ReadFile(c.snapshotPath(dataID, group))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"testing"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockConfigCenter(t *testing.T, opts ...ConfigCenterOption) (*ConfigCenter, *MockConfigClient) {
+	mock := NewMockConfigClient("")
+	client := &NacosConfigClient{name: "mock", valid: 1}
+	client.SetClient(mock)
+	return NewConfigCenter(client, opts...), mock
+}
+
+func TestConfigCenter(t *testing.T) {
+	cc, _ := newMockConfigCenter(t)
+
+	_, err := cc.Get("app", "")
+	assert.Equal(t, ErrConfigNotFound, err)
+	_, err = cc.Get("../app", "")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, cc.Publish("app", "", "name: demo\nport: 8080\n"))
+	content, err := cc.Get("app", "DEFAULT_GROUP")
+	assert.Nil(t, err)
+	assert.Equal(t, "name: demo\nport: 8080\n", content)
+
+	var conf testAppConfig
+	assert.Nil(t, cc.GetDecoded("app", "", DecodeYAML, &conf))
+	assert.Equal(t, 8080, conf.Port)
+
+	var values []*testAppConfig
+	var errs []error
+	err = cc.ListenTyped("app", "", DecodeJSON, &testAppConfig{}, func(value interface{}, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		values = append(values, value.(*testAppConfig))
+	})
+	assert.Nil(t, err)
+	// the current config is not json
+	assert.Equal(t, 1, len(errs))
+
+	var contents []string
+	assert.Nil(t, cc.Listen("app", "", func(content string) {
+		contents = append(contents, content)
+	}))
+	// the sdk pushes the current config which has been sent by Listen
+	cc.onChange("app", "DEFAULT_GROUP", "name: demo\nport: 8080\n")
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, 1, len(contents))
+
+	assert.Nil(t, cc.Publish("app", "", `{"name": "demo", "port": 9090}`))
+	assert.Nil(t, cc.Publish("app", "", `{"name": "demo", "port": 9090}`))
+	assert.Equal(t, 1, len(values))
+	assert.Equal(t, 9090, values[0].Port)
+	assert.Equal(t, 2, len(contents))
+
+	assert.Nil(t, cc.Delete("app", ""))
+	assert.Equal(t, ErrConfigNotFound, errs[1])
+	assert.Equal(t, "", contents[2])
+
+	assert.Nil(t, cc.Unlisten("app", ""))
+	assert.Nil(t, cc.Publish("app", "", "v"))
+	assert.Equal(t, 3, len(contents))
+	cc.Close()
+}
+
+func TestConfigCenterGrayAndBeta(t *testing.T) {
+	cc, mock := newMockConfigCenter(t, WithConfigCenterGroup("g"))
+
+	assert.Nil(t, cc.Publish("app", "", "stable"))
+	assert.Nil(t, cc.PublishGray("app", "", "canary", "gray"))
+	content, err := cc.GetGray("app", "", "canary")
+	assert.Nil(t, err)
+	assert.Equal(t, "gray", content)
+	content, err = cc.GetGray("app", "", "other")
+	assert.Nil(t, err)
+	assert.Equal(t, "stable", content)
+	assert.Nil(t, cc.DeleteGray("app", "", "canary"))
+	content, err = cc.GetGray("app", "", "canary")
+	assert.Nil(t, err)
+	assert.Equal(t, "stable", content)
+
+	mock.SetClientIP("10.0.0.1")
+	assert.Nil(t, cc.PublishBeta("app", "", "beta", []string{"10.0.0.2"}))
+	content, err = cc.Get("app", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "stable", content)
+	assert.Nil(t, cc.PublishBeta("app", "", "beta", []string{"10.0.0.1", "10.0.0.2"}))
+	content, err = cc.Get("app", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "beta", content)
+	assert.NotNil(t, cc.PublishBeta("app", "", "beta", nil))
+}
+
+func TestConfigCenterSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	cc, mock := newMockConfigCenter(t, WithSnapshotDir(dir))
+
+	assert.Nil(t, cc.Publish("app", "", "v1"))
+	assert.Nil(t, cc.Publish("removed", "", "v1"))
+	_, err := cc.Get("app", "")
+	assert.Nil(t, err)
+	_, err = cc.Get("removed", "")
+	assert.Nil(t, err)
+	assert.Nil(t, cc.Delete("removed", ""))
+
+	// the snapshot is served when the server is unreachable
+	mock.CloseClient()
+	restarted, _ := newMockConfigCenter(t, WithSnapshotDir(dir))
+	restarted.client.SetClient(mock)
+	content, err := restarted.Get("app", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", content)
+	_, err = restarted.Get("removed", "")
+	assert.Equal(t, ErrMockClientClosed, perrors.Cause(err))
+}
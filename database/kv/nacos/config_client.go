@@ -29,38 +29,64 @@ import (
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 )
 
-var (
-	configClientPool     nacosConfigClientPool
-	configClientPoolOnce sync.Once
+import (
+	"github.com/dubbogo/gost/database/kv"
 )
 
-type nacosConfigClientPool struct {
-	sync.Mutex
-	configClient map[string]*NacosConfigClient
+// configClientRegistry holds the shared config clients by name
+var configClientRegistry = kv.NewSharedRegistry()
+
+// ConfigureSharedConfigClients configures the idle timeout of the shared config clients. The sdk
+// keeps the connection to the server by itself, so the health check only recreates the
+// sdk client which has been closed.
+func ConfigureSharedConfigClients(opts ...kv.SharedOption) {
+	configClientRegistry.Configure(opts...)
 }
 
 type NacosConfigClient struct {
-	name        string
-	clientLock  sync.Mutex // for Client
-	client      config_client.IConfigClient
-	config      vo.NacosClientParam //conn config
-	valid       uint32
-	activeCount uint32
-	share       bool
+	name       string
+	clientLock sync.Mutex // for Client
+	client     config_client.IConfigClient
+	config     vo.NacosClientParam //conn config
+	valid      uint32
+	share      bool
+}
+
+// sharedConfigClient makes NacosConfigClient a kv.SharedClient
+type sharedConfigClient struct {
+	*NacosConfigClient
 }
 
-func initNacosConfigClientPool() {
-	configClientPool.configClient = make(map[string]*NacosConfigClient)
+// Healthy reports whether the sdk client is not closed. The server is not probed,
+// as recreating the sdk client would drop its listeners while the sdk reconnects.
+func (s sharedConfigClient) Healthy() bool {
+	return s.NacosClientValid() && s.Client() != nil
 }
 
+// Reconnect recreates the sdk client
+func (s sharedConfigClient) Reconnect() error {
+	return s.newConfigClient()
+}
+
+// Destroy closes the sdk client
+func (s sharedConfigClient) Destroy() {
+	s.destroy()
+}
+
+// newConfigClient creates the sdk client, and closes the previous one
 func (n *NacosConfigClient) newConfigClient() error {
 	client, err := clients.NewConfigClient(n.config)
 	if err != nil {
 		return err
 	}
-	n.activeCount++
-	atomic.StoreUint32(&n.valid, 1)
+	n.clientLock.Lock()
+	prev := n.client
 	n.client = client
+	n.clientLock.Unlock()
+	atomic.StoreUint32(&n.valid, 1)
+	if prev != nil {
+		prev.CloseClient()
+	}
 	return nil
 }
 
@@ -69,31 +95,29 @@ func NewNacosConfigClient(name string, share bool, sc []constant.ServerConfig,
 	cc constant.ClientConfig) (*NacosConfigClient, error) {
 
 	configClient := &NacosConfigClient{
-		name:        name,
-		activeCount: 0,
-		share:       share,
-		config:      vo.NacosClientParam{ClientConfig: &cc, ServerConfigs: sc},
+		name:   name,
+		share:  share,
+		config: vo.NacosClientParam{ClientConfig: &cc, ServerConfigs: sc},
 	}
 	if !share {
 		return configClient, configClient.newConfigClient()
 	}
-	configClientPoolOnce.Do(initNacosConfigClientPool)
-	configClientPool.Lock()
-	defer configClientPool.Unlock()
-	if client, ok := configClientPool.configClient[name]; ok {
-		client.activeCount++
-		return client, nil
-	}
-
-	err := configClient.newConfigClient()
-	if err == nil {
-		configClientPool.configClient[name] = configClient
+	client, err := configClientRegistry.Acquire(name, func() (kv.SharedClient, error) {
+		if err := configClient.newConfigClient(); err != nil {
+			return nil, err
+		}
+		return sharedConfigClient{configClient}, nil
+	})
+	if err != nil {
+		return configClient, err
 	}
-	return configClient, err
+	return client.(sharedConfigClient).NacosConfigClient, nil
 }
 
 // Client Get NacosConfigClient
 func (n *NacosConfigClient) Client() config_client.IConfigClient {
+	n.clientLock.Lock()
+	defer n.clientLock.Unlock()
 	return n.client
 }
 
@@ -110,23 +134,22 @@ func (n *NacosConfigClient) NacosClientValid() bool {
 	return atomic.LoadUint32(&n.valid) == 1
 }
 
-// Close close client
+// Close close client, the shared client is closed when all the holders close it
 func (n *NacosConfigClient) Close() {
-	configClientPool.Lock()
-	defer configClientPool.Unlock()
-	if n.client == nil {
+	if n.share {
+		configClientRegistry.Release(n.name, sharedConfigClient{n})
 		return
 	}
-	n.activeCount--
-	if n.share {
-		if n.activeCount == 0 {
-			n.client = nil
-			atomic.StoreUint32(&n.valid, 0)
-			delete(configClientPool.configClient, n.name)
-		}
-	} else {
-		n.client = nil
-		atomic.StoreUint32(&n.valid, 0)
-		delete(configClientPool.configClient, n.name)
+	n.destroy()
+}
+
+func (n *NacosConfigClient) destroy() {
+	n.clientLock.Lock()
+	client := n.client
+	n.client = nil
+	n.clientLock.Unlock()
+	atomic.StoreUint32(&n.valid, 0)
+	if client != nil {
+		client.CloseClient()
 	}
 }
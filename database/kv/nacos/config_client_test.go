@@ -56,13 +56,15 @@ func TestNewNacosConfigClient(t *testing.T) {
 	assert.Nil(t, err)
 
 	assert.Equal(t, client1, client2)
-	assert.Equal(t, client1.activeCount, uint32(2))
+	assert.Equal(t, configClientRegistry.Refs("nacos"), 2)
 	assert.Equal(t, client1.NacosClientValid(), true)
-	assert.Equal(t, client3.activeCount, uint32(1))
-	assert.Equal(t, client4.activeCount, uint32(1))
+	assert.True(t, client1 != client3)
+	assert.Equal(t, client3.NacosClientValid(), true)
+	assert.True(t, client4 != client1)
+	assert.Equal(t, configClientRegistry.Refs("test"), 1)
 
 	client1.Close()
-	assert.Equal(t, client1.activeCount, uint32(1))
+	assert.Equal(t, configClientRegistry.Refs("nacos"), 1)
 	client1.Close()
 
 	assert.Equal(t, client1.NacosClientValid(), false)
@@ -0,0 +1,230 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/gost/encoding/yaml"
+)
+
+// Decoder decodes the config content into @out, which should be a pointer
+type Decoder func(content []byte, out interface{}) error
+
+// DecodeYAML decodes the YAML content
+func DecodeYAML(content []byte, out interface{}) error {
+	return yaml.UnmarshalYML(content, out)
+}
+
+// DecodeJSON decodes the JSON content
+func DecodeJSON(content []byte, out interface{}) error {
+	return json.Unmarshal(content, out)
+}
+
+// DecodeProperties decodes the java properties content into *map[string]string
+// or a pointer to struct. The key of a struct field is its `properties` tag or
+// its name, and the fields of a nested struct are prefixed by the key of the
+// struct and ".". The field can be string, bool, integer, float, time.Duration
+// or []string which is separated by ",".
+func DecodeProperties(content []byte, out interface{}) error {
+	props, err := ParseProperties(string(content))
+	if err != nil {
+		return err
+	}
+
+	if m, ok := out.(*map[string]string); ok {
+		*m = props
+		return nil
+	}
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return perrors.Errorf("properties can not be decoded into %T", out)
+	}
+	return setStructFields(v.Elem(), "", props)
+}
+
+// ParseProperties parses the java properties content. The lines starting with
+// "#" or "!" are comments, the key ends at the first unescaped "=", ":" or
+// whitespace, which may be surrounded by whitespace, and a line ending with
+// "\" continues on the next line. A key without value has an empty value.
+func ParseProperties(content string) (map[string]string, error) {
+	props := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSpace(lines[i])
+		}
+
+		key, value := splitProperty(line)
+		if key == "" {
+			return nil, perrors.Errorf("line %d: empty key", i+1)
+		}
+		props[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	return props, nil
+}
+
+// splitProperty splits @line into the key and the value
+func splitProperty(line string) (string, string) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	value := strings.TrimLeft(line[end:], " \t\f")
+	if value != "" && (value[0] == '=' || value[0] == ':') {
+		value = strings.TrimLeft(value[1:], " \t\f")
+	}
+	return line[:end], value
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setStructFields(v reflect.Value, prefix string, props map[string]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Tag.Get("properties")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		key = prefix + key
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := setStructFields(fv, key+".", props); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := props[key]
+		if !ok {
+			continue
+		}
+		if err := setField(fv, value); err != nil {
+			return perrors.WithMessagef(err, "decode property %s", key)
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return perrors.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return perrors.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
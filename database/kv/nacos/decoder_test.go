@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type testAppConfig struct {
+	Name    string        `yaml:"name" json:"name" properties:"app.name"`
+	Port    int           `yaml:"port" json:"port" properties:"app.port"`
+	Debug   bool          `yaml:"debug" json:"debug" properties:"app.debug"`
+	Timeout time.Duration `yaml:"timeout" json:"timeout" properties:"app.timeout"`
+	Tags    []string      `yaml:"tags" json:"tags" properties:"app.tags"`
+	DB      struct {
+		URL string `properties:"url"`
+	} `yaml:"db" json:"db" properties:"db"`
+}
+
+func TestParseProperties(t *testing.T) {
+	props, err := ParseProperties(`
+# comment
+! comment
+a = 1
+b:2
+c = multi \
+    line
+d = tab\tnew\nline
+e =
+f	g
+h   :  i j
+k\=l = m
+n
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"a":   "1",
+		"b":   "2",
+		"c":   "multi line",
+		"d":   "tab\tnew\nline",
+		"e":   "",
+		"f":   "g",
+		"h":   "i j",
+		"k=l": "m",
+		"n":   "",
+	}, props)
+
+	_, err = ParseProperties("=value")
+	assert.NotNil(t, err)
+}
+
+func TestDecodeProperties(t *testing.T) {
+	content := []byte(`
+app.name = demo
+app.port = 8080
+app.debug = true
+app.timeout = 3s
+app.tags = a, b
+db.url = mysql://127.0.0.1
+`)
+	var conf testAppConfig
+	assert.Nil(t, DecodeProperties(content, &conf))
+	assert.Equal(t, "demo", conf.Name)
+	assert.Equal(t, 8080, conf.Port)
+	assert.True(t, conf.Debug)
+	assert.Equal(t, 3*time.Second, conf.Timeout)
+	assert.Equal(t, []string{"a", "b"}, conf.Tags)
+	assert.Equal(t, "mysql://127.0.0.1", conf.DB.URL)
+
+	var m map[string]string
+	assert.Nil(t, DecodeProperties(content, &m))
+	assert.Equal(t, "demo", m["app.name"])
+
+	assert.NotNil(t, DecodeProperties([]byte("app.port = x"), &conf))
+	assert.NotNil(t, DecodeProperties(content, conf))
+}
+
+func TestDecodeYAMLAndJSON(t *testing.T) {
+	var conf testAppConfig
+	assert.Nil(t, DecodeYAML([]byte("name: demo\nport: 8080\ntags: [a, b]\n"), &conf))
+	assert.Equal(t, "demo", conf.Name)
+	assert.Equal(t, []string{"a", "b"}, conf.Tags)
+
+	conf = testAppConfig{}
+	assert.Nil(t, DecodeJSON([]byte(`{"name": "demo", "port": 8080}`), &conf))
+	assert.Equal(t, 8080, conf.Port)
+}
@@ -0,0 +1,429 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	// defaultSubscribeRetryInterval is the interval of subscribing again after failure
+	defaultSubscribeRetryInterval = 5 * time.Second
+)
+
+var (
+	// ErrDiscoveryClosed the discovery has been closed
+	ErrDiscoveryClosed = perrors.New("nacos discovery is closed")
+	// ErrNilNamingClient the sdk naming client is nil
+	ErrNilNamingClient = perrors.New("nacos naming client is nil")
+)
+
+// InstanceFilter reports whether @instance should be kept in the cache
+type InstanceFilter func(instance model.Instance) bool
+
+// InstancesDiff is the change of the instances of a service
+type InstancesDiff struct {
+	Added   []model.Instance
+	Updated []model.Instance
+	Removed []model.Instance
+}
+
+// Empty reports whether there is no change
+func (d InstancesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Updated) == 0 && len(d.Removed) == 0
+}
+
+// DiscoveryListener receives the change and all the current instances of
+// @serviceName. It must not subscribe the same service again.
+type DiscoveryListener func(serviceName string, diff InstancesDiff, instances []model.Instance)
+
+// DiscoveryOption configures Discovery
+type DiscoveryOption func(*Discovery)
+
+// WithDiscoveryGroup sets the group of the services, it is constant.DEFAULT_GROUP by default
+func WithDiscoveryGroup(group string) DiscoveryOption {
+	return func(d *Discovery) {
+		d.group = group
+	}
+}
+
+// WithDiscoveryClusters only keeps the instances in @clusters
+func WithDiscoveryClusters(clusters ...string) DiscoveryOption {
+	return func(d *Discovery) {
+		d.clusters = clusters
+	}
+}
+
+// WithMetadataFilter only keeps the instances whose metadata contains all of @metadata
+func WithMetadataFilter(metadata map[string]string) DiscoveryOption {
+	return WithInstanceFilter(func(instance model.Instance) bool {
+		for k, v := range metadata {
+			if instance.Metadata[k] != v {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WithInstanceFilter adds @filter of the instances
+func WithInstanceFilter(filter InstanceFilter) DiscoveryOption {
+	return func(d *Discovery) {
+		d.filters = append(d.filters, filter)
+	}
+}
+
+// WithInstanceCacheDir persists the instances of the services in @dir, so they
+// are still available if nacos is unreachable after restart.
+func WithInstanceCacheDir(dir string) DiscoveryOption {
+	return func(d *Discovery) {
+		d.cacheDir = dir
+	}
+}
+
+// Discovery subscribes the services on NacosNamingClient and caches their
+// healthy instances, which are enabled, healthy and have positive weight.
+// The client is not closed by Discovery, as it may be shared.
+type Discovery struct {
+	client        *NacosNamingClient
+	group         string
+	clusters      []string
+	filters       []InstanceFilter
+	cacheDir      string
+	retryInterval time.Duration
+
+	lock     sync.RWMutex
+	services map[string]*serviceCache
+	done     chan struct{}
+	closed   bool
+}
+
+type serviceCache struct {
+	// notifyLock keeps the order of the notifications, it is locked before Discovery.lock
+	notifyLock sync.Mutex
+	instances  map[string]model.Instance // instance id -> instance
+	listeners  []DiscoveryListener
+	param      *vo.SubscribeParam
+	subscribed bool
+}
+
+// NewDiscovery returns a Discovery on @client
+func NewDiscovery(client *NacosNamingClient, opts ...DiscoveryOption) *Discovery {
+	d := &Discovery{
+		client:        client,
+		group:         constant.DEFAULT_GROUP,
+		retryInterval: defaultSubscribeRetryInterval,
+		services:      make(map[string]*serviceCache),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Subscribe subscribes @serviceName, and @listener receives its instances at
+// first. If nacos is unreachable but the instances are persisted, the persisted
+// ones are used and the subscription is retried in background.
+func (d *Discovery) Subscribe(serviceName string, listener DiscoveryListener) error {
+	d.lock.Lock()
+	if d.closed {
+		d.lock.Unlock()
+		return ErrDiscoveryClosed
+	}
+	if sc, ok := d.services[serviceName]; ok {
+		d.lock.Unlock()
+		sc.notifyLock.Lock()
+		defer sc.notifyLock.Unlock()
+		d.lock.Lock()
+		sc.listeners = append(sc.listeners, listener)
+		instances := sortedInstances(sc.instances)
+		d.lock.Unlock()
+		if len(instances) > 0 {
+			listener(serviceName, InstancesDiff{Added: instances}, instances)
+		}
+		return nil
+	}
+
+	sc := &serviceCache{
+		instances: make(map[string]model.Instance),
+		listeners: []DiscoveryListener{listener},
+	}
+	for _, instance := range d.loadInstances(serviceName) {
+		sc.instances[instanceKey(instance)] = instance
+	}
+	sc.param = &vo.SubscribeParam{
+		ServiceName: serviceName,
+		GroupName:   d.group,
+		Clusters:    d.clusters,
+		SubscribeCallback: func(services []model.Instance, err error) {
+			if err == nil {
+				d.update(serviceName, sc, services)
+			}
+		},
+	}
+	d.services[serviceName] = sc
+	d.lock.Unlock()
+
+	if instances := sortedInstances(sc.instances); len(instances) > 0 {
+		sc.notifyLock.Lock()
+		listener(serviceName, InstancesDiff{Added: instances}, instances)
+		sc.notifyLock.Unlock()
+	}
+
+	err := d.subscribe(sc)
+	if err == nil {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(sc.instances) == 0 {
+		delete(d.services, serviceName)
+		return err
+	}
+	go d.retrySubscribe(serviceName, sc)
+	return nil
+}
+
+func (d *Discovery) subscribe(sc *serviceCache) error {
+	client := d.client.Client()
+	if client == nil {
+		return ErrNilNamingClient
+	}
+	if err := client.Subscribe(sc.param); err != nil {
+		return perrors.WithMessagef(err, "subscribe service %s", sc.param.ServiceName)
+	}
+	d.lock.Lock()
+	sc.subscribed = true
+	d.lock.Unlock()
+	return nil
+}
+
+func (d *Discovery) retrySubscribe(serviceName string, sc *serviceCache) {
+	ticker := time.NewTicker(d.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+		d.lock.RLock()
+		current := d.services[serviceName]
+		d.lock.RUnlock()
+		if current != sc {
+			// unsubscribed
+			return
+		}
+		if d.subscribe(sc) == nil {
+			return
+		}
+	}
+}
+
+// update replaces the instances of the service with @instances and notifies the listeners
+func (d *Discovery) update(serviceName string, sc *serviceCache, instances []model.Instance) {
+	sc.notifyLock.Lock()
+	defer sc.notifyLock.Unlock()
+
+	selected := make(map[string]model.Instance)
+	for _, instance := range instances {
+		if d.selected(instance) {
+			selected[instanceKey(instance)] = instance
+		}
+	}
+
+	d.lock.Lock()
+	if d.services[serviceName] != sc {
+		d.lock.Unlock()
+		return
+	}
+	diff := diffInstances(sc.instances, selected)
+	if diff.Empty() {
+		d.lock.Unlock()
+		return
+	}
+	sc.instances = selected
+	listeners := append([]DiscoveryListener(nil), sc.listeners...)
+	d.lock.Unlock()
+
+	current := sortedInstances(selected)
+	d.saveInstances(serviceName, current)
+	for _, l := range listeners {
+		l(serviceName, diff, current)
+	}
+}
+
+func (d *Discovery) selected(instance model.Instance) bool {
+	if !instance.Healthy || !instance.Enable || instance.Weight <= 0 {
+		return false
+	}
+	if len(d.clusters) > 0 && !containsString(d.clusters, instance.ClusterName) {
+		return false
+	}
+	for _, filter := range d.filters {
+		if !filter(instance) {
+			return false
+		}
+	}
+	return true
+}
+
+// Instances returns the cached instances of @serviceName sorted by id
+func (d *Discovery) Instances(serviceName string) []model.Instance {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if sc, ok := d.services[serviceName]; ok {
+		return sortedInstances(sc.instances)
+	}
+	return nil
+}
+
+// Unsubscribe removes the listeners and the instances of @serviceName
+func (d *Discovery) Unsubscribe(serviceName string) error {
+	d.lock.Lock()
+	sc, ok := d.services[serviceName]
+	subscribed := ok && sc.subscribed
+	delete(d.services, serviceName)
+	d.lock.Unlock()
+	if !subscribed {
+		return nil
+	}
+	client := d.client.Client()
+	if client == nil {
+		return ErrNilNamingClient
+	}
+	return perrors.WithMessagef(client.Unsubscribe(sc.param), "unsubscribe service %s", serviceName)
+}
+
+// Close unsubscribes all the services
+func (d *Discovery) Close() {
+	d.lock.Lock()
+	if d.closed {
+		d.lock.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.done)
+	services := make([]string, 0, len(d.services))
+	for name := range d.services {
+		services = append(services, name)
+	}
+	d.lock.Unlock()
+
+	for _, name := range services {
+		_ = d.Unsubscribe(name)
+	}
+}
+
+func (d *Discovery) cacheFile(serviceName string) string {
+	return filepath.Join(d.cacheDir, util.GetGroupName(serviceName, d.group)+".json")
+}
+
+// saveInstances persists @instances of the service, the cache is best effort,
+// so the error is ignored.
+func (d *Discovery) saveInstances(serviceName string, instances []model.Instance) {
+	if d.cacheDir == "" {
+		return
+	}
+	data, err := json.Marshal(instances)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(d.cacheDir, 0o755); err != nil {
+		return
+	}
+	file := d.cacheFile(serviceName)
+	tmp := file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, file)
+}
+
+func (d *Discovery) loadInstances(serviceName string) []model.Instance {
+	if d.cacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.cacheFile(serviceName))
+	if err != nil {
+		return nil
+	}
+	var instances []model.Instance
+	if err = json.Unmarshal(data, &instances); err != nil {
+		return nil
+	}
+	return instances
+}
+
+func instanceKey(instance model.Instance) string {
+	if instance.InstanceId != "" {
+		return instance.InstanceId
+	}
+	return fmt.Sprintf("%s#%d#%s", instance.Ip, instance.Port, instance.ClusterName)
+}
+
+func sortedInstances(instances map[string]model.Instance) []model.Instance {
+	keys := make([]string, 0, len(instances))
+	for k := range instances {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]model.Instance, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, instances[k])
+	}
+	return list
+}
+
+// diffInstances returns the change from @oldInstances to @newInstances, the
+// instances in it are sorted by id.
+func diffInstances(oldInstances, newInstances map[string]model.Instance) InstancesDiff {
+	var diff InstancesDiff
+	for _, instance := range sortedInstances(newInstances) {
+		prev, ok := oldInstances[instanceKey(instance)]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, instance)
+		case !reflect.DeepEqual(prev, instance):
+			diff.Updated = append(diff.Updated, instance)
+		}
+	}
+	for _, instance := range sortedInstances(oldInstances) {
+		if _, ok := newInstances[instanceKey(instance)]; !ok {
+			diff.Removed = append(diff.Removed, instance)
+		}
+	}
+	return diff
+}
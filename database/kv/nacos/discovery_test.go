@@ -0,0 +1,190 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type discoveryRecorder struct {
+	lock  sync.Mutex
+	diffs []InstancesDiff
+}
+
+func (r *discoveryRecorder) listener(_ string, diff InstancesDiff, _ []model.Instance) {
+	r.lock.Lock()
+	r.diffs = append(r.diffs, diff)
+	r.lock.Unlock()
+}
+
+func (r *discoveryRecorder) last() InstancesDiff {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.diffs[len(r.diffs)-1]
+}
+
+func (r *discoveryRecorder) count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.diffs)
+}
+
+func newMockNamingClient(mock *MockNamingClient) *NacosNamingClient {
+	client := &NacosNamingClient{name: "mock", valid: 1}
+	client.SetClient(mock)
+	return client
+}
+
+func registerInstance(t *testing.T, mock *MockNamingClient, ip string, healthy bool, metadata map[string]string) {
+	_, err := mock.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          ip,
+		Port:        20000,
+		Weight:      10,
+		Enable:      true,
+		Healthy:     healthy,
+		ServiceName: "demo",
+		Metadata:    metadata,
+	})
+	assert.Nil(t, err)
+}
+
+func TestDiscovery(t *testing.T) {
+	mock := NewMockNamingClient()
+	registerInstance(t, mock, "10.0.0.1", true, map[string]string{"version": "1"})
+	registerInstance(t, mock, "10.0.0.2", false, map[string]string{"version": "1"})
+	registerInstance(t, mock, "10.0.0.3", true, map[string]string{"version": "2"})
+
+	d := NewDiscovery(newMockNamingClient(mock), WithMetadataFilter(map[string]string{"version": "1"}))
+	defer d.Close()
+
+	r := &discoveryRecorder{}
+	assert.Nil(t, d.Subscribe("demo", r.listener))
+	assert.Equal(t, 1, r.count())
+	assert.Equal(t, 1, len(r.last().Added))
+	assert.Equal(t, "10.0.0.1", r.last().Added[0].Ip)
+
+	// becomes healthy
+	registerInstance(t, mock, "10.0.0.2", true, map[string]string{"version": "1"})
+	assert.Equal(t, 2, r.count())
+	assert.Equal(t, "10.0.0.2", r.last().Added[0].Ip)
+	assert.Equal(t, 2, len(d.Instances("demo")))
+
+	// filtered out, no change
+	registerInstance(t, mock, "10.0.0.4", true, map[string]string{"version": "2"})
+	assert.Equal(t, 2, r.count())
+
+	registerInstance(t, mock, "10.0.0.1", true, map[string]string{"version": "1", "weight": "high"})
+	assert.Equal(t, 3, r.count())
+	assert.Equal(t, 1, len(r.last().Updated))
+
+	_, err := mock.DeregisterInstance(vo.DeregisterInstanceParam{Ip: "10.0.0.2", Port: 20000, ServiceName: "demo"})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, r.count())
+	assert.Equal(t, "10.0.0.2", r.last().Removed[0].Ip)
+
+	// the new listener receives the current instances at first
+	r2 := &discoveryRecorder{}
+	assert.Nil(t, d.Subscribe("demo", r2.listener))
+	assert.Equal(t, 1, len(r2.last().Added))
+
+	assert.Nil(t, d.Unsubscribe("demo"))
+	assert.Nil(t, d.Instances("demo"))
+	registerInstance(t, mock, "10.0.0.5", true, map[string]string{"version": "1"})
+	assert.Equal(t, 4, r.count())
+}
+
+func TestDiscoveryClusters(t *testing.T) {
+	mock := NewMockNamingClient()
+	_, err := mock.RegisterInstance(vo.RegisterInstanceParam{
+		Ip: "10.0.0.1", Port: 20000, Weight: 1, Enable: true, Healthy: true, ServiceName: "demo", ClusterName: "a",
+	})
+	assert.Nil(t, err)
+	_, err = mock.RegisterInstance(vo.RegisterInstanceParam{
+		Ip: "10.0.0.2", Port: 20000, Weight: 1, Enable: true, Healthy: true, ServiceName: "demo", ClusterName: "b",
+	})
+	assert.Nil(t, err)
+
+	d := NewDiscovery(newMockNamingClient(mock), WithDiscoveryClusters("a"))
+	defer d.Close()
+	assert.Nil(t, d.Subscribe("demo", func(string, InstancesDiff, []model.Instance) {}))
+	instances := d.Instances("demo")
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, "a", instances[0].ClusterName)
+}
+
+func TestDiscoveryPersistence(t *testing.T) {
+	dir := t.TempDir()
+	mock := NewMockNamingClient()
+	registerInstance(t, mock, "10.0.0.1", true, nil)
+
+	d := NewDiscovery(newMockNamingClient(mock), WithInstanceCacheDir(dir))
+	assert.Nil(t, d.Subscribe("demo", func(string, InstancesDiff, []model.Instance) {}))
+	d.Close()
+
+	// nacos is unreachable after restart
+	mock.CloseClient()
+	d = NewDiscovery(newMockNamingClient(mock), WithInstanceCacheDir(dir))
+	d.retryInterval = 10 * time.Millisecond
+	r := &discoveryRecorder{}
+	assert.Nil(t, d.Subscribe("demo", r.listener))
+	assert.Equal(t, 1, r.count())
+	assert.Equal(t, "10.0.0.1", r.last().Added[0].Ip)
+	assert.Equal(t, 1, len(d.Instances("demo")))
+	d.Close()
+	assert.Equal(t, ErrDiscoveryClosed, d.Subscribe("demo", r.listener))
+
+	// no persisted instances
+	d = NewDiscovery(newMockNamingClient(mock))
+	defer d.Close()
+	assert.NotNil(t, d.Subscribe("demo", r.listener))
+	assert.Nil(t, d.Instances("demo"))
+}
+
+func TestDiscoveryRetry(t *testing.T) {
+	dir := t.TempDir()
+	mock := NewMockNamingClient()
+	registerInstance(t, mock, "10.0.0.1", true, nil)
+	d := NewDiscovery(newMockNamingClient(mock), WithInstanceCacheDir(dir))
+	assert.Nil(t, d.Subscribe("demo", func(string, InstancesDiff, []model.Instance) {}))
+	d.Close()
+
+	// the nil client fails the subscription until it is recovered
+	client := &NacosNamingClient{name: "mock", valid: 1}
+	d = NewDiscovery(client, WithInstanceCacheDir(dir))
+	d.retryInterval = 10 * time.Millisecond
+	defer d.Close()
+	r := &discoveryRecorder{}
+	assert.Nil(t, d.Subscribe("demo", r.listener))
+	assert.Equal(t, 1, r.count())
+
+	registerInstance(t, mock, "10.0.0.2", true, nil)
+	client.SetClient(mock)
+	assert.Eventually(t, func() bool {
+		return len(d.Instances("demo")) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "10.0.0.2", r.last().Added[0].Ip)
+}
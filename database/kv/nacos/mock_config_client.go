@@ -0,0 +1,325 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	perrors "github.com/pkg/errors"
+)
+
+var (
+	// ErrMockClientClosed the mock client has been closed
+	ErrMockClientClosed = perrors.New("nacos mock client is closed")
+	// ErrCasMd5Mismatch the md5 of PublishConfig mismatches the current config
+	ErrCasMd5Mismatch = perrors.New("cas md5 mismatch")
+)
+
+type configListener func(namespace, group, dataId, data string)
+
+type betaConfig struct {
+	content string
+	ips     []string
+}
+
+// MockConfigClient is an in-memory config_client.IConfigClient for tests,
+// it can be injected by NacosConfigClient.SetClient. The listeners are called
+// synchronously by the goroutine which changes the config.
+//
+// The config published with Tag is only visible to GetConfig with the same
+// Tag, and the one published with BetaIps is visible to the client whose ip
+// (see SetClientIP) is in BetaIps until the config is deleted.
+type MockConfigClient struct {
+	lock      sync.RWMutex
+	namespace string
+	clientIP  string
+	configs   map[string]model.ConfigItem // group@@dataId -> config
+	tagged    map[string]string           // group@@dataId#tag -> content
+	beta      map[string]betaConfig       // group@@dataId -> beta config
+	listeners map[string][]configListener // group@@dataId -> listeners
+	closed    bool
+}
+
+var _ config_client.IConfigClient = (*MockConfigClient)(nil)
+
+// NewMockConfigClient returns an empty MockConfigClient of @namespace
+func NewMockConfigClient(namespace string) *MockConfigClient {
+	return &MockConfigClient{
+		namespace: namespace,
+		clientIP:  "127.0.0.1",
+		configs:   make(map[string]model.ConfigItem),
+		tagged:    make(map[string]string),
+		beta:      make(map[string]betaConfig),
+		listeners: make(map[string][]configListener),
+	}
+}
+
+// SetClientIP sets the ip of the client to receive the beta configs, it is 127.0.0.1 by default
+func (m *MockConfigClient) SetClientIP(ip string) {
+	m.lock.Lock()
+	m.clientIP = ip
+	m.lock.Unlock()
+}
+
+func checkConfigParam(param *vo.ConfigParam) error {
+	if param.DataId == "" {
+		return perrors.New("dataId can not be empty")
+	}
+	if param.Group == "" {
+		param.Group = constant.DEFAULT_GROUP
+	}
+	return nil
+}
+
+// GetConfig returns the content of the config, it is empty if the config is absent
+func (m *MockConfigClient) GetConfig(param vo.ConfigParam) (string, error) {
+	if err := checkConfigParam(&param); err != nil {
+		return "", err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.closed {
+		return "", ErrMockClientClosed
+	}
+	key := util.GetGroupName(param.DataId, param.Group)
+	if param.Tag != "" {
+		return m.tagged[key+"#"+param.Tag], nil
+	}
+	if beta, ok := m.beta[key]; ok && containsString(beta.ips, m.clientIP) {
+		return beta.content, nil
+	}
+	return m.configs[key].Content, nil
+}
+
+// PublishConfig creates or updates the config, the update fails if CasMd5 is
+// set and mismatches the md5 of the current content.
+func (m *MockConfigClient) PublishConfig(param vo.ConfigParam) (bool, error) {
+	if err := checkConfigParam(&param); err != nil {
+		return false, err
+	}
+	if param.Content == "" {
+		return false, perrors.New("content can not be empty")
+	}
+
+	key := util.GetGroupName(param.DataId, param.Group)
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return false, ErrMockClientClosed
+	}
+	if param.Tag != "" {
+		m.tagged[key+"#"+param.Tag] = param.Content
+		m.lock.Unlock()
+		return true, nil
+	}
+	if param.BetaIps != "" {
+		ips := strings.Split(param.BetaIps, ",")
+		m.beta[key] = betaConfig{content: param.Content, ips: ips}
+		listeners := m.listeners[key]
+		visible := containsString(ips, m.clientIP)
+		m.lock.Unlock()
+		if visible {
+			m.notify(listeners, param.Group, param.DataId, param.Content)
+		}
+		return true, nil
+	}
+
+	prev, ok := m.configs[key]
+	if param.CasMd5 != "" && (!ok || prev.Md5 != param.CasMd5) {
+		m.lock.Unlock()
+		return false, ErrCasMd5Mismatch
+	}
+	m.configs[key] = model.ConfigItem{
+		DataId:  param.DataId,
+		Group:   param.Group,
+		Content: param.Content,
+		Md5:     util.Md5(param.Content),
+		Tenant:  m.namespace,
+		Appname: param.AppName,
+	}
+	listeners := m.listeners[key]
+	m.lock.Unlock()
+
+	if prev.Content != param.Content {
+		m.notify(listeners, param.Group, param.DataId, param.Content)
+	}
+	return true, nil
+}
+
+// DeleteConfig deletes the config with its beta config, or only the tagged
+// config if Tag is set. The listeners receive empty content.
+func (m *MockConfigClient) DeleteConfig(param vo.ConfigParam) (bool, error) {
+	if err := checkConfigParam(&param); err != nil {
+		return false, err
+	}
+
+	key := util.GetGroupName(param.DataId, param.Group)
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return false, ErrMockClientClosed
+	}
+	if param.Tag != "" {
+		delete(m.tagged, key+"#"+param.Tag)
+		m.lock.Unlock()
+		return true, nil
+	}
+	_, ok := m.configs[key]
+	delete(m.configs, key)
+	delete(m.beta, key)
+	listeners := m.listeners[key]
+	m.lock.Unlock()
+
+	if ok {
+		m.notify(listeners, param.Group, param.DataId, "")
+	}
+	return true, nil
+}
+
+func (m *MockConfigClient) notify(listeners []configListener, group, dataId, content string) {
+	for _, l := range listeners {
+		l(m.namespace, group, dataId, content)
+	}
+}
+
+// ListenConfig adds OnChange of @params as a listener of the config
+func (m *MockConfigClient) ListenConfig(params vo.ConfigParam) error {
+	if err := checkConfigParam(&params); err != nil {
+		return err
+	}
+	if params.OnChange == nil {
+		return perrors.New("onChange can not be nil")
+	}
+
+	key := util.GetGroupName(params.DataId, params.Group)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return ErrMockClientClosed
+	}
+	m.listeners[key] = append(m.listeners[key], params.OnChange)
+	return nil
+}
+
+// CancelListenConfig removes all the listeners of the config
+func (m *MockConfigClient) CancelListenConfig(params vo.ConfigParam) error {
+	if err := checkConfigParam(&params); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.listeners, util.GetGroupName(params.DataId, params.Group))
+	return nil
+}
+
+// SearchConfig searches the configs by page, "*" in DataId or Group matches any
+// characters if Search is "blur", and the empty condition matches all.
+func (m *MockConfigClient) SearchConfig(param vo.SearchConfigParam) (*model.ConfigPage, error) {
+	if param.Search != "accurate" && param.Search != "blur" {
+		return nil, perrors.Errorf("search must be accurate or blur, but got %q", param.Search)
+	}
+	if param.PageNo <= 0 {
+		param.PageNo = 1
+	}
+	if param.PageSize <= 0 {
+		param.PageSize = 10
+	}
+
+	m.lock.RLock()
+	if m.closed {
+		m.lock.RUnlock()
+		return nil, ErrMockClientClosed
+	}
+	match := func(pattern, s string) bool {
+		if param.Search == "blur" {
+			return matchBlur(pattern, s)
+		}
+		return pattern == "" || pattern == s
+	}
+	var items []model.ConfigItem
+	for _, item := range m.configs {
+		if match(param.DataId, item.DataId) && match(param.Group, item.Group) &&
+			(param.AppName == "" || param.AppName == item.Appname) {
+			items = append(items, item)
+		}
+	}
+	m.lock.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Group != items[j].Group {
+			return items[i].Group < items[j].Group
+		}
+		return items[i].DataId < items[j].DataId
+	})
+	page := &model.ConfigPage{
+		TotalCount:     len(items),
+		PageNumber:     param.PageNo,
+		PagesAvailable: (len(items) + param.PageSize - 1) / param.PageSize,
+	}
+	if start := (param.PageNo - 1) * param.PageSize; start < len(items) {
+		end := start + param.PageSize
+		if end > len(items) {
+			end = len(items)
+		}
+		page.PageItems = items[start:end]
+	}
+	return page, nil
+}
+
+// CloseClient closes the client, the configs are kept
+func (m *MockConfigClient) CloseClient() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+	m.listeners = make(map[string][]configListener)
+}
+
+// matchBlur matches @s with @pattern in which "*" matches any characters
+func matchBlur(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockConfigClient(t *testing.T) {
+	mock := NewMockConfigClient("ns")
+	client := &NacosConfigClient{name: "mock", valid: 1}
+	client.SetClient(mock)
+
+	var changes []string
+	err := client.Client().ListenConfig(vo.ConfigParam{
+		DataId: "app",
+		OnChange: func(namespace, group, dataId, data string) {
+			assert.Equal(t, "ns", namespace)
+			assert.Equal(t, "DEFAULT_GROUP", group)
+			changes = append(changes, data)
+		},
+	})
+	assert.Nil(t, err)
+
+	content, err := client.Client().GetConfig(vo.ConfigParam{DataId: "app"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", content)
+	_, err = client.Client().PublishConfig(vo.ConfigParam{DataId: "app"})
+	assert.NotNil(t, err)
+
+	ok, err := client.Client().PublishConfig(vo.ConfigParam{DataId: "app", Content: "v1"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	// the same content does not notify the listener
+	_, _ = client.Client().PublishConfig(vo.ConfigParam{DataId: "app", Content: "v1"})
+	_, err = client.Client().PublishConfig(vo.ConfigParam{DataId: "app", Content: "v2", CasMd5: util.Md5("v0")})
+	assert.Equal(t, ErrCasMd5Mismatch, err)
+	_, err = client.Client().PublishConfig(vo.ConfigParam{DataId: "app", Content: "v2", CasMd5: util.Md5("v1")})
+	assert.Nil(t, err)
+	content, err = client.Client().GetConfig(vo.ConfigParam{DataId: "app", Group: "DEFAULT_GROUP"})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", content)
+
+	_, err = client.Client().DeleteConfig(vo.ConfigParam{DataId: "app"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1", "v2", ""}, changes)
+
+	assert.Nil(t, client.Client().CancelListenConfig(vo.ConfigParam{DataId: "app"}))
+	_, _ = client.Client().PublishConfig(vo.ConfigParam{DataId: "app", Content: "v3"})
+	assert.Equal(t, 3, len(changes))
+
+	client.Client().CloseClient()
+	_, err = client.Client().GetConfig(vo.ConfigParam{DataId: "app"})
+	assert.Equal(t, ErrMockClientClosed, err)
+}
+
+func TestMockConfigClientSearch(t *testing.T) {
+	mock := NewMockConfigClient("")
+	for _, dataID := range []string{"a:1", "a:2", "a:3", "b:1"} {
+		_, err := mock.PublishConfig(vo.ConfigParam{DataId: dataID, Group: "g", Content: dataID})
+		assert.Nil(t, err)
+	}
+
+	page, err := mock.SearchConfig(vo.SearchConfigParam{Search: "blur", DataId: "a:*", Group: "g", PageSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, page.TotalCount)
+	assert.Equal(t, 2, page.PagesAvailable)
+	assert.Equal(t, 2, len(page.PageItems))
+	assert.Equal(t, "a:1", page.PageItems[0].DataId)
+	page, err = mock.SearchConfig(vo.SearchConfigParam{Search: "blur", DataId: "a:*", Group: "g", PageNo: 2, PageSize: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(page.PageItems))
+	assert.Equal(t, "a:3", page.PageItems[0].DataId)
+
+	page, err = mock.SearchConfig(vo.SearchConfigParam{Search: "accurate", DataId: "b:1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.TotalCount)
+	page, err = mock.SearchConfig(vo.SearchConfigParam{Search: "accurate", Group: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, page.TotalCount)
+	_, err = mock.SearchConfig(vo.SearchConfigParam{})
+	assert.NotNil(t, err)
+}
+
+func TestMatchBlur(t *testing.T) {
+	assert.True(t, matchBlur("", "abc"))
+	assert.True(t, matchBlur("*", "abc"))
+	assert.True(t, matchBlur("a*", "abc"))
+	assert.True(t, matchBlur("*c", "abc"))
+	assert.True(t, matchBlur("a*b*c", "abbc"))
+	assert.False(t, matchBlur("a*d", "abc"))
+	assert.False(t, matchBlur("abc", "abcd"))
+	assert.False(t, matchBlur("ab*bc", "abc"))
+}
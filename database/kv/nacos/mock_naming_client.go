@@ -0,0 +1,382 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/util"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	defaultClusterName = "DEFAULT"
+)
+
+type serviceCallback func(services []model.Instance, err error)
+
+type mockSubscriber struct {
+	clusters []string
+	callback serviceCallback
+}
+
+// MockNamingClient is an in-memory naming_client.INamingClient for tests, it
+// can be injected by NacosNamingClient.SetClient. The subscribers are called
+// synchronously by the goroutine which changes the service.
+type MockNamingClient struct {
+	lock        sync.RWMutex
+	services    map[string]map[string]model.Instance // group@@service -> instance id -> instance
+	subscribers map[string][]*mockSubscriber         // group@@service -> subscribers
+	closed      bool
+}
+
+var _ naming_client.INamingClient = (*MockNamingClient)(nil)
+
+// NewMockNamingClient returns an empty MockNamingClient
+func NewMockNamingClient() *MockNamingClient {
+	return &MockNamingClient{
+		services:    make(map[string]map[string]model.Instance),
+		subscribers: make(map[string][]*mockSubscriber),
+	}
+}
+
+func groupOrDefault(group string) string {
+	if group == "" {
+		return constant.DEFAULT_GROUP
+	}
+	return group
+}
+
+func clusterOrDefault(cluster string) string {
+	if cluster == "" {
+		return defaultClusterName
+	}
+	return cluster
+}
+
+func instanceID(ip string, port uint64, cluster, serviceName string) string {
+	return fmt.Sprintf("%s#%d#%s#%s", ip, port, cluster, serviceName)
+}
+
+// RegisterInstance registers or replaces the instance
+func (m *MockNamingClient) RegisterInstance(param vo.RegisterInstanceParam) (bool, error) {
+	if param.ServiceName == "" {
+		return false, perrors.New("serviceName cannot be empty!")
+	}
+	serviceName := util.GetGroupName(param.ServiceName, groupOrDefault(param.GroupName))
+	cluster := clusterOrDefault(param.ClusterName)
+	metadata := make(map[string]string, len(param.Metadata))
+	for k, v := range param.Metadata {
+		metadata[k] = v
+	}
+	instance := model.Instance{
+		InstanceId:  instanceID(param.Ip, param.Port, cluster, serviceName),
+		Ip:          param.Ip,
+		Port:        param.Port,
+		Weight:      param.Weight,
+		Healthy:     param.Healthy,
+		Enable:      param.Enable,
+		Ephemeral:   param.Ephemeral,
+		ClusterName: cluster,
+		ServiceName: serviceName,
+		Metadata:    metadata,
+	}
+
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return false, ErrMockClientClosed
+	}
+	instances, ok := m.services[serviceName]
+	if !ok {
+		instances = make(map[string]model.Instance)
+		m.services[serviceName] = instances
+	}
+	instances[instance.InstanceId] = instance
+	m.lock.Unlock()
+
+	m.notify(serviceName)
+	return true, nil
+}
+
+// BatchRegisterInstance registers all the instances of @param
+func (m *MockNamingClient) BatchRegisterInstance(param vo.BatchRegisterInstanceParam) (bool, error) {
+	if len(param.Instances) == 0 {
+		return false, perrors.New("instances cannot be empty!")
+	}
+	for _, instance := range param.Instances {
+		instance.ServiceName = param.ServiceName
+		instance.GroupName = param.GroupName
+		if _, err := m.RegisterInstance(instance); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// DeregisterInstance removes the instance
+func (m *MockNamingClient) DeregisterInstance(param vo.DeregisterInstanceParam) (bool, error) {
+	if param.ServiceName == "" {
+		return false, perrors.New("serviceName cannot be empty!")
+	}
+	serviceName := util.GetGroupName(param.ServiceName, groupOrDefault(param.GroupName))
+	id := instanceID(param.Ip, param.Port, clusterOrDefault(param.Cluster), serviceName)
+
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return false, ErrMockClientClosed
+	}
+	_, ok := m.services[serviceName][id]
+	delete(m.services[serviceName], id)
+	m.lock.Unlock()
+
+	if ok {
+		m.notify(serviceName)
+	}
+	return true, nil
+}
+
+// UpdateInstance replaces the instance
+func (m *MockNamingClient) UpdateInstance(param vo.UpdateInstanceParam) (bool, error) {
+	return m.RegisterInstance(vo.RegisterInstanceParam(param))
+}
+
+// instances returns the instances of the service in @clusters sorted by id,
+// it should be called with the lock held.
+func (m *MockNamingClient) instances(serviceName string, clusters []string) []model.Instance {
+	var hosts []model.Instance
+	for _, instance := range m.services[serviceName] {
+		if len(clusters) > 0 && !containsString(clusters, instance.ClusterName) {
+			continue
+		}
+		instance.Metadata = util.DeepCopyMap(instance.Metadata)
+		hosts = append(hosts, instance)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].InstanceId < hosts[j].InstanceId
+	})
+	return hosts
+}
+
+// GetService returns the service with its instances in the clusters
+func (m *MockNamingClient) GetService(param vo.GetServiceParam) (model.Service, error) {
+	group := groupOrDefault(param.GroupName)
+	serviceName := util.GetGroupName(param.ServiceName, group)
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.closed {
+		return model.Service{}, ErrMockClientClosed
+	}
+	return model.Service{
+		Name:      serviceName,
+		GroupName: group,
+		Clusters:  strings.Join(param.Clusters, ","),
+		Hosts:     m.instances(serviceName, param.Clusters),
+		Valid:     true,
+	}, nil
+}
+
+// SelectAllInstances returns all the instances in the clusters
+func (m *MockNamingClient) SelectAllInstances(param vo.SelectAllInstancesParam) ([]model.Instance, error) {
+	service, err := m.GetService(vo.GetServiceParam(param))
+	if err != nil || len(service.Hosts) == 0 {
+		return []model.Instance{}, err
+	}
+	return service.Hosts, nil
+}
+
+// SelectInstances returns the enabled instances whose weight is positive and
+// healthy status equals to HealthyOnly.
+func (m *MockNamingClient) SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error) {
+	service, err := m.GetService(vo.GetServiceParam{
+		Clusters:    param.Clusters,
+		ServiceName: param.ServiceName,
+		GroupName:   param.GroupName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(service.Hosts) == 0 {
+		return []model.Instance{}, perrors.New("instance list is empty!")
+	}
+	var result []model.Instance
+	for _, host := range service.Hosts {
+		if host.Healthy == param.HealthyOnly && host.Enable && host.Weight > 0 {
+			result = append(result, host)
+		}
+	}
+	return result, nil
+}
+
+// SelectOneHealthyInstance selects a healthy instance randomly by weight
+func (m *MockNamingClient) SelectOneHealthyInstance(param vo.SelectOneHealthInstanceParam) (*model.Instance, error) {
+	hosts, err := m.SelectInstances(vo.SelectInstancesParam{
+		Clusters:    param.Clusters,
+		ServiceName: param.ServiceName,
+		GroupName:   param.GroupName,
+		HealthyOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, perrors.New("healthy instance list is empty!")
+	}
+
+	var total float64
+	for _, host := range hosts {
+		total += host.Weight
+	}
+	r := rand.Float64() * total
+	for i := range hosts {
+		r -= hosts[i].Weight
+		if r < 0 {
+			return &hosts[i], nil
+		}
+	}
+	return &hosts[len(hosts)-1], nil
+}
+
+// Subscribe adds the callback of @param, it is called with the current
+// instances at once if there is any.
+func (m *MockNamingClient) Subscribe(param *vo.SubscribeParam) error {
+	if param.SubscribeCallback == nil {
+		return perrors.New("subscribeCallback cannot be nil!")
+	}
+	serviceName := util.GetGroupName(param.ServiceName, groupOrDefault(param.GroupName))
+	s := &mockSubscriber{clusters: param.Clusters, callback: param.SubscribeCallback}
+
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return ErrMockClientClosed
+	}
+	m.subscribers[serviceName] = append(m.subscribers[serviceName], s)
+	hosts := m.instances(serviceName, s.clusters)
+	m.lock.Unlock()
+
+	if len(hosts) > 0 {
+		s.callback(hosts, nil)
+	}
+	return nil
+}
+
+// Unsubscribe removes the callback of @param
+func (m *MockNamingClient) Unsubscribe(param *vo.SubscribeParam) error {
+	serviceName := util.GetGroupName(param.ServiceName, groupOrDefault(param.GroupName))
+	clusters := strings.Join(param.Clusters, ",")
+	callback := reflect.ValueOf(param.SubscribeCallback).Pointer()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	subscribers := m.subscribers[serviceName][:0]
+	for _, s := range m.subscribers[serviceName] {
+		if strings.Join(s.clusters, ",") != clusters || reflect.ValueOf(s.callback).Pointer() != callback {
+			subscribers = append(subscribers, s)
+		}
+	}
+	m.subscribers[serviceName] = subscribers
+	return nil
+}
+
+// notify calls the subscribers of the service with its instances
+func (m *MockNamingClient) notify(serviceName string) {
+	type call struct {
+		callback serviceCallback
+		hosts    []model.Instance
+	}
+	var calls []call
+	m.lock.RLock()
+	for _, s := range m.subscribers[serviceName] {
+		calls = append(calls, call{callback: s.callback, hosts: m.instances(serviceName, s.clusters)})
+	}
+	m.lock.RUnlock()
+
+	for _, c := range calls {
+		if c.hosts == nil {
+			c.hosts = []model.Instance{}
+		}
+		c.callback(c.hosts, nil)
+	}
+}
+
+// GetAllServicesInfo returns the names of the services in the group by page
+func (m *MockNamingClient) GetAllServicesInfo(param vo.GetAllServiceInfoParam) (model.ServiceList, error) {
+	if param.PageNo == 0 {
+		param.PageNo = 1
+	}
+	if param.PageSize == 0 {
+		param.PageSize = 10
+	}
+	prefix := groupOrDefault(param.GroupName) + constant.SERVICE_INFO_SPLITER
+
+	m.lock.RLock()
+	if m.closed {
+		m.lock.RUnlock()
+		return model.ServiceList{}, ErrMockClientClosed
+	}
+	var names []string
+	for name, instances := range m.services {
+		if len(instances) > 0 && strings.HasPrefix(name, prefix) {
+			names = append(names, strings.TrimPrefix(name, prefix))
+		}
+	}
+	m.lock.RUnlock()
+
+	sort.Strings(names)
+	list := model.ServiceList{Count: int64(len(names)), Doms: []string{}}
+	if start := int((param.PageNo - 1) * param.PageSize); start < len(names) {
+		end := start + int(param.PageSize)
+		if end > len(names) {
+			end = len(names)
+		}
+		list.Doms = names[start:end]
+	}
+	return list, nil
+}
+
+// CloseClient closes the client, the instances are kept
+func (m *MockNamingClient) CloseClient() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.closed = true
+	m.subscribers = make(map[string][]*mockSubscriber)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
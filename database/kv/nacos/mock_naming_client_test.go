@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/model"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockNamingClient(t *testing.T) {
+	mock := NewMockNamingClient()
+	client := &NacosNamingClient{name: "mock", valid: 1}
+	client.SetClient(mock)
+
+	var pushed [][]model.Instance
+	callback := func(services []model.Instance, err error) {
+		assert.Nil(t, err)
+		pushed = append(pushed, services)
+	}
+	assert.Nil(t, client.Client().Subscribe(&vo.SubscribeParam{ServiceName: "svc", SubscribeCallback: callback}))
+
+	register := func(ip string, healthy bool) {
+		ok, err := client.Client().RegisterInstance(vo.RegisterInstanceParam{
+			Ip: ip, Port: 20000, Weight: 1, Enable: true, Healthy: healthy,
+			ServiceName: "svc", Metadata: map[string]string{"k": "v"},
+		})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+	register("127.0.0.1", true)
+	register("127.0.0.2", false)
+	assert.Equal(t, 2, len(pushed))
+	assert.Equal(t, 2, len(pushed[1]))
+
+	service, err := client.Client().GetService(vo.GetServiceParam{ServiceName: "svc"})
+	assert.Nil(t, err)
+	assert.Equal(t, "DEFAULT_GROUP@@svc", service.Name)
+	assert.Equal(t, "127.0.0.1", service.Hosts[0].Ip)
+	assert.Equal(t, "DEFAULT", service.Hosts[0].ClusterName)
+	assert.Equal(t, "v", service.Hosts[0].Metadata["k"])
+
+	hosts, err := client.Client().SelectInstances(vo.SelectInstancesParam{ServiceName: "svc", HealthyOnly: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(hosts))
+	host, err := client.Client().SelectOneHealthyInstance(vo.SelectOneHealthInstanceParam{ServiceName: "svc"})
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", host.Ip)
+	hosts, err = client.Client().SelectAllInstances(vo.SelectAllInstancesParam{ServiceName: "svc", Clusters: []string{"other"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(hosts))
+
+	list, err := client.Client().GetAllServicesInfo(vo.GetAllServiceInfoParam{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"svc"}, list.Doms)
+
+	ok, err := client.Client().DeregisterInstance(vo.DeregisterInstanceParam{Ip: "127.0.0.1", Port: 20000, ServiceName: "svc"})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(pushed))
+	assert.Equal(t, 1, len(pushed[2]))
+
+	assert.Nil(t, client.Client().Unsubscribe(&vo.SubscribeParam{ServiceName: "svc", SubscribeCallback: callback}))
+	register("127.0.0.3", true)
+	assert.Equal(t, 3, len(pushed))
+
+	// the subscriber receives the current instances at once
+	assert.Nil(t, client.Client().Subscribe(&vo.SubscribeParam{ServiceName: "svc", SubscribeCallback: callback}))
+	assert.Equal(t, 4, len(pushed))
+	assert.Equal(t, 2, len(pushed[3]))
+}
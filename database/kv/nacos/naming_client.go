@@ -29,72 +29,95 @@ import (
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
 )
 
-var (
-	namingClientPool nacosClientPool
-	clientPoolOnce   sync.Once
+import (
+	"github.com/dubbogo/gost/database/kv"
 )
 
-type nacosClientPool struct {
-	sync.Mutex
-	namingClient map[string]*NacosNamingClient
+// namingClientRegistry holds the shared naming clients by name
+var namingClientRegistry = kv.NewSharedRegistry()
+
+// ConfigureSharedNamingClients configures the idle timeout of the shared naming clients. The sdk
+// keeps the connection to the server by itself, so the health check only recreates the
+// sdk client which has been closed.
+func ConfigureSharedNamingClients(opts ...kv.SharedOption) {
+	namingClientRegistry.Configure(opts...)
 }
 
 type NacosNamingClient struct {
-	name        string
-	clientLock  sync.Mutex // for Client
-	client      naming_client.INamingClient
-	config      vo.NacosClientParam //conn config
-	valid       uint32
-	activeCount uint32
-	share       bool
+	name       string
+	clientLock sync.Mutex // for Client
+	client     naming_client.INamingClient
+	config     vo.NacosClientParam //conn config
+	valid      uint32
+	share      bool
 }
 
-func initNacosClientPool() {
-	namingClientPool.namingClient = make(map[string]*NacosNamingClient)
+// sharedNamingClient makes NacosNamingClient a kv.SharedClient
+type sharedNamingClient struct {
+	*NacosNamingClient
 }
 
-// NewNacosNamingClient create nacos client
-func NewNacosNamingClient(name string, share bool, sc []constant.ServerConfig,
-	cc constant.ClientConfig) (*NacosNamingClient, error) {
+// Healthy reports whether the sdk client is not closed. The server is not probed,
+// as recreating the sdk client would drop its listeners while the sdk reconnects.
+func (s sharedNamingClient) Healthy() bool {
+	return s.NacosClientValid() && s.Client() != nil
+}
 
-	namingClient := &NacosNamingClient{
-		name:        name,
-		activeCount: 0,
-		share:       share,
-		config:      vo.NacosClientParam{ClientConfig: &cc, ServerConfigs: sc},
-	}
-	if !share {
-		return namingClient, namingClient.newNamingClient()
-	}
-	clientPoolOnce.Do(initNacosClientPool)
-	namingClientPool.Lock()
-	defer namingClientPool.Unlock()
-	if client, ok := namingClientPool.namingClient[name]; ok {
-		client.activeCount++
-		return client, nil
-	}
+// Reconnect recreates the sdk client
+func (s sharedNamingClient) Reconnect() error {
+	return s.newNamingClient()
+}
 
-	err := namingClient.newNamingClient()
-	if err == nil {
-		namingClientPool.namingClient[name] = namingClient
-	}
-	return namingClient, err
+// Destroy closes the sdk client
+func (s sharedNamingClient) Destroy() {
+	s.destroy()
 }
 
-// newNamingClient create NamingClient
+// newNamingClient creates the sdk client, and closes the previous one
 func (n *NacosNamingClient) newNamingClient() error {
 	client, err := clients.NewNamingClient(n.config)
 	if err != nil {
 		return err
 	}
-	n.activeCount++
-	atomic.StoreUint32(&n.valid, 1)
+	n.clientLock.Lock()
+	prev := n.client
 	n.client = client
+	n.clientLock.Unlock()
+	atomic.StoreUint32(&n.valid, 1)
+	if prev != nil {
+		prev.CloseClient()
+	}
 	return nil
 }
 
+// NewNacosNamingClient create nacos client
+func NewNacosNamingClient(name string, share bool, sc []constant.ServerConfig,
+	cc constant.ClientConfig) (*NacosNamingClient, error) {
+
+	namingClient := &NacosNamingClient{
+		name:   name,
+		share:  share,
+		config: vo.NacosClientParam{ClientConfig: &cc, ServerConfigs: sc},
+	}
+	if !share {
+		return namingClient, namingClient.newNamingClient()
+	}
+	client, err := namingClientRegistry.Acquire(name, func() (kv.SharedClient, error) {
+		if err := namingClient.newNamingClient(); err != nil {
+			return nil, err
+		}
+		return sharedNamingClient{namingClient}, nil
+	})
+	if err != nil {
+		return namingClient, err
+	}
+	return client.(sharedNamingClient).NacosNamingClient, nil
+}
+
 // Client Get NacosNamingClient
 func (n *NacosNamingClient) Client() naming_client.INamingClient {
+	n.clientLock.Lock()
+	defer n.clientLock.Unlock()
 	return n.client
 }
 
@@ -111,23 +134,22 @@ func (n *NacosNamingClient) NacosClientValid() bool {
 	return atomic.LoadUint32(&n.valid) == 1
 }
 
-// Close close client
+// Close close client, the shared client is closed when all the holders close it
 func (n *NacosNamingClient) Close() {
-	namingClientPool.Lock()
-	defer namingClientPool.Unlock()
-	if n.client == nil {
+	if n.share {
+		namingClientRegistry.Release(n.name, sharedNamingClient{n})
 		return
 	}
-	n.activeCount--
-	if n.share {
-		if n.activeCount == 0 {
-			n.client = nil
-			atomic.StoreUint32(&n.valid, 0)
-			delete(namingClientPool.namingClient, n.name)
-		}
-	} else {
-		n.client = nil
-		atomic.StoreUint32(&n.valid, 0)
-		delete(namingClientPool.namingClient, n.name)
+	n.destroy()
+}
+
+func (n *NacosNamingClient) destroy() {
+	n.clientLock.Lock()
+	client := n.client
+	n.client = nil
+	n.clientLock.Unlock()
+	atomic.StoreUint32(&n.valid, 0)
+	if client != nil {
+		client.CloseClient()
 	}
 }
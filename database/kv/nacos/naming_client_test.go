@@ -47,7 +47,7 @@ func TestNewNacosClient(t *testing.T) {
 	assert.True(t, err == nil && client4 != nil)
 
 	assert.Equal(t, client1, client2)
-	assert.Equal(t, client1.activeCount, uint32(2))
+	assert.Equal(t, namingClientRegistry.Refs("nacos"), 2)
 	assert.True(t, client1 != client3)
 	assert.True(t, client1 != client4)
 }
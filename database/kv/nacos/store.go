@@ -0,0 +1,430 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/gost/database/kv"
+	gxnet "github.com/dubbogo/gost/net"
+)
+
+const (
+	// DataIDSeparator replaces "/" of the key in the dataId, because "/" is illegal in nacos dataId
+	DataIDSeparator = ":"
+	// storePollInterval is the interval of searching the configs of a watched prefix
+	storePollInterval = 5 * time.Second
+	storePageSize     = 100
+	storeBufferSize   = 64
+	// ephemeralInstancePort is the port of the naming instance of an ephemeral key,
+	// the instance only carries the value so the port is meaningless
+	ephemeralInstancePort = 1
+	// ephemeralValueKey is the metadata key of the value of an ephemeral key
+	ephemeralValueKey = "kv.value"
+)
+
+var (
+	// ErrNilConfigClient the sdk config client is nil
+	ErrNilConfigClient = perrors.New("nacos config client is nil")
+)
+
+// Store adapts NacosConfigClient to kv.Store. Every key is a config whose dataId is
+// the key with "/" replaced by DataIDSeparator, and all of them are in the same group.
+// Nacos config has no ephemeral config, so an ephemeral key is an ephemeral instance
+// of the naming service named by its dataId, which is removed by nacos once the
+// naming client stops its heartbeat. CreateEphemeral returns kv.ErrNotSupported
+// without WithNamingClient. Nacos has no prefix watch, so WatchPrefix polls the
+// configs and services periodically.
+type Store struct {
+	client       *NacosConfigClient
+	naming       *NacosNamingClient
+	group        string
+	pollInterval time.Duration
+
+	ip            string // the ip of the naming instances
+	ephemeralLock sync.Mutex
+	ephemeral     map[string]struct{} // the ephemeral keys created by the store
+
+	closeOnce sync.Once
+	done      chan struct{} // closed by Close to stop the watches
+}
+
+var _ kv.Store = (*Store)(nil)
+
+// StoreOption configures a Store
+type StoreOption func(*Store)
+
+// WithNamingClient makes the store create the ephemeral keys by @naming, which
+// will be closed when the store is closed.
+func WithNamingClient(naming *NacosNamingClient) StoreOption {
+	return func(s *Store) {
+		s.naming = naming
+	}
+}
+
+// NewStore returns a kv.Store backed by @client, the configs are in @group and
+// constant.DEFAULT_GROUP is used if it is empty. The client will be closed when
+// the store is closed.
+func NewStore(client *NacosConfigClient, group string, opts ...StoreOption) *Store {
+	if group == "" {
+		group = constant.DEFAULT_GROUP
+	}
+	s := &Store{
+		client:       client,
+		group:        group,
+		pollInterval: storePollInterval,
+		ephemeral:    make(map[string]struct{}),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.naming != nil {
+		ip, err := gxnet.GetLocalIP()
+		if err != nil {
+			ip = "127.0.0.1"
+		}
+		s.ip = ip
+	}
+	return s
+}
+
+// Client returns the nacos config client of the store
+func (s *Store) Client() *NacosConfigClient {
+	return s.client
+}
+
+// NamingClient returns the nacos naming client of the store, it is nil
+// without WithNamingClient
+func (s *Store) NamingClient() *NacosNamingClient {
+	return s.naming
+}
+
+// KeyToDataID converts the kv key into the nacos dataId
+func KeyToDataID(key string) string {
+	return strings.ReplaceAll(strings.Trim(key, "/"), "/", DataIDSeparator)
+}
+
+// DataIDToKey converts the nacos dataId into the kv key
+func DataIDToKey(dataID string) string {
+	return "/" + strings.ReplaceAll(dataID, DataIDSeparator, "/")
+}
+
+// Get gets the value of @key
+func (s *Store) Get(key string) (string, error) {
+	client := s.client.Client()
+	if client == nil {
+		return "", ErrNilConfigClient
+	}
+	content, err := client.GetConfig(vo.ConfigParam{DataId: KeyToDataID(key), Group: s.group})
+	if err != nil {
+		return "", perrors.WithMessagef(err, "get config (key %s)", key)
+	}
+	// nacos refuses empty content, so the empty one means the config is absent
+	if content != "" {
+		return content, nil
+	}
+	if s.naming == nil {
+		return "", kv.ErrKeyNotFound
+	}
+	value, ok, err := s.getEphemeral(KeyToDataID(key))
+	if err != nil {
+		return "", perrors.WithMessagef(err, "get ephemeral (key %s)", key)
+	}
+	if !ok {
+		return "", kv.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// getEphemeral gets the value of the ephemeral key whose service is @serviceName
+func (s *Store) getEphemeral(serviceName string) (string, bool, error) {
+	client := s.naming.Client()
+	if client == nil {
+		return "", false, ErrNilNamingClient
+	}
+	instances, err := client.SelectAllInstances(vo.SelectAllInstancesParam{
+		ServiceName: serviceName,
+		GroupName:   s.group,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	for _, instance := range instances {
+		if value, ok := instance.Metadata[ephemeralValueKey]; ok {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Put publishes @value as the config of @key, @value should not be empty
+func (s *Store) Put(key string, value string) error {
+	client := s.client.Client()
+	if client == nil {
+		return ErrNilConfigClient
+	}
+	ok, err := client.PublishConfig(vo.ConfigParam{DataId: KeyToDataID(key), Group: s.group, Content: value})
+	if err != nil {
+		return perrors.WithMessagef(err, "publish config (key %s)", key)
+	}
+	if !ok {
+		return perrors.Errorf("publish config (key %s) fail", key)
+	}
+	return nil
+}
+
+// CreateEphemeral registers an ephemeral instance carrying @value to the service
+// named by the dataId of @key, it returns kv.ErrNotSupported without WithNamingClient
+func (s *Store) CreateEphemeral(key string, value string) error {
+	if s.naming == nil {
+		return kv.ErrNotSupported
+	}
+	client := s.naming.Client()
+	if client == nil {
+		return ErrNilNamingClient
+	}
+	ok, err := client.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          s.ip,
+		Port:        ephemeralInstancePort,
+		Weight:      1,
+		Enable:      true,
+		Healthy:     true,
+		Ephemeral:   true,
+		Metadata:    map[string]string{ephemeralValueKey: value},
+		ServiceName: KeyToDataID(key),
+		GroupName:   s.group,
+	})
+	if err != nil {
+		return perrors.WithMessagef(err, "register instance (key %s)", key)
+	}
+	if !ok {
+		return perrors.Errorf("register instance (key %s) fail", key)
+	}
+	s.ephemeralLock.Lock()
+	s.ephemeral[key] = struct{}{}
+	s.ephemeralLock.Unlock()
+	return nil
+}
+
+// Delete deletes the config of @key, and the ephemeral key created by the store
+func (s *Store) Delete(key string) error {
+	client := s.client.Client()
+	if client == nil {
+		return ErrNilConfigClient
+	}
+	if _, err := client.DeleteConfig(vo.ConfigParam{DataId: KeyToDataID(key), Group: s.group}); err != nil {
+		return perrors.WithMessagef(err, "delete config (key %s)", key)
+	}
+
+	s.ephemeralLock.Lock()
+	_, ok := s.ephemeral[key]
+	delete(s.ephemeral, key)
+	s.ephemeralLock.Unlock()
+	if !ok {
+		return nil
+	}
+	return perrors.WithMessagef(s.deregister(key), "deregister instance (key %s)", key)
+}
+
+func (s *Store) deregister(key string) error {
+	client := s.naming.Client()
+	if client == nil {
+		return ErrNilNamingClient
+	}
+	_, err := client.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          s.ip,
+		Port:        ephemeralInstancePort,
+		Ephemeral:   true,
+		ServiceName: KeyToDataID(key),
+		GroupName:   s.group,
+	})
+	return err
+}
+
+// Children returns the names of the direct children of @key
+func (s *Store) Children(key string) ([]string, error) {
+	kvs, err := s.list(key)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	return kv.ChildNames(key, keys), nil
+}
+
+// list searches all the configs under @prefix
+func (s *Store) list(prefix string) (map[string]string, error) {
+	client := s.client.Client()
+	if client == nil {
+		return nil, ErrNilConfigClient
+	}
+	dataIDPrefix := KeyToDataID(prefix)
+	if dataIDPrefix != "" {
+		dataIDPrefix += DataIDSeparator
+	}
+
+	kvs := make(map[string]string)
+	for pageNo := 1; ; pageNo++ {
+		page, err := client.SearchConfig(vo.SearchConfigParam{
+			Search:   "blur",
+			DataId:   dataIDPrefix + "*",
+			Group:    s.group,
+			PageNo:   pageNo,
+			PageSize: storePageSize,
+		})
+		if err != nil {
+			return nil, perrors.WithMessagef(err, "search config (prefix %s)", prefix)
+		}
+		if page == nil {
+			break
+		}
+		for _, item := range page.PageItems {
+			// blur search may match more than the prefix
+			if strings.HasPrefix(item.DataId, dataIDPrefix) {
+				kvs[DataIDToKey(item.DataId)] = item.Content
+			}
+		}
+		if pageNo >= page.PagesAvailable || len(page.PageItems) == 0 {
+			break
+		}
+	}
+	if s.naming != nil {
+		if err := s.listEphemeral(dataIDPrefix, kvs); err != nil {
+			return nil, perrors.WithMessagef(err, "list ephemeral (prefix %s)", prefix)
+		}
+	}
+	return kvs, nil
+}
+
+// listEphemeral adds the ephemeral keys whose dataId starts with @dataIDPrefix to @kvs
+func (s *Store) listEphemeral(dataIDPrefix string, kvs map[string]string) error {
+	client := s.naming.Client()
+	if client == nil {
+		return ErrNilNamingClient
+	}
+	var services []string
+	for pageNo := uint32(1); ; pageNo++ {
+		list, err := client.GetAllServicesInfo(vo.GetAllServiceInfoParam{
+			GroupName: s.group,
+			PageNo:    pageNo,
+			PageSize:  storePageSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range list.Doms {
+			if strings.HasPrefix(name, dataIDPrefix) {
+				services = append(services, name)
+			}
+		}
+		if len(list.Doms) < storePageSize || int64(pageNo)*storePageSize >= list.Count {
+			break
+		}
+	}
+
+	for _, name := range services {
+		value, ok, err := s.getEphemeral(name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			kvs[DataIDToKey(name)] = value
+		}
+	}
+	return nil
+}
+
+// WatchPrefix sends the existing keys under @prefix and their changes by polling
+func (s *Store) WatchPrefix(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	kvs, err := s.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan kv.Event, storeBufferSize)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		snapshot := make(map[string]string)
+		for {
+			for _, e := range kv.Diff(snapshot, kvs) {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			}
+			snapshot = kvs
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+			if kvs, err = s.list(prefix); err != nil {
+				if cause := perrors.Cause(err); cause == ErrNilConfigClient || cause == ErrNilNamingClient {
+					// the clients are closed
+					return
+				}
+				// keep the snapshot and list it again in the next tick
+				kvs = snapshot
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Close stops the watches, removes the ephemeral keys created by the store and closes the clients
+func (s *Store) Close() {
+	s.closeOnce.Do(s.close)
+}
+
+func (s *Store) close() {
+	close(s.done)
+	if s.naming != nil {
+		s.ephemeralLock.Lock()
+		keys := s.ephemeral
+		s.ephemeral = make(map[string]struct{})
+		s.ephemeralLock.Unlock()
+		for key := range keys {
+			_ = s.deregister(key)
+		}
+		s.naming.Close()
+	}
+	s.client.Close()
+}
@@ -0,0 +1,137 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nacos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
+func TestKeyToDataID(t *testing.T) {
+	assert.Equal(t, "dubbo:svc:providers", KeyToDataID("/dubbo/svc/providers"))
+	assert.Equal(t, "/dubbo/svc/providers", DataIDToKey("dubbo:svc:providers"))
+}
+
+func TestStore(t *testing.T) {
+	client := &NacosConfigClient{name: "mock", valid: 1}
+	client.SetClient(NewMockConfigClient(""))
+	store := NewStore(client, "")
+	store.pollInterval = 10 * time.Millisecond
+
+	_, err := store.Get("/dubbo/a")
+	assert.Equal(t, kv.ErrKeyNotFound, err)
+	assert.Equal(t, kv.ErrNotSupported, store.CreateEphemeral("/dubbo/a", "v"))
+
+	// the existing keys are sent first
+	assert.Nil(t, store.Put("/dubbo/z", "0"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.WatchPrefix(ctx, "/dubbo")
+	assert.Nil(t, err)
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/z", Value: "0"}, <-events)
+
+	assert.Nil(t, store.Put("/dubbo/a", "1"))
+	assert.Nil(t, store.Put("/dubbo/b/c", "2"))
+	assert.Nil(t, store.Put("/other", "3"))
+	v, err := store.Get("/dubbo/a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	children, err := store.Children("/dubbo")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "z"}, children)
+
+	e := <-events
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/a", Value: "1"}, e)
+	e = <-events
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/b/c", Value: "2"}, e)
+
+	assert.Nil(t, store.Delete("/dubbo/a"))
+	e = <-events
+	assert.Equal(t, kv.Event{Type: kv.EventDelete, Key: "/dubbo/a"}, e)
+
+	cancel()
+	for range events {
+	}
+	// the watch stops and closes the channel once the store is closed
+	events, err = store.WatchPrefix(context.Background(), "/dubbo")
+	assert.Nil(t, err)
+	store.Close()
+	for range events {
+	}
+	assert.Nil(t, client.Client())
+	store.Close()
+}
+
+func TestStoreEphemeral(t *testing.T) {
+	client := &NacosConfigClient{name: "mock", valid: 1}
+	client.SetClient(NewMockConfigClient(""))
+	namingClient := NewMockNamingClient()
+	naming := &NacosNamingClient{name: "mock", valid: 1}
+	naming.SetClient(namingClient)
+	store := NewStore(client, "", WithNamingClient(naming))
+	store.pollInterval = 10 * time.Millisecond
+	assert.Equal(t, naming, store.NamingClient())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.WatchPrefix(ctx, "/dubbo")
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.CreateEphemeral("/dubbo/svc/providers/p1", "p1"))
+	assert.Nil(t, store.Put("/dubbo/svc/configurators", "c"))
+	v, err := store.Get("/dubbo/svc/providers/p1")
+	assert.Nil(t, err)
+	assert.Equal(t, "p1", v)
+	children, err := store.Children("/dubbo/svc")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"configurators", "providers"}, children)
+	instances, err := namingClient.SelectAllInstances(vo.SelectAllInstancesParam{ServiceName: "dubbo:svc:providers:p1"})
+	assert.Nil(t, err)
+	assert.Len(t, instances, 1)
+	assert.True(t, instances[0].Ephemeral)
+
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/svc/configurators", Value: "c"}, <-events)
+	assert.Equal(t, kv.Event{Type: kv.EventAdd, Key: "/dubbo/svc/providers/p1", Value: "p1"}, <-events)
+
+	assert.Nil(t, store.Delete("/dubbo/svc/providers/p1"))
+	assert.Equal(t, kv.Event{Type: kv.EventDelete, Key: "/dubbo/svc/providers/p1"}, <-events)
+	_, err = store.Get("/dubbo/svc/providers/p1")
+	assert.Equal(t, kv.ErrKeyNotFound, err)
+
+	// the ephemeral keys are removed when the store is closed
+	assert.Nil(t, store.CreateEphemeral("/dubbo/svc/providers/p2", "p2"))
+	cancel()
+	for range events {
+	}
+	store.Close()
+	// the mock keeps the instances after it is closed
+	namingClient.lock.RLock()
+	assert.Empty(t, namingClient.services["DEFAULT_GROUP@@dubbo:svc:providers:p2"])
+	namingClient.lock.RUnlock()
+}
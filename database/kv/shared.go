@@ -0,0 +1,280 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kv
+
+import (
+	"sync"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+var (
+	// ErrRegistryClosed is returned when the shared registry has been closed
+	ErrRegistryClosed = perrors.New("kv: shared registry is closed")
+)
+
+// SharedClient is a client which can be shared by name in SharedRegistry
+type SharedClient interface {
+	// Healthy reports whether the client is available
+	Healthy() bool
+	// Reconnect recreates the connection of the unhealthy client in place,
+	// so the holders of the client are not affected
+	Reconnect() error
+	// Destroy closes the client when no one refers to it
+	Destroy()
+}
+
+// SharedFactory creates the client when there is no client of the name
+type SharedFactory func() (SharedClient, error)
+
+// SharedOption configures SharedRegistry
+type SharedOption func(*SharedRegistry)
+
+// WithIdleTimeout keeps the released client for @timeout before destroying
+// it, so it can be reused by the next Acquire. The client is destroyed at
+// once if @timeout is not positive, which is the default.
+func WithIdleTimeout(timeout time.Duration) SharedOption {
+	return func(r *SharedRegistry) {
+		r.idleTimeout = timeout
+	}
+}
+
+// WithHealthCheck checks the clients every @interval and reconnects the
+// unhealthy ones, it is disabled if @interval is not positive, which is the default.
+func WithHealthCheck(interval time.Duration) SharedOption {
+	return func(r *SharedRegistry) {
+		r.healthCheckInterval = interval
+	}
+}
+
+// SharedRegistry is a reference-counted registry of the clients shared by name
+type SharedRegistry struct {
+	lock                sync.Mutex
+	clients             map[string]*sharedEntry
+	idleTimeout         time.Duration
+	healthCheckInterval time.Duration
+	lastHealthCheck     time.Time
+	loopOnce            sync.Once
+	reset               chan struct{} // wakes up the loop after configured
+	done                chan struct{}
+	closed              bool
+}
+
+type sharedEntry struct {
+	// lock serializes Reconnect and Destroy of the client
+	lock      sync.Mutex
+	client    SharedClient
+	refs      int
+	idleSince time.Time
+	destroyed bool
+}
+
+func (e *sharedEntry) reconnect() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if !e.destroyed && !e.client.Healthy() {
+		// retry in the next check if it fails
+		_ = e.client.Reconnect()
+	}
+}
+
+func (e *sharedEntry) destroy() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if !e.destroyed {
+		e.destroyed = true
+		e.client.Destroy()
+	}
+}
+
+// NewSharedRegistry returns an empty SharedRegistry
+func NewSharedRegistry(opts ...SharedOption) *SharedRegistry {
+	r := &SharedRegistry{
+		clients: make(map[string]*sharedEntry),
+		reset:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Configure applies @opts to the registry, the clients in it are kept
+func (r *SharedRegistry) Configure(opts ...SharedOption) {
+	r.lock.Lock()
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.lock.Unlock()
+
+	select {
+	case r.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Acquire returns the client of @name and increases its reference count, the
+// client is created by @factory if it is absent.
+func (r *SharedRegistry) Acquire(name string, factory SharedFactory) (SharedClient, error) {
+	r.loopOnce.Do(func() {
+		go r.loop()
+	})
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return nil, ErrRegistryClosed
+	}
+	if e, ok := r.clients[name]; ok {
+		e.refs++
+		return e.client, nil
+	}
+	client, err := factory()
+	if err != nil {
+		return nil, perrors.WithMessagef(err, "create shared client %s", name)
+	}
+	r.clients[name] = &sharedEntry{client: client, refs: 1}
+	return client, nil
+}
+
+// Release decreases the reference count of the client of @name, and destroys
+// it when no one refers to it and the idle timeout is not set. It returns false
+// if @client is not the client of @name or it has been released completely.
+func (r *SharedRegistry) Release(name string, client SharedClient) bool {
+	r.lock.Lock()
+	e, ok := r.clients[name]
+	if !ok || e.client != client || e.refs == 0 {
+		r.lock.Unlock()
+		return false
+	}
+	e.refs--
+	if e.refs > 0 {
+		r.lock.Unlock()
+		return true
+	}
+	if r.idleTimeout > 0 {
+		e.idleSince = time.Now()
+		r.lock.Unlock()
+		return true
+	}
+	delete(r.clients, name)
+	r.lock.Unlock()
+
+	e.destroy()
+	return true
+}
+
+// Refs returns the reference count of the client of @name
+func (r *SharedRegistry) Refs(name string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if e, ok := r.clients[name]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+// Close destroys all the clients, and the registry can not be used any more
+func (r *SharedRegistry) Close() {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.done)
+	entries := r.clients
+	r.clients = make(map[string]*sharedEntry)
+	r.lock.Unlock()
+
+	for _, e := range entries {
+		e.destroy()
+	}
+}
+
+// tickInterval returns the interval of the loop, 0 means there is nothing to do
+func (r *SharedRegistry) tickInterval() time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	interval := r.healthCheckInterval
+	if r.idleTimeout > 0 && (interval <= 0 || r.idleTimeout < interval) {
+		interval = r.idleTimeout
+	}
+	return interval
+}
+
+func (r *SharedRegistry) loop() {
+	for {
+		var (
+			timer *time.Timer
+			tick  <-chan time.Time
+		)
+		if interval := r.tickInterval(); interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+		select {
+		case <-r.done:
+		case <-r.reset:
+			// the idle clients are destroyed at once if the idle timeout is reset to 0
+			r.check(time.Now())
+		case now := <-tick:
+			r.check(now)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+	}
+}
+
+// check destroys the idle clients and reconnects the unhealthy ones
+func (r *SharedRegistry) check(now time.Time) {
+	var idle, alive []*sharedEntry
+	r.lock.Lock()
+	for name, e := range r.clients {
+		if e.refs == 0 && now.Sub(e.idleSince) >= r.idleTimeout {
+			delete(r.clients, name)
+			idle = append(idle, e)
+		} else {
+			alive = append(alive, e)
+		}
+	}
+	healthCheck := r.healthCheckInterval > 0 && now.Sub(r.lastHealthCheck) >= r.healthCheckInterval
+	if healthCheck {
+		r.lastHealthCheck = now
+	}
+	r.lock.Unlock()
+
+	for _, e := range idle {
+		e.destroy()
+	}
+	if healthCheck {
+		for _, e := range alive {
+			e.reconnect()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kv
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSharedClient struct {
+	healthy    int32
+	reconnects int32
+	destroyed  int32
+}
+
+func (c *testSharedClient) Healthy() bool {
+	return atomic.LoadInt32(&c.healthy) == 1
+}
+
+func (c *testSharedClient) Reconnect() error {
+	atomic.AddInt32(&c.reconnects, 1)
+	atomic.StoreInt32(&c.healthy, 1)
+	return nil
+}
+
+func (c *testSharedClient) Destroy() {
+	atomic.AddInt32(&c.destroyed, 1)
+}
+
+func newTestSharedClient() (SharedClient, error) {
+	return &testSharedClient{healthy: 1}, nil
+}
+
+func TestSharedRegistry(t *testing.T) {
+	r := NewSharedRegistry()
+	defer r.Close()
+
+	c1, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	c2, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	assert.True(t, c1 == c2)
+	assert.Equal(t, 2, r.Refs("a"))
+
+	_, err = r.Acquire("b", func() (SharedClient, error) {
+		return nil, perrors.New("dial fail")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, r.Refs("b"))
+
+	assert.True(t, r.Release("a", c1))
+	assert.Equal(t, int32(0), c1.(*testSharedClient).destroyed)
+	assert.True(t, r.Release("a", c2))
+	assert.Equal(t, int32(1), c1.(*testSharedClient).destroyed)
+	// released completely
+	assert.False(t, r.Release("a", c1))
+
+	c3, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	assert.True(t, c1 != c3)
+	assert.False(t, r.Release("a", c1))
+	assert.Equal(t, 1, r.Refs("a"))
+
+	r.Close()
+	assert.Equal(t, int32(1), c3.(*testSharedClient).destroyed)
+	_, err = r.Acquire("a", newTestSharedClient)
+	assert.Equal(t, ErrRegistryClosed, err)
+}
+
+func TestSharedRegistryConcurrent(t *testing.T) {
+	r := NewSharedRegistry()
+	defer r.Close()
+
+	var created int32
+	factory := func() (SharedClient, error) {
+		atomic.AddInt32(&created, 1)
+		return newTestSharedClient()
+	}
+	c, err := r.Acquire("a", factory)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := r.Acquire("a", factory)
+			assert.NoError(t, err)
+			r.Release("a", client)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&created))
+	assert.Equal(t, 1, r.Refs("a"))
+	assert.Equal(t, int32(0), c.(*testSharedClient).destroyed)
+}
+
+func TestSharedRegistryIdleTimeout(t *testing.T) {
+	r := NewSharedRegistry(WithIdleTimeout(50 * time.Millisecond))
+	defer r.Close()
+
+	c1, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	assert.True(t, r.Release("a", c1))
+
+	// reused before it is evicted
+	c2, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	assert.True(t, c1 == c2)
+	assert.True(t, r.Release("a", c2))
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&c1.(*testSharedClient).destroyed) == 1
+	}, time.Second, 10*time.Millisecond)
+	c3, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	assert.True(t, c1 != c3)
+
+	// the idle client is destroyed at once when the idle timeout is disabled
+	assert.True(t, r.Release("a", c3))
+	r.Configure(WithIdleTimeout(0))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&c3.(*testSharedClient).destroyed) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSharedRegistryHealthCheck(t *testing.T) {
+	r := NewSharedRegistry()
+	defer r.Close()
+
+	c, err := r.Acquire("a", newTestSharedClient)
+	assert.NoError(t, err)
+	client := c.(*testSharedClient)
+	atomic.StoreInt32(&client.healthy, 0)
+
+	r.Configure(WithHealthCheck(20 * time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return client.Healthy()
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&client.reconnects))
+}
@@ -0,0 +1,143 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kv defines Store, the common abstraction of the zookeeper, etcd
+// and nacos clients, so a registry can switch its backend by configuration.
+package kv
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+var (
+	// ErrKeyNotFound is returned when the key does not exist
+	ErrKeyNotFound = perrors.New("kv: key not found")
+	// ErrNotSupported is returned when the backend can not support the operation
+	ErrNotSupported = perrors.New("kv: operation not supported")
+	// ErrStoreClosed is returned when the store has been closed
+	ErrStoreClosed = perrors.New("kv: store is closed")
+)
+
+// EventType is the type of the watch event
+type EventType int
+
+const (
+	// EventAdd the key is created
+	EventAdd EventType = iota
+	// EventUpdate the value of the key is changed
+	EventUpdate
+	// EventDelete the key is deleted
+	EventDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdd:
+		return "add"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a key under the watched prefix
+type Event struct {
+	Type  EventType
+	Key   string
+	Value string
+}
+
+// Store is a hierarchical key/value store. The keys are paths separated
+// by "/", eg: /dubbo/org.apache.dubbo.HelloService/providers.
+type Store interface {
+	// Get gets the value of @key, ErrKeyNotFound is returned if it is absent
+	Get(key string) (string, error)
+	// Put creates or updates @key with @value
+	Put(key string, value string) error
+	// CreateEphemeral creates @key with @value, and the key will be removed
+	// when the store is closed or its session to the server is lost
+	CreateEphemeral(key string, value string) error
+	// Delete deletes @key, it is not an error if @key is absent
+	Delete(key string) error
+	// Children returns the names of the direct children of @key, it is empty
+	// if @key is absent
+	Children(key string) ([]string, error)
+	// WatchPrefix first sends the keys existing under @prefix as EventAdd in
+	// the order of key, then sends their changes until @ctx is done or the
+	// store is closed, the channel is closed then.
+	WatchPrefix(ctx context.Context, prefix string) (<-chan Event, error)
+	// Close releases the store
+	Close()
+}
+
+// Diff returns the events which change @oldKVs into @newKVs, the events are
+// sorted by key.
+func Diff(oldKVs, newKVs map[string]string) []Event {
+	var events []Event
+	for k, v := range newKVs {
+		prev, ok := oldKVs[k]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventAdd, Key: k, Value: v})
+		case prev != v:
+			events = append(events, Event{Type: EventUpdate, Key: k, Value: v})
+		}
+	}
+	for k := range oldKVs {
+		if _, ok := newKVs[k]; !ok {
+			events = append(events, Event{Type: EventDelete, Key: k})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
+
+// ChildNames returns the names of the direct children of @parent in @keys,
+// the names are deduplicated and sorted.
+func ChildNames(parent string, keys []string) []string {
+	parent = strings.TrimSuffix(parent, "/") + "/"
+	set := make(map[string]struct{})
+	for _, k := range keys {
+		if !strings.HasPrefix(k, parent) {
+			continue
+		}
+		name := strings.TrimPrefix(k, parent)
+		if idx := strings.Index(name, "/"); idx >= 0 {
+			name = name[:idx]
+		}
+		if name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
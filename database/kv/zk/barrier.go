@@ -0,0 +1,170 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"path"
+	"sort"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	barrierReadyNode = "ready"
+)
+
+// DoubleBarrier enables @count participants to start and finish a computation
+// together. Enter blocks until all participants have entered, and Leave blocks
+// until all participants have left.
+// A DoubleBarrier instance should not be shared by goroutines.
+type DoubleBarrier struct {
+	client    *ZookeeperClient
+	path      string
+	id        string
+	count     int
+	readyPath string
+	nodePath  string
+}
+
+// NewDoubleBarrier returns a DoubleBarrier on @barrierPath, @id should be
+// unique among the participants.
+func NewDoubleBarrier(client *ZookeeperClient, barrierPath string, id string, count int) *DoubleBarrier {
+	return &DoubleBarrier{
+		client:    client,
+		path:      barrierPath,
+		id:        id,
+		count:     count,
+		readyPath: path.Join(barrierPath, barrierReadyNode),
+		nodePath:  path.Join(barrierPath, id),
+	}
+}
+
+// Enter joins the barrier and blocks until all participants have entered
+func (b *DoubleBarrier) Enter(ctx context.Context) error {
+	conn := b.client.getConn()
+	if conn == nil {
+		return ErrNilZkClientConn
+	}
+	if err := b.client.ensurePath(b.path); err != nil {
+		return err
+	}
+
+	exist, _, watcher, err := conn.ExistsW(b.readyPath)
+	if err != nil {
+		return perrors.WithMessagef(err, "zk.ExistsW(path:%s)", b.readyPath)
+	}
+	_, err = conn.Create(b.nodePath, []byte{}, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+	if err != nil && err != zk.ErrNodeExists {
+		conn.RemoveWatcher(watcher)
+		return perrors.WithMessagef(err, "zk.Create(path:%s)", b.nodePath)
+	}
+	if exist {
+		conn.RemoveWatcher(watcher)
+		return nil
+	}
+
+	children, err := b.participants()
+	if err != nil {
+		conn.RemoveWatcher(watcher)
+		return err
+	}
+	if len(children) >= b.count {
+		conn.RemoveWatcher(watcher)
+		_, err = conn.Create(b.readyPath, []byte{}, 0, zk.WorldACL(zk.PermAll))
+		if err != nil && err != zk.ErrNodeExists {
+			return perrors.WithMessagef(err, "zk.Create(path:%s)", b.readyPath)
+		}
+		return nil
+	}
+
+	select {
+	case event := <-watcher.EvtCh:
+		if event.Err != nil {
+			return event.Err
+		}
+		return nil
+	case <-ctx.Done():
+		conn.RemoveWatcher(watcher)
+		return ctx.Err()
+	}
+}
+
+// Leave quits the barrier and blocks until all participants have left
+func (b *DoubleBarrier) Leave(ctx context.Context) error {
+	conn := b.client.getConn()
+	if conn == nil {
+		return ErrNilZkClientConn
+	}
+	for {
+		children, err := b.participants()
+		if err != nil {
+			return err
+		}
+		if len(children) == 0 {
+			break
+		}
+		if len(children) == 1 && children[0] == b.id {
+			if err = conn.Delete(b.nodePath, -1); err != nil && err != zk.ErrNoNode {
+				return perrors.WithMessagef(err, "zk.Delete(path:%s)", b.nodePath)
+			}
+			break
+		}
+
+		// the lowest participant leaves at last, it waits for the highest one,
+		// and others wait for the lowest one after deleting their own nodes.
+		sort.Strings(children)
+		var waitFor string
+		if children[0] == b.id {
+			waitFor = children[len(children)-1]
+		} else {
+			if err = conn.Delete(b.nodePath, -1); err != nil && err != zk.ErrNoNode {
+				return perrors.WithMessagef(err, "zk.Delete(path:%s)", b.nodePath)
+			}
+			waitFor = children[0]
+		}
+		if err = b.client.waitDeleted(ctx, path.Join(b.path, waitFor)); err != nil {
+			return err
+		}
+	}
+
+	// the ready node is useless when all participants have left
+	if err := conn.Delete(b.readyPath, -1); err != nil && err != zk.ErrNoNode {
+		return perrors.WithMessagef(err, "zk.Delete(path:%s)", b.readyPath)
+	}
+	return nil
+}
+
+// participants returns the ids of the participants in the barrier
+func (b *DoubleBarrier) participants() ([]string, error) {
+	children, err := b.client.GetChildren(b.path)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(children))
+	for _, child := range children {
+		if child != barrierReadyNode {
+			ids = append(ids, child)
+		}
+	}
+	return ids, nil
+}
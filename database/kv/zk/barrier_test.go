@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoubleBarrier(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	const count = 3
+	var (
+		wg      sync.WaitGroup
+		entered int32
+	)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := NewDoubleBarrier(z, "/test/barrier", "member"+strconv.Itoa(i), count)
+			assert.NoError(t, b.Enter(context.Background()))
+			atomic.AddInt32(&entered, 1)
+			assert.NoError(t, b.Leave(context.Background()))
+			assert.Equal(t, int32(count), atomic.LoadInt32(&entered))
+		}(i)
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	b := NewDoubleBarrier(z, "/test/barrier", "alone", count)
+	assert.Equal(t, context.DeadlineExceeded, b.Enter(ctx))
+}
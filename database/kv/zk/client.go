@@ -31,13 +31,17 @@ import (
 	perrors "github.com/pkg/errors"
 )
 
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
 const (
 	SLASH = "/"
 )
 
 var (
-	zkClientPool   zookeeperClientPool
-	clientPoolOnce sync.Once
+	// zkClientRegistry holds the shared clients by name
+	zkClientRegistry = kv.NewSharedRegistry()
 
 	// ErrNilZkClientConn no conn error
 	ErrNilZkClientConn = perrors.New("Zookeeper Client{conn} is nil")
@@ -50,10 +54,10 @@ type ZookeeperClient struct {
 	ZkAddrs           []string
 	sync.RWMutex      // for conn
 	Conn              *zk.Conn
-	activeNumber      uint32
 	Timeout           time.Duration
 	Wait              sync.WaitGroup
 	valid             uint32
+	expired           uint32 // the session is expired and is not recovered by the zk sdk yet
 	share             bool
 	initialized       uint32
 	reconnectCh       chan struct{}
@@ -61,11 +65,38 @@ type ZookeeperClient struct {
 	eventRegistryLock sync.RWMutex
 	zkEventHandler    ZkEventHandler
 	Session           <-chan zk.Event
+	// ephemeralRecovery is not nil if the ephemeral nodes should be re-created in new session
+	ephemeralRecovery EphemeralRecoveryCallback
+	ephemeralNodes    map[string]ephemeralNode
+	ephemeralLock     sync.Mutex
+	recoverLock       sync.Mutex // serializes RecoverEphemeralNodes
+}
+
+// sharedClient makes ZookeeperClient a kv.SharedClient
+type sharedClient struct {
+	*ZookeeperClient
+}
+
+// Healthy reports whether the session is alive. The zk sdk reconnects in the same
+// session after disconnected, and a new connection would lose the ephemeral nodes
+// and watches, so only the expired session or the nil conn is unhealthy.
+func (s sharedClient) Healthy() bool {
+	return s.getConn() != nil && atomic.LoadUint32(&s.expired) == 0
+}
+
+// Reconnect replaces the connection with a new one
+func (s sharedClient) Reconnect() error {
+	return s.reconnect()
+}
+
+// Destroy closes the connection
+func (s sharedClient) Destroy() {
+	s.destroy()
 }
 
-type zookeeperClientPool struct {
-	sync.Mutex
-	zkClient map[string]*ZookeeperClient
+// ConfigureSharedClients configures the idle timeout and health check of the shared clients
+func ConfigureSharedClients(opts ...kv.SharedOption) {
+	zkClientRegistry.Configure(opts...)
 }
 
 // ZkEventHandler interface
@@ -102,40 +133,34 @@ func StateToString(state zk.State) string {
 	}
 }
 
-func initZookeeperClientPool() {
-	zkClientPool.zkClient = make(map[string]*ZookeeperClient)
-}
-
 // NewZookeeperClient will create a ZookeeperClient
 func NewZookeeperClient(name string, zkAddrs []string, share bool, opts ...zkClientOption) (*ZookeeperClient, error) {
 	if !share {
 		return newClient(name, zkAddrs, share, opts...)
 	}
-	clientPoolOnce.Do(initZookeeperClientPool)
-	zkClientPool.Lock()
-	defer zkClientPool.Unlock()
-	if zkClient, ok := zkClientPool.zkClient[name]; ok {
-		zkClient.activeNumber++
-		return zkClient, nil
-	}
-	newZkClient, err := newClient(name, zkAddrs, share, opts...)
+	client, err := zkClientRegistry.Acquire(name, func() (kv.SharedClient, error) {
+		newZkClient, err := newClient(name, zkAddrs, share, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return sharedClient{newZkClient}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	zkClientPool.zkClient[name] = newZkClient
-	return newZkClient, nil
+	return client.(sharedClient).ZookeeperClient, nil
 }
 
 func newClient(name string, zkAddrs []string, share bool, opts ...zkClientOption) (*ZookeeperClient, error) {
 	newZkClient := &ZookeeperClient{
 		name:           name,
 		ZkAddrs:        zkAddrs,
-		activeNumber:   0,
 		share:          share,
 		reconnectCh:    make(chan struct{}),
 		eventRegistry:  make(map[string][]chan zk.Event),
 		Session:        make(<-chan zk.Event),
 		zkEventHandler: &DefaultHandler{},
+		ephemeralNodes: make(map[string]ephemeralNode),
 	}
 	for _, opt := range opts {
 		opt(newZkClient)
@@ -144,7 +169,6 @@ func newClient(name string, zkAddrs []string, share bool, opts ...zkClientOption
 	if err != nil {
 		return nil, err
 	}
-	newZkClient.activeNumber++
 	return newZkClient, nil
 }
 
@@ -162,6 +186,31 @@ func (z *ZookeeperClient) createZookeeperConn() error {
 	return nil
 }
 
+// reconnect replaces the connection with a new one, the previous one is closed
+func (z *ZookeeperClient) reconnect() error {
+	conn, session, err := zk.Connect(z.ZkAddrs, z.Timeout)
+	if err != nil {
+		return perrors.WithMessagef(err, "zk.Connect(addrs:%v)", z.ZkAddrs)
+	}
+	z.Lock()
+	prev := z.Conn
+	z.Conn, z.Session = conn, session
+	z.Unlock()
+	atomic.StoreUint32(&z.expired, 0)
+	if prev != nil {
+		prev.Close()
+	}
+	go z.zkEventHandler.HandleZkEvent(z)
+	return nil
+}
+
+// getSession gets the event channel of the current connection safely
+func (z *ZookeeperClient) getSession() <-chan zk.Event {
+	z.RLock()
+	defer z.RUnlock()
+	return z.Session
+}
+
 // WithTestCluster sets test cluster for zk Client
 func WithTestCluster(ts *zk.TestCluster) Option {
 	return func(opt *options) {
@@ -186,6 +235,7 @@ func NewMockZookeeperClient(name string, timeout time.Duration, opts ...Option)
 		eventRegistry:  make(map[string][]chan zk.Event),
 		Session:        make(<-chan zk.Event),
 		zkEventHandler: &DefaultHandler{},
+		ephemeralNodes: make(map[string]ephemeralNode),
 	}
 
 	option := &options{}
@@ -208,17 +258,24 @@ func NewMockZookeeperClient(name string, timeout time.Duration, opts ...Option)
 		return nil, nil, nil, perrors.WithMessagef(err, "zk.Connect fail")
 	}
 	atomic.StoreUint32(&z.valid, 1)
-	z.activeNumber++
 	return ts, z, z.Session, nil
 }
 
 // HandleZkEvent handles zookeeper events
 func (d *DefaultHandler) HandleZkEvent(z *ZookeeperClient) {
 	var state int
-	for event := range z.Session {
+	session := z.getSession()
+	for event := range session {
+		if z.getSession() != session {
+			// the connection has been replaced, drain the events of the previous one
+			continue
+		}
 		switch event.State {
 		case zk.StateDisconnected:
 			atomic.StoreUint32(&z.valid, 0)
+		case zk.StateExpired:
+			atomic.StoreUint32(&z.valid, 0)
+			atomic.StoreUint32(&z.expired, 1)
 		case zk.StateConnected:
 			z.eventRegistryLock.RLock()
 			for path, a := range z.eventRegistry {
@@ -235,10 +292,12 @@ func (d *DefaultHandler) HandleZkEvent(z *ZookeeperClient) {
 			}
 			if event.State == zk.StateHasSession {
 				atomic.StoreUint32(&z.valid, 1)
+				atomic.StoreUint32(&z.expired, 0)
 				//if this is the first connection, don't trigger reconnect event
 				if !atomic.CompareAndSwapUint32(&z.initialized, 0, 1) {
 					close(z.reconnectCh)
 					z.reconnectCh = make(chan struct{})
+					go z.RecoverEphemeralNodes()
 				}
 			}
 			z.eventRegistryLock.RLock()
@@ -358,6 +417,7 @@ func (z *ZookeeperClient) CreateTempWithValue(basePath string, value []byte) err
 			if err != nil {
 				return perrors.WithMessagef(err, "Error while invoking zk.Create(path:%s), the reason maybe is: ", tmpPath)
 			}
+			z.trackEphemeral(tmpPath, value, false)
 			break
 		}
 		// we need ignore node exists error for those parent node
@@ -376,6 +436,7 @@ func (z *ZookeeperClient) Delete(basePath string) error {
 	if conn == nil {
 		return ErrNilZkClientConn
 	}
+	z.untrackEphemeral(basePath)
 	return perrors.WithMessagef(conn.Delete(basePath, -1), "Delete(basePath:%s)", basePath)
 }
 
@@ -391,6 +452,7 @@ func (z *ZookeeperClient) RegisterTemp(basePath string, node string) (string, er
 	if err != nil {
 		return zkPath, perrors.WithStack(err)
 	}
+	z.trackEphemeral(tmpPath, []byte(""), false)
 
 	return tmpPath, nil
 }
@@ -416,6 +478,9 @@ func (z *ZookeeperClient) RegisterTempSeq(basePath string, data []byte) (string,
 	if err != nil && err != zk.ErrNodeExists {
 		return "", perrors.WithStack(err)
 	}
+	if err == nil {
+		z.trackEphemeral(tmpPath, data, true)
+	}
 	return tmpPath, nil
 }
 
@@ -500,23 +565,21 @@ func (z *ZookeeperClient) GetEventHandler() ZkEventHandler {
 	return z.zkEventHandler
 }
 
+// Close closes the client, the shared client is closed when all the holders close it
 func (z *ZookeeperClient) Close() {
 	if z.share {
-		zkClientPool.Lock()
-		defer zkClientPool.Unlock()
-		z.activeNumber--
-		if z.activeNumber == 0 {
-			z.Conn.Close()
-			delete(zkClientPool.zkClient, z.name)
-		}
-	} else {
-		z.Lock()
-		conn := z.Conn
-		z.activeNumber--
-		z.Conn = nil
-		z.Unlock()
-		if conn != nil {
-			conn.Close()
-		}
+		zkClientRegistry.Release(z.name, sharedClient{z})
+		return
+	}
+	z.destroy()
+}
+
+func (z *ZookeeperClient) destroy() {
+	z.Lock()
+	conn := z.Conn
+	z.Conn = nil
+	z.Unlock()
+	if conn != nil {
+		conn.Close()
 	}
 }
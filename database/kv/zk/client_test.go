@@ -103,12 +103,12 @@ func Test_Close(t *testing.T) {
 		t.Fatalf("NewZookeeperClient failed")
 	}
 	client2.Close()
-	assert.Equal(t, client1.activeNumber, uint32(1))
+	assert.Equal(t, zkClientRegistry.Refs("test1"), 1)
 	client1.Close()
-	assert.Equal(t, client1.activeNumber, uint32(0))
+	assert.Equal(t, zkClientRegistry.Refs("test1"), 0)
 	client4, err := NewZookeeperClient("test1", address, true, WithZkTimeOut(3*time.Second))
 	assert.Nil(t, err)
-	assert.Equal(t, client4.activeNumber, uint32(1))
+	assert.Equal(t, zkClientRegistry.Refs("test1"), 1)
 	if client4 == client3 {
 		t.Fatalf("NewZookeeperClient failed")
 	}
@@ -120,15 +120,47 @@ func Test_Close(t *testing.T) {
 		t.Fatalf("NewZookeeperClient failed")
 	}
 	client5.Close()
-	assert.Equal(t, client5.activeNumber, uint32(0))
 	assert.Equal(t, client5.Conn, (*zk.Conn)(nil))
 	assert.NotEqual(t, client6.Conn, nil)
 	client6.Close()
-	assert.Equal(t, client6.activeNumber, uint32(0))
 	assert.Equal(t, client6.Conn, (*zk.Conn)(nil))
 	_ = tc.Stop()
 }
 
+func TestSharedClientHealthy(t *testing.T) {
+	events := make(chan zk.Event, 8)
+	z := &ZookeeperClient{
+		Conn:           &zk.Conn{},
+		Session:        events,
+		reconnectCh:    make(chan struct{}),
+		eventRegistry:  make(map[string][]chan zk.Event),
+		zkEventHandler: &DefaultHandler{},
+		ephemeralNodes: make(map[string]ephemeralNode),
+	}
+	s := sharedClient{z}
+	go z.zkEventHandler.HandleZkEvent(z)
+	defer close(events)
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	// the session is kept after disconnected
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateDisconnected}
+	assert.Eventually(t, func() bool { return !z.ZkConnValid() }, time.Second, 10*time.Millisecond)
+	assert.True(t, s.Healthy())
+
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	assert.Eventually(t, func() bool { return !s.Healthy() }, time.Second, 10*time.Millisecond)
+
+	// the zk sdk gets a new session by itself
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateConnecting}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+	assert.Eventually(t, s.Healthy, time.Second, 10*time.Millisecond)
+
+	z.Lock()
+	z.Conn = nil
+	z.Unlock()
+	assert.False(t, s.Healthy())
+}
+
 func Test_newMockZookeeperClient(t *testing.T) {
 	ts, _, event, err := NewMockZookeeperClient("test", 15*time.Second)
 	assert.NoError(t, err)
@@ -0,0 +1,241 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	electionPrefix = "n-"
+	// electionRetryInterval is the wait time before retrying after a zk error
+	electionRetryInterval = time.Second
+)
+
+var (
+	// ErrElectionStarted is returned when starting a LeaderElection twice
+	ErrElectionStarted = perrors.New("zk leader election has been started")
+	// ErrNoLeader is returned when there is no candidate in the election
+	ErrNoLeader = perrors.New("zk leader election has no leader")
+)
+
+// LeaderElection elects a leader among the candidates which share the same
+// election path. Every candidate owns an ephemeral sequential node, and the
+// one with the lowest sequence number is the leader. When the leader goes
+// away, only the next candidate in line is notified.
+type LeaderElection struct {
+	client *ZookeeperClient
+	path   string
+	id     string
+
+	lock     sync.RWMutex // for nodePath & leader
+	nodePath string
+	leader   bool
+
+	changed chan bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewLeaderElection returns a LeaderElection on @electionPath, @id is the identity
+// of this candidate which will be stored as the data of its node.
+func NewLeaderElection(client *ZookeeperClient, electionPath string, id string) *LeaderElection {
+	return &LeaderElection{
+		client:  client,
+		path:    electionPath,
+		id:      id,
+		changed: make(chan bool, 1),
+	}
+}
+
+// Start joins the election in background. The leadership changes will be sent
+// to LeadershipChanged.
+func (e *LeaderElection) Start() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.cancel != nil {
+		return ErrElectionStarted
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go e.run(ctx)
+	return nil
+}
+
+// LeadershipChanged returns a channel which receives true when this candidate
+// becomes the leader and false when it loses the leadership. Only the latest
+// state is kept if the receiver is slow.
+func (e *LeaderElection) LeadershipChanged() <-chan bool {
+	return e.changed
+}
+
+// IsLeader returns true if this candidate is the leader now
+func (e *LeaderElection) IsLeader() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.leader
+}
+
+// ID returns the identity of this candidate
+func (e *LeaderElection) ID() string {
+	return e.id
+}
+
+// Leader returns the identity of the current leader
+func (e *LeaderElection) Leader() (string, error) {
+	nodes, err := e.client.sortedSeqChildren(e.path)
+	if err != nil {
+		return "", err
+	}
+	for _, node := range nodes {
+		data, _, err := e.client.GetContent(path.Join(e.path, node.name))
+		if err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return "", perrors.WithMessagef(err, "zk.Get(path:%s)", path.Join(e.path, node.name))
+		}
+		return string(data), nil
+	}
+	return "", ErrNoLeader
+}
+
+// Resign quits the election and gives up the leadership if this candidate
+// is the leader. The LeaderElection can be started again after Resign.
+func (e *LeaderElection) Resign() {
+	e.lock.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel = nil
+	e.lock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (e *LeaderElection) run(ctx context.Context) {
+	defer func() {
+		e.deleteNode()
+		e.setLeader(false)
+		close(e.done)
+	}()
+
+	for {
+		err := e.campaign(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// the node may be lost, eg: session expired, so recreate it
+		if err != nil {
+			e.setLeader(false)
+			e.deleteNode()
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.client.Reconnect():
+			case <-time.After(electionRetryInterval):
+			}
+		}
+	}
+}
+
+// campaign returns nil when the node of the candidate need to be checked again,
+// and returns error when the node should be recreated.
+func (e *LeaderElection) campaign(ctx context.Context) error {
+	nodePath := e.getNodePath()
+	if nodePath == "" {
+		var err error
+		if nodePath, err = e.client.createSeqNode(e.path, electionPrefix, []byte(e.id)); err != nil {
+			return err
+		}
+		e.lock.Lock()
+		e.nodePath = nodePath
+		e.lock.Unlock()
+	}
+
+	nodes, err := e.client.sortedSeqChildren(e.path)
+	if err != nil {
+		return err
+	}
+	own, _ := parseSeqNode(path.Base(nodePath))
+	found := false
+	for _, node := range nodes {
+		if node.seq == own.seq {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return perrors.Errorf("the node %s of the candidate is lost", nodePath)
+	}
+
+	prev := exclusiveBlocker(nodes, own)
+	if prev == "" {
+		e.setLeader(true)
+		// watch the own node to find out the leadership is lost
+		prev = path.Base(nodePath)
+	} else {
+		e.setLeader(false)
+	}
+	return e.client.waitDeleted(ctx, path.Join(e.path, prev))
+}
+
+func (e *LeaderElection) getNodePath() string {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.nodePath
+}
+
+func (e *LeaderElection) deleteNode() {
+	e.lock.Lock()
+	nodePath := e.nodePath
+	e.nodePath = ""
+	e.lock.Unlock()
+	if nodePath == "" {
+		return
+	}
+	if conn := e.client.getConn(); conn != nil {
+		_ = conn.Delete(nodePath, -1)
+	}
+}
+
+func (e *LeaderElection) setLeader(leader bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.leader == leader {
+		return
+	}
+	e.leader = leader
+	// drop the stale state
+	select {
+	case <-e.changed:
+	default:
+	}
+	e.changed <- leader
+}
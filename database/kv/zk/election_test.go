@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElection(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	e1 := NewLeaderElection(z, "/test/election", "candidate1")
+	e2 := NewLeaderElection(z, "/test/election", "candidate2")
+	assert.NoError(t, e1.Start())
+	assert.Equal(t, ErrElectionStarted, e1.Start())
+	assert.True(t, <-e1.LeadershipChanged())
+	assert.True(t, e1.IsLeader())
+
+	assert.NoError(t, e2.Start())
+	leader, err := e2.Leader()
+	assert.NoError(t, err)
+	assert.Equal(t, "candidate1", leader)
+	assert.False(t, e2.IsLeader())
+
+	e1.Resign()
+	assert.False(t, <-e1.LeadershipChanged())
+	assert.True(t, <-e2.LeadershipChanged())
+	leader, err = e1.Leader()
+	assert.NoError(t, err)
+	assert.Equal(t, "candidate2", leader)
+
+	e2.Resign()
+	_, err = e2.Leader()
+	assert.Equal(t, ErrNoLeader, err)
+}
@@ -0,0 +1,176 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"path"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+// ErrEphemeralNodeConflict is reported to EphemeralRecoveryCallback if a recorded
+// ephemeral node is owned by another session, eg: another process has registered the
+// same path, it is left as it is.
+var ErrEphemeralNodeConflict = perrors.New("the ephemeral node is owned by another session")
+
+// EphemeralRecoveryCallback is called after an ephemeral node is re-created in a new
+// session. The @newPath differs from @oldPath only for sequential nodes, and @err
+// is not nil if the node can not be re-created.
+type EphemeralRecoveryCallback func(oldPath string, newPath string, err error)
+
+// ephemeralNode is an ephemeral node created by this client
+type ephemeralNode struct {
+	data []byte
+	// sequential node is re-created under its parent with a new sequence number
+	sequential bool
+}
+
+// trackEphemeral records the ephemeral node @zkPath if ephemeral recovery is enabled
+func (z *ZookeeperClient) trackEphemeral(zkPath string, data []byte, sequential bool) {
+	if z.ephemeralRecovery == nil {
+		return
+	}
+	z.ephemeralLock.Lock()
+	defer z.ephemeralLock.Unlock()
+	z.ephemeralNodes[zkPath] = ephemeralNode{data: data, sequential: sequential}
+}
+
+// untrackEphemeral removes @zkPath from the recorded ephemeral nodes
+func (z *ZookeeperClient) untrackEphemeral(zkPath string) {
+	if z.ephemeralRecovery == nil {
+		return
+	}
+	z.ephemeralLock.Lock()
+	defer z.ephemeralLock.Unlock()
+	delete(z.ephemeralNodes, zkPath)
+}
+
+// EphemeralNodes returns the paths of the ephemeral nodes recorded by this client
+func (z *ZookeeperClient) EphemeralNodes() []string {
+	z.ephemeralLock.Lock()
+	defer z.ephemeralLock.Unlock()
+	paths := make([]string, 0, len(z.ephemeralNodes))
+	for zkPath := range z.ephemeralNodes {
+		paths = append(paths, zkPath)
+	}
+	return paths
+}
+
+// RecoverEphemeralNodes re-creates the recorded ephemeral nodes which are not owned
+// by the current session, eg: they have been removed by zookeeper when the session
+// expired. The node owned by another session is never deleted, ErrEphemeralNodeConflict
+// is reported instead. It is called by DefaultHandler after a new session is established, and a
+// custom ZkEventHandler should call it in the same way. The calls are serialized.
+func (z *ZookeeperClient) RecoverEphemeralNodes() {
+	if z.ephemeralRecovery == nil {
+		return
+	}
+	z.recoverLock.Lock()
+	defer z.recoverLock.Unlock()
+
+	z.ephemeralLock.Lock()
+	nodes := make(map[string]ephemeralNode, len(z.ephemeralNodes))
+	for zkPath, node := range z.ephemeralNodes {
+		nodes[zkPath] = node
+	}
+	z.ephemeralLock.Unlock()
+
+	for oldPath, node := range nodes {
+		if !z.isTrackedEphemeral(oldPath) {
+			continue
+		}
+		newPath, recovered, err := z.recoverEphemeral(oldPath, node)
+		if err == nil && !recovered {
+			continue
+		}
+		if err == nil && !z.retrackEphemeral(oldPath, newPath) {
+			// the node is deleted by the user during the recovery
+			if conn := z.getConn(); conn != nil {
+				_ = conn.Delete(newPath, -1)
+			}
+			continue
+		}
+		z.ephemeralRecovery(oldPath, newPath, err)
+	}
+}
+
+// isTrackedEphemeral reports whether @zkPath is still recorded
+func (z *ZookeeperClient) isTrackedEphemeral(zkPath string) bool {
+	z.ephemeralLock.Lock()
+	defer z.ephemeralLock.Unlock()
+	_, ok := z.ephemeralNodes[zkPath]
+	return ok
+}
+
+// retrackEphemeral records the re-created node @newPath instead of @oldPath, it
+// returns false if @oldPath has been untracked.
+func (z *ZookeeperClient) retrackEphemeral(oldPath, newPath string) bool {
+	z.ephemeralLock.Lock()
+	defer z.ephemeralLock.Unlock()
+	node, ok := z.ephemeralNodes[oldPath]
+	if !ok {
+		return false
+	}
+	delete(z.ephemeralNodes, oldPath)
+	z.ephemeralNodes[newPath] = node
+	return true
+}
+
+// recoverEphemeral returns the path of the node and whether the node is re-created
+func (z *ZookeeperClient) recoverEphemeral(zkPath string, node ephemeralNode) (string, bool, error) {
+	conn := z.getConn()
+	if conn == nil {
+		return zkPath, false, ErrNilZkClientConn
+	}
+
+	exist, stat, err := conn.Exists(zkPath)
+	if err != nil {
+		return zkPath, false, perrors.WithMessagef(err, "zk.Exists(path:%s)", zkPath)
+	}
+	if exist {
+		if stat.EphemeralOwner == conn.SessionID() {
+			return zkPath, false, nil
+		}
+		// zookeeper has removed the nodes of the expired session before the new
+		// session is established, so the node belongs to another live session
+		return zkPath, false, perrors.WithMessagef(ErrEphemeralNodeConflict,
+			"zk.Exists(path:%s) owner:%#x", zkPath, stat.EphemeralOwner)
+	}
+
+	parent := path.Dir(zkPath)
+	if parent != SLASH {
+		if err = z.ensurePath(parent); err != nil {
+			return zkPath, false, err
+		}
+	}
+	if node.sequential {
+		newPath, err := conn.Create(parent+SLASH, node.data, zk.FlagEphemeral|zk.FlagSequence, zk.WorldACL(zk.PermAll))
+		if err != nil {
+			return zkPath, false, perrors.WithMessagef(err, "zk.Create(path:%s)", parent+SLASH)
+		}
+		return newPath, true, nil
+	}
+	if _, err = conn.Create(zkPath, node.data, zk.FlagEphemeral, zk.WorldACL(zk.PermAll)); err != nil {
+		return zkPath, false, perrors.WithMessagef(err, "zk.Create(path:%s)", zkPath)
+	}
+	return zkPath, true, nil
+}
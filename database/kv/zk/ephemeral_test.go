@@ -0,0 +1,165 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ephemeralRecovery struct {
+	oldPath string
+	newPath string
+	err     error
+}
+
+// expireSession sends the events of a session expiry and a new session to @events,
+// which makes DefaultHandler recover the ephemeral nodes
+func expireSession(events chan zk.Event) {
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateDisconnected}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateExpired}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateConnecting}
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+}
+
+func TestRecoverEphemeralNodes(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	recovered := make(chan ephemeralRecovery, 4)
+	WithEphemeralRecovery(func(oldPath string, newPath string, err error) {
+		recovered <- ephemeralRecovery{oldPath: oldPath, newPath: newPath, err: err}
+	})(z)
+
+	// feed the session events to DefaultHandler by hand
+	events := make(chan zk.Event, 8)
+	z.Lock()
+	z.Session = events
+	z.Unlock()
+	go z.zkEventHandler.HandleZkEvent(z)
+	defer close(events)
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	err = z.CreateTempWithValue("/test/provider/node1", []byte("data1"))
+	assert.NoError(t, err)
+	seqPath, err := z.RegisterTempSeq("/test/provider", []byte("data2"))
+	assert.NoError(t, err)
+	assert.Len(t, z.EphemeralNodes(), 2)
+
+	// nothing to do while the nodes are owned by the current session
+	expireSession(events)
+	select {
+	case r := <-recovered:
+		t.Fatalf("unexpected recovery %+v", r)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// remove the nodes behind the client as if the session has expired
+	assert.NoError(t, z.Conn.Delete("/test/provider/node1", -1))
+	assert.NoError(t, z.Conn.Delete(seqPath, -1))
+	expireSession(events)
+	paths := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		r := <-recovered
+		assert.NoError(t, r.err)
+		paths[r.oldPath] = r.newPath
+	}
+	assert.Equal(t, "/test/provider/node1", paths["/test/provider/node1"])
+	assert.NotEqual(t, seqPath, paths[seqPath])
+
+	data, _, err := z.GetContent("/test/provider/node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data1"), data)
+	data, _, err = z.GetContent(paths[seqPath])
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data2"), data)
+
+	assert.NoError(t, z.Delete(paths[seqPath]))
+	assert.Len(t, z.EphemeralNodes(), 1)
+}
+
+func TestRecoverEphemeralNodesConflict(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	recovered := make(chan ephemeralRecovery, 4)
+	WithEphemeralRecovery(func(oldPath string, newPath string, err error) {
+		recovered <- ephemeralRecovery{oldPath: oldPath, newPath: newPath, err: err}
+	})(z)
+
+	events := make(chan zk.Event, 8)
+	z.Lock()
+	z.Session = events
+	z.Unlock()
+	go z.zkEventHandler.HandleZkEvent(z)
+	defer close(events)
+	events <- zk.Event{Type: zk.EventSession, State: zk.StateHasSession}
+
+	assert.NoError(t, z.CreateTempWithValue("/test/provider/node1", []byte("data1")))
+
+	// another process registers the same path after the node is removed
+	other, _, err := ts.ConnectWithOptions(15 * time.Second)
+	assert.NoError(t, err)
+	defer other.Close()
+	assert.NoError(t, z.Conn.Delete("/test/provider/node1", -1))
+	_, err = other.Create("/test/provider/node1", []byte("other"), zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+	assert.NoError(t, err)
+
+	expireSession(events)
+	r := <-recovered
+	assert.Equal(t, "/test/provider/node1", r.oldPath)
+	assert.Equal(t, ErrEphemeralNodeConflict, perrors.Cause(r.err))
+
+	// the node of the other process is kept
+	data, stat, err := other.Get("/test/provider/node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("other"), data)
+	assert.Equal(t, other.SessionID(), stat.EphemeralOwner)
+}
+
+func TestRetrackEphemeral(t *testing.T) {
+	z := &ZookeeperClient{
+		ephemeralRecovery: func(string, string, error) {},
+		ephemeralNodes:    make(map[string]ephemeralNode),
+	}
+	z.trackEphemeral("/seq/n-0000000001", []byte("1"), true)
+
+	assert.True(t, z.isTrackedEphemeral("/seq/n-0000000001"))
+	assert.True(t, z.retrackEphemeral("/seq/n-0000000001", "/seq/n-0000000002"))
+	assert.False(t, z.isTrackedEphemeral("/seq/n-0000000001"))
+	assert.Equal(t, []string{"/seq/n-0000000002"}, z.EphemeralNodes())
+
+	// the node deleted during the recovery is not tracked again
+	z.untrackEphemeral("/seq/n-0000000002")
+	assert.False(t, z.retrackEphemeral("/seq/n-0000000002", "/seq/n-0000000003"))
+	assert.Empty(t, z.EphemeralNodes())
+}
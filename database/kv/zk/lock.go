@@ -0,0 +1,305 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	lockPrefix  = "lock-"
+	readPrefix  = "read-"
+	writePrefix = "write-"
+)
+
+var (
+	// ErrLockHeld is returned when locking a lock instance which already holds the lock
+	ErrLockHeld = perrors.New("zk lock is already held by this instance")
+	// ErrLockNotHeld is returned when unlocking a lock instance which does not hold the lock
+	ErrLockNotHeld = perrors.New("zk lock is not held by this instance")
+)
+
+// seqNode is a child node created with zk.FlagSequence
+type seqNode struct {
+	name string
+	seq  int
+}
+
+func parseSeqNode(name string) (seqNode, bool) {
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return seqNode{}, false
+	}
+	seq, err := strconv.Atoi(name[idx+1:])
+	if err != nil {
+		return seqNode{}, false
+	}
+	return seqNode{name: name, seq: seq}, true
+}
+
+// createSeqNode creates an ephemeral sequential node named @prefix under @dir,
+// and the @dir will be created if it is absent.
+func (z *ZookeeperClient) createSeqNode(dir string, prefix string, data []byte) (string, error) {
+	conn := z.getConn()
+	if conn == nil {
+		return "", ErrNilZkClientConn
+	}
+	if err := z.ensurePath(dir); err != nil {
+		return "", err
+	}
+	nodePath, err := conn.CreateProtectedEphemeralSequential(path.Join(dir, prefix), data, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return "", perrors.WithMessagef(err, "zk.CreateProtectedEphemeralSequential(path:%s)", path.Join(dir, prefix))
+	}
+	return nodePath, nil
+}
+
+// ensurePath creates @dir recursively, it is fine if @dir exists already.
+func (z *ZookeeperClient) ensurePath(dir string) error {
+	err := z.Create(dir)
+	if err != nil && perrors.Cause(err) != zk.ErrNodeExists {
+		return perrors.WithMessagef(err, "ensurePath(path:%s)", dir)
+	}
+	return nil
+}
+
+// sortedSeqChildren gets the sequential children of @dir ordered by their sequence number.
+// Children whose names do not carry a sequence number are ignored.
+func (z *ZookeeperClient) sortedSeqChildren(dir string) ([]seqNode, error) {
+	conn := z.getConn()
+	if conn == nil {
+		return nil, ErrNilZkClientConn
+	}
+	children, _, err := conn.Children(dir)
+	if err != nil {
+		return nil, perrors.WithMessagef(err, "zk.Children(path:%s)", dir)
+	}
+	nodes := make([]seqNode, 0, len(children))
+	for _, child := range children {
+		if node, ok := parseSeqNode(child); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	return nodes, nil
+}
+
+// waitDeleted blocks until @zkPath is deleted or @ctx is done. It returns
+// immediately if the node does not exist, or when any other event arrives
+// on the node, so the caller should check its condition again.
+func (z *ZookeeperClient) waitDeleted(ctx context.Context, zkPath string) error {
+	conn := z.getConn()
+	if conn == nil {
+		return ErrNilZkClientConn
+	}
+	exist, _, watcher, err := conn.ExistsW(zkPath)
+	if err != nil {
+		return perrors.WithMessagef(err, "zk.ExistsW(path:%s)", zkPath)
+	}
+	if !exist {
+		conn.RemoveWatcher(watcher)
+		return nil
+	}
+	select {
+	case event := <-watcher.EvtCh:
+		return event.Err
+	case <-ctx.Done():
+		conn.RemoveWatcher(watcher)
+		return ctx.Err()
+	}
+}
+
+// queueLock is the common part of Mutex and RWMutex. Every contender creates
+// an ephemeral sequential node under the lock path and waits until @blocker
+// reports that no node in front of it blocks it.
+type queueLock struct {
+	client   *ZookeeperClient
+	path     string
+	prefix   string
+	lockPath string
+	// blocker returns the node that @own must wait for, or "" if @own gets the lock
+	blocker func(nodes []seqNode, own seqNode) string
+}
+
+func (l *queueLock) lock(ctx context.Context) error {
+	if l.lockPath != "" {
+		return ErrLockHeld
+	}
+	lockPath, err := l.client.createSeqNode(l.path, l.prefix, []byte{})
+	if err != nil {
+		return err
+	}
+	own, _ := parseSeqNode(path.Base(lockPath))
+	for {
+		nodes, err := l.client.sortedSeqChildren(l.path)
+		if err != nil {
+			l.cleanup(lockPath)
+			return err
+		}
+		blocker := l.blocker(nodes, own)
+		if blocker == "" {
+			l.lockPath = lockPath
+			return nil
+		}
+		if err = l.client.waitDeleted(ctx, path.Join(l.path, blocker)); err != nil {
+			l.cleanup(lockPath)
+			return err
+		}
+	}
+}
+
+func (l *queueLock) unlock() error {
+	if l.lockPath == "" {
+		return ErrLockNotHeld
+	}
+	conn := l.client.getConn()
+	if conn == nil {
+		return ErrNilZkClientConn
+	}
+	if err := conn.Delete(l.lockPath, -1); err != nil && err != zk.ErrNoNode {
+		return perrors.WithMessagef(err, "zk.Delete(path:%s)", l.lockPath)
+	}
+	l.lockPath = ""
+	return nil
+}
+
+func (l *queueLock) cleanup(lockPath string) {
+	if conn := l.client.getConn(); conn != nil {
+		_ = conn.Delete(lockPath, -1)
+	}
+}
+
+// exclusiveBlocker waits for the node just in front of @own
+func exclusiveBlocker(nodes []seqNode, own seqNode) string {
+	var prev string
+	for _, node := range nodes {
+		if node.seq >= own.seq {
+			break
+		}
+		prev = node.name
+	}
+	return prev
+}
+
+// sharedBlocker waits for the last write node in front of @own
+func sharedBlocker(nodes []seqNode, own seqNode) string {
+	var prev string
+	for _, node := range nodes {
+		if node.seq >= own.seq {
+			break
+		}
+		if strings.Contains(node.name, writePrefix) {
+			prev = node.name
+		}
+	}
+	return prev
+}
+
+// Mutex is an inter-process mutual exclusion lock based on zookeeper
+// ephemeral sequential nodes. The lock will be released automatically
+// if the session of the holder expires.
+// A Mutex instance should not be shared by goroutines, every goroutine
+// should create its own instance instead.
+type Mutex struct {
+	queue queueLock
+}
+
+// NewMutex returns a Mutex on @lockPath. The @lockPath should be used only by locks.
+func NewMutex(client *ZookeeperClient, lockPath string) *Mutex {
+	return &Mutex{
+		queue: queueLock{
+			client:  client,
+			path:    lockPath,
+			prefix:  lockPrefix,
+			blocker: exclusiveBlocker,
+		},
+	}
+}
+
+// Lock blocks until the lock is acquired or @ctx is done
+func (m *Mutex) Lock(ctx context.Context) error {
+	return m.queue.lock(ctx)
+}
+
+// Unlock releases the lock
+func (m *Mutex) Unlock() error {
+	return m.queue.unlock()
+}
+
+// IsLocked returns true if this instance holds the lock
+func (m *Mutex) IsLocked() bool {
+	return m.queue.lockPath != ""
+}
+
+// RWMutex is an inter-process reader/writer lock. Readers share the lock
+// unless there is a writer queued in front of them.
+// Like Mutex, a RWMutex instance should not be shared by goroutines.
+type RWMutex struct {
+	read  queueLock
+	write queueLock
+}
+
+// NewRWMutex returns a RWMutex on @lockPath. The @lockPath should be used only by locks.
+func NewRWMutex(client *ZookeeperClient, lockPath string) *RWMutex {
+	return &RWMutex{
+		read: queueLock{
+			client:  client,
+			path:    lockPath,
+			prefix:  readPrefix,
+			blocker: sharedBlocker,
+		},
+		write: queueLock{
+			client:  client,
+			path:    lockPath,
+			prefix:  writePrefix,
+			blocker: exclusiveBlocker,
+		},
+	}
+}
+
+// RLock blocks until the read lock is acquired or @ctx is done
+func (rw *RWMutex) RLock(ctx context.Context) error {
+	return rw.read.lock(ctx)
+}
+
+// RUnlock releases the read lock
+func (rw *RWMutex) RUnlock() error {
+	return rw.read.unlock()
+}
+
+// Lock blocks until the write lock is acquired or @ctx is done
+func (rw *RWMutex) Lock(ctx context.Context) error {
+	return rw.write.lock(ctx)
+}
+
+// Unlock releases the write lock
+func (rw *RWMutex) Unlock() error {
+	return rw.write.unlock()
+}
@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeqNode(t *testing.T) {
+	node, ok := parseSeqNode("_c_0123456789abcdef-lock-0000000012")
+	assert.True(t, ok)
+	assert.Equal(t, 12, node.seq)
+
+	_, ok = parseSeqNode("ready")
+	assert.False(t, ok)
+	_, ok = parseSeqNode("lock-abc")
+	assert.False(t, ok)
+}
+
+func TestBlocker(t *testing.T) {
+	nodes := []seqNode{
+		{name: "read-0000000001", seq: 1},
+		{name: "write-0000000002", seq: 2},
+		{name: "read-0000000003", seq: 3},
+		{name: "read-0000000004", seq: 4},
+		{name: "write-0000000005", seq: 5},
+	}
+	assert.Equal(t, "", exclusiveBlocker(nodes, nodes[0]))
+	assert.Equal(t, "read-0000000003", exclusiveBlocker(nodes, nodes[3]))
+	assert.Equal(t, "read-0000000004", exclusiveBlocker(nodes, nodes[4]))
+
+	assert.Equal(t, "", sharedBlocker(nodes, nodes[0]))
+	assert.Equal(t, "write-0000000002", sharedBlocker(nodes, nodes[2]))
+	assert.Equal(t, "write-0000000002", sharedBlocker(nodes, nodes[3]))
+}
+
+func TestMutex(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	m1 := NewMutex(z, "/test/lock")
+	m2 := NewMutex(z, "/test/lock")
+	assert.Equal(t, ErrLockNotHeld, m1.Unlock())
+	assert.NoError(t, m1.Lock(context.Background()))
+	assert.True(t, m1.IsLocked())
+	assert.Equal(t, ErrLockHeld, m1.Lock(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, m2.Lock(ctx))
+	assert.False(t, m2.IsLocked())
+
+	locked := make(chan struct{})
+	go func() {
+		assert.NoError(t, m2.Lock(context.Background()))
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("m2 should not get the lock before m1 unlocks")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.NoError(t, m1.Unlock())
+	<-locked
+	assert.NoError(t, m2.Unlock())
+}
+
+func TestRWMutex(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	rw1 := NewRWMutex(z, "/test/rwlock")
+	rw2 := NewRWMutex(z, "/test/rwlock")
+	assert.NoError(t, rw1.RLock(context.Background()))
+	assert.NoError(t, rw2.RLock(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rw3 := NewRWMutex(z, "/test/rwlock")
+	assert.Equal(t, context.DeadlineExceeded, rw3.Lock(ctx))
+
+	assert.NoError(t, rw1.RUnlock())
+	assert.NoError(t, rw2.RUnlock())
+	assert.NoError(t, rw3.Lock(context.Background()))
+	assert.NoError(t, rw3.Unlock())
+}
@@ -57,3 +57,11 @@ func WithZkTimeOut(t time.Duration) zkClientOption {
 		opt.Timeout = t
 	}
 }
+
+// WithEphemeralRecovery makes zk Client record the ephemeral nodes it creates and
+// re-create them after the session expires, @callback reports the result of each node
+func WithEphemeralRecovery(callback EphemeralRecoveryCallback) zkClientOption {
+	return func(opt *ZookeeperClient) {
+		opt.ephemeralRecovery = callback
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/gost/database/kv"
+)
+
+const (
+	storeWatchBufferSize = 64
+	// storeWatchRetryInterval is the wait time before walking the tree again after an error
+	storeWatchRetryInterval = time.Second
+)
+
+// Store adapts ZookeeperClient to kv.Store, the keys are zookeeper paths
+type Store struct {
+	client    *ZookeeperClient
+	closeOnce sync.Once
+	done      chan struct{} // closed by Close to stop the watches
+}
+
+var _ kv.Store = (*Store)(nil)
+
+// NewStore returns a kv.Store backed by @client, the client will be closed
+// when the store is closed.
+func NewStore(client *ZookeeperClient) *Store {
+	return &Store{client: client, done: make(chan struct{})}
+}
+
+// Client returns the zookeeper client of the store
+func (s *Store) Client() *ZookeeperClient {
+	return s.client
+}
+
+// Get gets the value of @key
+func (s *Store) Get(key string) (string, error) {
+	conn := s.client.getConn()
+	if conn == nil {
+		return "", ErrNilZkClientConn
+	}
+	data, _, err := conn.Get(key)
+	if err == zk.ErrNoNode {
+		return "", kv.ErrKeyNotFound
+	}
+	if err != nil {
+		return "", perrors.WithMessagef(err, "zk.Get(path:%s)", key)
+	}
+	return string(data), nil
+}
+
+// Put creates @key with its parents or updates its value
+func (s *Store) Put(key string, value string) error {
+	conn := s.client.getConn()
+	if conn == nil {
+		return ErrNilZkClientConn
+	}
+	_, err := conn.Set(key, []byte(value), -1)
+	if err != zk.ErrNoNode {
+		return perrors.WithMessagef(err, "zk.Set(path:%s)", key)
+	}
+	err = s.client.CreateWithValue(key, []byte(value))
+	if perrors.Cause(err) == zk.ErrNodeExists {
+		// created by others just now
+		_, err = conn.Set(key, []byte(value), -1)
+	}
+	return perrors.WithMessagef(err, "zk.Create(path:%s)", key)
+}
+
+// CreateEphemeral creates the ephemeral node @key with its parents
+func (s *Store) CreateEphemeral(key string, value string) error {
+	return s.client.CreateTempWithValue(key, []byte(value))
+}
+
+// Delete deletes @key
+func (s *Store) Delete(key string) error {
+	err := s.client.Delete(key)
+	if perrors.Cause(err) == zk.ErrNoNode {
+		return nil
+	}
+	return err
+}
+
+// Children returns the names of the children of @key
+func (s *Store) Children(key string) ([]string, error) {
+	children, err := s.client.GetChildren(key)
+	if perrors.Cause(err) == zk.ErrNoNode {
+		return []string{}, nil
+	}
+	return children, err
+}
+
+// WatchPrefix sends the existing nodes in the subtree of @prefix and their
+// changes, and @prefix itself is not included. As zookeeper watches are one-shot, the
+// subtree is walked again after any change, so it is not suitable for a
+// large subtree.
+func (s *Store) WatchPrefix(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	if s.client.getConn() == nil {
+		return nil, ErrNilZkClientConn
+	}
+	ch := make(chan kv.Event, storeWatchBufferSize)
+	go s.watchTree(ctx, path.Clean(prefix), ch)
+	return ch, nil
+}
+
+func (s *Store) watchTree(ctx context.Context, root string, ch chan<- kv.Event) {
+	defer close(ch)
+
+	snapshot := make(map[string]string)
+	for {
+		kvs, watchers, err := s.walk(root)
+		if err == nil {
+			for _, e := range kv.Diff(snapshot, kvs) {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					s.removeWatchers(watchers)
+					return
+				case <-s.done:
+					s.removeWatchers(watchers)
+					return
+				}
+			}
+			snapshot = kvs
+			err = waitAny(ctx, s.done, watchers)
+			s.removeWatchers(watchers)
+		}
+		if ctx.Err() != nil || s.closed() {
+			return
+		}
+		if perrors.Cause(err) == ErrNilZkClientConn {
+			// the client is closed
+			return
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case <-s.client.Reconnect():
+			case <-time.After(storeWatchRetryInterval):
+			}
+		}
+	}
+}
+
+// walk reads the data of all the nodes under @root and watches their data and children
+func (s *Store) walk(root string) (map[string]string, []*zk.Watcher, error) {
+	conn := s.client.getConn()
+	if conn == nil {
+		return nil, nil, ErrNilZkClientConn
+	}
+
+	var watchers []*zk.Watcher
+	kvs := make(map[string]string)
+	var visit func(node string) error
+	visit = func(node string) error {
+		if node != root {
+			data, _, w, err := conn.GetW(node)
+			if err == zk.ErrNoNode {
+				return nil
+			}
+			if err != nil {
+				return perrors.WithMessagef(err, "zk.GetW(path:%s)", node)
+			}
+			watchers = append(watchers, w)
+			kvs[node] = string(data)
+		}
+		children, _, w, err := conn.ChildrenW(node)
+		if err == zk.ErrNoNode {
+			return nil
+		}
+		if err != nil {
+			return perrors.WithMessagef(err, "zk.ChildrenW(path:%s)", node)
+		}
+		watchers = append(watchers, w)
+		for _, child := range children {
+			if err = visit(path.Join(node, child)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// watch the creation of the root if it is absent
+	exist, _, w, err := conn.ExistsW(root)
+	if err != nil {
+		return nil, nil, perrors.WithMessagef(err, "zk.ExistsW(path:%s)", root)
+	}
+	if !exist {
+		return kvs, []*zk.Watcher{w}, nil
+	}
+	conn.RemoveWatcher(w)
+	if err = visit(root); err != nil {
+		s.removeWatchers(watchers)
+		return nil, nil, err
+	}
+	return kvs, watchers, nil
+}
+
+func (s *Store) removeWatchers(watchers []*zk.Watcher) {
+	conn := s.client.getConn()
+	if conn == nil {
+		return
+	}
+	for _, w := range watchers {
+		conn.RemoveWatcher(w)
+	}
+}
+
+// closed reports whether the store is closed
+func (s *Store) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitAny blocks until any of @watchers fires, @ctx is done or @done is closed
+func waitAny(ctx context.Context, done <-chan struct{}, watchers []*zk.Watcher) error {
+	fired := make(chan zk.Event, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	for _, w := range watchers {
+		go func(w *zk.Watcher) {
+			select {
+			case event := <-w.EvtCh:
+				select {
+				case fired <- event:
+				default:
+				}
+			case <-stop:
+			}
+		}(w)
+	}
+
+	select {
+	case event := <-fired:
+		return event.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return kv.ErrStoreClosed
+	}
+}
+
+// Close stops the watches and closes the client
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.client.Close()
+	})
+}
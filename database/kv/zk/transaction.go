@@ -0,0 +1,220 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	// MaxCASRetries is the max retry times of CompareAndSwap on version conflicts
+	MaxCASRetries = 64
+)
+
+var (
+	// ErrEmptyTransaction is returned when committing a transaction without any operation
+	ErrEmptyTransaction = perrors.New("zk transaction has no operation")
+	// ErrCASRetryExhausted is returned when CompareAndSwap conflicts more than MaxCASRetries times
+	ErrCASRetryExhausted = perrors.New("zk compare and swap retries exhausted")
+)
+
+// Transaction collects create, set, delete and check-version operations and
+// commits them as one zookeeper multi request, so all of them succeed or none
+// of them takes effect.
+type Transaction struct {
+	client *ZookeeperClient
+	ops    []interface{}
+	err    error
+}
+
+// NewTransaction returns an empty transaction on @z
+func (z *ZookeeperClient) NewTransaction() *Transaction {
+	return &Transaction{client: z}
+}
+
+// Create adds an operation creating @zkPath with @value and @flags, eg: zk.FlagEphemeral
+func (t *Transaction) Create(zkPath string, value []byte, flags int32) *Transaction {
+	t.ops = append(t.ops, &zk.CreateRequest{
+		Path:  zkPath,
+		Data:  value,
+		Acl:   zk.WorldACL(zk.PermAll),
+		Flags: flags,
+	})
+	return t
+}
+
+// CreateWithParents adds operations creating the absent ancestors of @zkPath and
+// @zkPath itself. The ancestors are checked when this method is called.
+func (t *Transaction) CreateWithParents(zkPath string, value []byte, flags int32) *Transaction {
+	conn := t.client.getConn()
+	if conn == nil {
+		t.err = ErrNilZkClientConn
+		return t
+	}
+	if !strings.HasPrefix(zkPath, SLASH) {
+		zkPath = SLASH + zkPath
+	}
+	paths := strings.Split(zkPath, SLASH)
+	for idx := 2; idx < len(paths); idx++ {
+		tmpPath := strings.Join(paths[:idx], SLASH)
+		exist, _, err := conn.Exists(tmpPath)
+		if err != nil {
+			t.err = perrors.WithMessagef(err, "zk.Exists(path:%s)", tmpPath)
+			return t
+		}
+		if !exist {
+			t.Create(tmpPath, []byte{}, 0)
+		}
+	}
+	return t.Create(zkPath, value, flags)
+}
+
+// Set adds an operation setting @value of @zkPath if its version is @version,
+// -1 matches any version
+func (t *Transaction) Set(zkPath string, value []byte, version int32) *Transaction {
+	t.ops = append(t.ops, &zk.SetDataRequest{
+		Path:    zkPath,
+		Data:    value,
+		Version: version,
+	})
+	return t
+}
+
+// Delete adds an operation deleting @zkPath if its version is @version,
+// -1 matches any version
+func (t *Transaction) Delete(zkPath string, version int32) *Transaction {
+	t.ops = append(t.ops, &zk.DeleteRequest{
+		Path:    zkPath,
+		Version: version,
+	})
+	return t
+}
+
+// Check adds an operation which fails the transaction if the version of @zkPath is not @version
+func (t *Transaction) Check(zkPath string, version int32) *Transaction {
+	t.ops = append(t.ops, &zk.CheckVersionRequest{
+		Path:    zkPath,
+		Version: version,
+	})
+	return t
+}
+
+// Len returns the number of operations in the transaction
+func (t *Transaction) Len() int {
+	return len(t.ops)
+}
+
+// Commit executes the operations atomically. The responses are in the same order as
+// the operations, and the returned error tells the first failed operation.
+func (t *Transaction) Commit() ([]zk.MultiResponse, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if len(t.ops) == 0 {
+		return nil, ErrEmptyTransaction
+	}
+	conn := t.client.getConn()
+	if conn == nil {
+		return nil, ErrNilZkClientConn
+	}
+
+	responses, err := conn.Multi(t.ops...)
+	if err != nil {
+		for idx, rsp := range responses {
+			if rsp.Error != nil && idx < len(t.ops) {
+				return responses, perrors.WithMessagef(rsp.Error, "zk.Multi(op:%d, %s)", idx, opString(t.ops[idx]))
+			}
+		}
+		return responses, perrors.WithMessagef(err, "zk.Multi(ops:%d)", len(t.ops))
+	}
+	return responses, nil
+}
+
+func opString(op interface{}) string {
+	switch req := op.(type) {
+	case *zk.CreateRequest:
+		return "create " + req.Path
+	case *zk.SetDataRequest:
+		return "set " + req.Path
+	case *zk.DeleteRequest:
+		return "delete " + req.Path
+	case *zk.CheckVersionRequest:
+		return "check " + req.Path
+	default:
+		return "unknown"
+	}
+}
+
+// CompareAndSwap updates the value of @zkPath with the result of @update. The @update
+// receives the current value, which is nil if the node is absent, and the node is
+// written only if it has not been changed by others in the meantime. Otherwise,
+// the @update will be called again with the latest value.
+// The error returned by @update is returned as is and no change is made.
+func (z *ZookeeperClient) CompareAndSwap(zkPath string, update func(old []byte) ([]byte, error)) (*zk.Stat, error) {
+	for i := 0; i < MaxCASRetries; i++ {
+		conn := z.getConn()
+		if conn == nil {
+			return nil, ErrNilZkClientConn
+		}
+
+		old, stat, err := conn.Get(zkPath)
+		if err != nil && err != zk.ErrNoNode {
+			return nil, perrors.WithMessagef(err, "zk.Get(path:%s)", zkPath)
+		}
+		absent := err == zk.ErrNoNode
+		if absent {
+			old = nil
+		}
+
+		value, err := update(old)
+		if err != nil {
+			return nil, err
+		}
+
+		if absent {
+			err = z.CreateWithValue(zkPath, value)
+			if perrors.Cause(err) == zk.ErrNodeExists {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			_, stat, err = conn.Exists(zkPath)
+			if err != nil {
+				return nil, perrors.WithMessagef(err, "zk.Exists(path:%s)", zkPath)
+			}
+			return stat, nil
+		}
+
+		stat, err = conn.Set(zkPath, value, stat.Version)
+		if err == zk.ErrBadVersion || err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, perrors.WithMessagef(err, "zk.Set(path:%s)", zkPath)
+		}
+		return stat, nil
+	}
+	return nil, perrors.WithMessagef(ErrCASRetryExhausted, "CompareAndSwap(path:%s)", zkPath)
+}
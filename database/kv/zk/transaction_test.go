@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxzookeeper
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	_, err = z.NewTransaction().Commit()
+	assert.Equal(t, ErrEmptyTransaction, err)
+
+	txn := z.NewTransaction().
+		CreateWithParents("/test/txn/node1", []byte("1"), 0).
+		Create("/test/txn/node2", []byte("2"), 0)
+	assert.Equal(t, 4, txn.Len())
+	_, err = txn.Commit()
+	assert.NoError(t, err)
+
+	// the failed check-version rolls back the whole transaction
+	_, err = z.NewTransaction().
+		Set("/test/txn/node1", []byte("11"), 0).
+		Delete("/test/txn/node2", -1).
+		Check("/test/txn/node1", 100).
+		Commit()
+	assert.Error(t, err)
+	data, _, err := z.GetContent("/test/txn/node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), data)
+
+	_, err = z.NewTransaction().
+		Set("/test/txn/node1", []byte("11"), 0).
+		Delete("/test/txn/node2", -1).
+		Commit()
+	assert.NoError(t, err)
+	data, _, err = z.GetContent("/test/txn/node1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("11"), data)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ts, z, _, err := NewMockZookeeperClient("test", 15*time.Second)
+	assert.NoError(t, err)
+	defer func() {
+		_ = ts.Stop()
+	}()
+
+	incr := func(old []byte) ([]byte, error) {
+		n := 0
+		if old != nil {
+			n, _ = strconv.Atoi(string(old))
+		}
+		return []byte(strconv.Itoa(n + 1)), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := z.CompareAndSwap("/test/cas/counter", incr)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	data, _, err := z.GetContent("/test/cas/counter")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("10"), data)
+
+	errAbort := perrors.New("abort")
+	_, err = z.CompareAndSwap("/test/cas/counter", func(old []byte) ([]byte, error) {
+		return nil, errAbort
+	})
+	assert.Equal(t, errAbort, err)
+
+	stat, err := z.CompareAndSwap("/test/cas/counter", func(old []byte) ([]byte, error) {
+		return []byte("0"), nil
+	})
+	assert.NoError(t, err)
+	_, err = z.SetContent("/test/cas/counter", []byte("1"), stat.Version-1)
+	assert.Equal(t, zk.ErrBadVersion, err)
+}
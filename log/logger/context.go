@@ -0,0 +1,94 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+const (
+	// TraceIDKey is the field of the trace id added by FromContext
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the field of the span id added by FromContext
+	SpanIDKey = "span_id"
+)
+
+type (
+	loggerCtxKey struct{}
+	traceCtxKey  struct{}
+)
+
+type traceContext struct {
+	traceID string
+	spanID  string
+}
+
+// TraceExtractor extracts the trace id and span id from @ctx, eg: by the
+// span context of opentelemetry. It returns empty strings if there is no trace.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+var traceExtractor atomic.Value // TraceExtractor
+
+// SetTraceExtractor sets the TraceExtractor used by FromContext if the trace
+// is not set by ContextWithTrace
+func SetTraceExtractor(extractor TraceExtractor) {
+	traceExtractor.Store(extractor)
+}
+
+// ContextWithTrace returns a copy of @ctx carrying the trace id and span id
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, traceContext{traceID: traceID, spanID: spanID})
+}
+
+// ContextWithLogger returns a copy of @ctx carrying @l, which is returned by FromContext
+func ContextWithLogger(ctx context.Context, l StructuredLogger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// FromContext returns the logger carried by @ctx or the global logger, with
+// the trace id and span id of @ctx as fields
+func FromContext(ctx context.Context) StructuredLogger {
+	l, ok := ctx.Value(loggerCtxKey{}).(StructuredLogger)
+	if !ok {
+		l = Structured(logger)
+	}
+
+	traceID, spanID := traceFromContext(ctx)
+	var fields []interface{}
+	if traceID != "" {
+		fields = append(fields, TraceIDKey, traceID)
+	}
+	if spanID != "" {
+		fields = append(fields, SpanIDKey, spanID)
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+func traceFromContext(ctx context.Context) (traceID, spanID string) {
+	if tc, ok := ctx.Value(traceCtxKey{}).(traceContext); ok {
+		return tc.traceID, tc.spanID
+	}
+	if extractor, ok := traceExtractor.Load().(TraceExtractor); ok && extractor != nil {
+		return extractor(ctx)
+	}
+	return "", ""
+}
@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+func TestFromContext(t *testing.T) {
+	defer InitLogger(nil)
+	defer SetTraceExtractor(nil)
+
+	dl, logs := newObservedLogger()
+	SetLogger(dl)
+
+	// no trace
+	FromContext(context.Background()).InfoKV("no trace")
+	assert.Equal(t, 0, len(logs.AllUntimed()[0].Context))
+
+	ctx := ContextWithTrace(context.Background(), "t1", "s1")
+	FromContext(ctx).InfoKV("trace")
+	assert.Equal(t, map[string]interface{}{TraceIDKey: "t1", SpanIDKey: "s1"}, logs.AllUntimed()[1].ContextMap())
+
+	// the logger carried by the context
+	ctx = ContextWithLogger(ctx, dl.Named("remoting"))
+	FromContext(ctx).InfoKV("named")
+	assert.Equal(t, "remoting", logs.AllUntimed()[2].LoggerName)
+	assert.Equal(t, "t1", logs.AllUntimed()[2].ContextMap()[TraceIDKey])
+
+	// the trace extracted from the context
+	SetTraceExtractor(func(ctx context.Context) (string, string) {
+		span, _ := ctx.Value(spanKey{}).(string)
+		return "t2", span
+	})
+	FromContext(context.WithValue(context.Background(), spanKey{}, "s2")).InfoKV("extracted")
+	assert.Equal(t, map[string]interface{}{TraceIDKey: "t2", SpanIDKey: "s2"}, logs.AllUntimed()[3].ContextMap())
+}
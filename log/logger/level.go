@@ -0,0 +1,211 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// noLevel is greater than all the levels, it means there is no override
+const noLevel = int32(zapcore.FatalLevel + 1)
+
+// levelRegistry is the level overrides of the named loggers
+type levelRegistry struct {
+	lock   sync.RWMutex
+	levels map[string]zapcore.Level
+	min    int32 // the lowest level of the overrides, or noLevel
+}
+
+var namedLevels = &levelRegistry{levels: make(map[string]zapcore.Level), min: noLevel}
+
+func (r *levelRegistry) set(name string, level zapcore.Level) {
+	r.lock.Lock()
+	r.levels[name] = level
+	r.refresh()
+	r.lock.Unlock()
+}
+
+func (r *levelRegistry) unset(name string) {
+	r.lock.Lock()
+	delete(r.levels, name)
+	r.refresh()
+	r.lock.Unlock()
+}
+
+// refresh should be called with the lock held
+func (r *levelRegistry) refresh() {
+	min := noLevel
+	for _, lv := range r.levels {
+		if int32(lv) < min {
+			min = int32(lv)
+		}
+	}
+	atomic.StoreInt32(&r.min, min)
+}
+
+// lookup returns the level of @name or its nearest parent, like "a.b" for "a.b.c"
+func (r *levelRegistry) lookup(name string) (zapcore.Level, bool) {
+	if atomic.LoadInt32(&r.min) == noLevel || name == "" {
+		return 0, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for {
+		if lv, ok := r.levels[name]; ok {
+			return lv, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+func (r *levelRegistry) list() map[string]string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	levels := make(map[string]string, len(r.levels))
+	for name, lv := range r.levels {
+		levels[name] = lv.String()
+	}
+	return levels
+}
+
+// SetNamedLoggerLevel sets the level of the logger named @name and its
+// sub-loggers without their own level, eg: "registry" covers "registry.zk".
+// It only works for the zap logger initialized by InitLogger.
+func SetNamedLoggerLevel(name, level string) bool {
+	if name == "" {
+		return SetLoggerLevel(level)
+	}
+	lv, err := zapcore.ParseLevel(level)
+	if err != nil || level == "" {
+		return false
+	}
+	namedLevels.set(name, lv)
+	return true
+}
+
+// ResetNamedLoggerLevel removes the level of the logger named @name, so it
+// follows its parent again
+func ResetNamedLoggerLevel(name string) {
+	namedLevels.unset(name)
+}
+
+// NamedLoggerLevels returns the levels set by SetNamedLoggerLevel
+func NamedLoggerLevels() map[string]string {
+	return namedLevels.list()
+}
+
+// GetNamedLoggerLevel returns the effective level of the logger named @name
+func GetNamedLoggerLevel(name string) string {
+	if lv, ok := namedLevels.lookup(name); ok {
+		return lv.String()
+	}
+	if dl, ok := logger.(*DubboLogger); ok && dl.DynamicLevel != (zap.AtomicLevel{}) {
+		return dl.DynamicLevel.String()
+	}
+	if l, ok := logger.(interface{ Level() string }); ok {
+		return l.Level()
+	}
+	return ""
+}
+
+// namedLevelCore filters the entries by the level of their logger names,
+// the wrapped core should enable all the levels
+type namedLevelCore struct {
+	zapcore.Core
+	root zap.AtomicLevel
+}
+
+func (c *namedLevelCore) Enabled(lv zapcore.Level) bool {
+	return c.root.Enabled(lv) || int32(lv) >= atomic.LoadInt32(&namedLevels.min)
+}
+
+func (c *namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelCore{Core: c.Core.With(fields), root: c.root}
+}
+
+func (c *namedLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if lv, ok := namedLevels.lookup(ent.LoggerName); ok {
+		if ent.Level < lv {
+			return ce
+		}
+	} else if !c.root.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+type levelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+type levelResponse struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers"`
+}
+
+type levelError struct {
+	Error string `json:"error"`
+}
+
+// LevelHandler returns the http handler to list and set the levels. GET
+// returns the level of the logger and the named loggers like
+// {"level":"info","loggers":{"registry.zk":"debug"}}, and PUT sets a level
+// by the body like {"name":"registry.zk","level":"debug"}. The empty name
+// sets the level of the logger, and the empty level resets the named logger.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = enc.Encode(levelError{Error: "invalid request body: " + err.Error()})
+				return
+			}
+			if req.Level == "" && req.Name != "" {
+				ResetNamedLoggerLevel(req.Name)
+			} else if !SetNamedLoggerLevel(req.Name, req.Level) {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = enc.Encode(levelError{Error: "can not set level " + req.Level})
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = enc.Encode(levelError{Error: "only GET, PUT and POST are supported"})
+			return
+		}
+		_ = enc.Encode(levelResponse{Level: GetNamedLoggerLevel(""), Loggers: NamedLoggerLevels()})
+	})
+}
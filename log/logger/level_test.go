@@ -0,0 +1,137 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/zap"
+)
+
+func resetNamedLoggerLevels() {
+	for name := range NamedLoggerLevels() {
+		ResetNamedLoggerLevel(name)
+	}
+}
+
+func initFileLogger(t *testing.T) func() string {
+	file := filepath.Join(t.TempDir(), "test.log")
+	InitLogger(&Config{
+		ZapConfig: &zap.Config{
+			Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+			Encoding:      "json",
+			EncoderConfig: zap.NewProductionEncoderConfig(),
+			OutputPaths:   []string{file},
+		},
+	})
+	return func() string {
+		content, err := os.ReadFile(file)
+		assert.Nil(t, err)
+		return string(content)
+	}
+}
+
+func TestNamedLoggerLevel(t *testing.T) {
+	defer InitLogger(nil)
+	defer resetNamedLoggerLevels()
+	read := initFileLogger(t)
+
+	zk := Named("registry").Named("zk")
+	nacos := Named("registry").Named("nacos")
+	getty := Named("remoting").Named("getty")
+
+	zk.Debug("zk debug 1")
+	assert.NotContains(t, read(), "zk debug 1")
+
+	// the parent level covers the children
+	assert.True(t, SetNamedLoggerLevel("registry", "debug"))
+	zk.Debug("zk debug 2")
+	nacos.Debug("nacos debug 2")
+	getty.Debug("getty debug 2")
+	content := read()
+	assert.Contains(t, content, "zk debug 2")
+	assert.Contains(t, content, "nacos debug 2")
+	assert.NotContains(t, content, "getty debug 2")
+	assert.Equal(t, "debug", GetNamedLoggerLevel("registry.nacos"))
+	assert.Equal(t, "info", GetNamedLoggerLevel("remoting.getty"))
+
+	// the child level overrides the parent, and it is independent of the logger level
+	assert.True(t, SetNamedLoggerLevel("registry.zk", "error"))
+	assert.True(t, SetLoggerLevel("warn"))
+	zk.Warn("zk warn 3")
+	nacos.Debug("nacos debug 3")
+	getty.Info("getty info 3")
+	Info("root info 3")
+	content = read()
+	assert.NotContains(t, content, "zk warn 3")
+	assert.Contains(t, content, "nacos debug 3")
+	assert.NotContains(t, content, "getty info 3")
+	assert.NotContains(t, content, "root info 3")
+	assert.Equal(t, map[string]string{"registry": "debug", "registry.zk": "error"}, NamedLoggerLevels())
+
+	assert.False(t, SetNamedLoggerLevel("registry", "bad"))
+	assert.False(t, SetNamedLoggerLevel("registry", ""))
+	ResetNamedLoggerLevel("registry.zk")
+	zk.Debug("zk debug 4")
+	assert.Contains(t, read(), "zk debug 4")
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer InitLogger(nil)
+	defer resetNamedLoggerLevels()
+	_ = initFileLogger(t)
+
+	handler := LevelHandler()
+	serve := func(method, body string) (int, string) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	code, body := serve(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"level":"info","loggers":{}}`, body)
+
+	code, body = serve(http.MethodPut, `{"name":"cluster","level":"debug"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"level":"info","loggers":{"cluster":"debug"}}`, body)
+
+	code, body = serve(http.MethodPut, `{"level":"error"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"level":"error","loggers":{"cluster":"debug"}}`, body)
+
+	code, body = serve(http.MethodPost, `{"name":"cluster"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"level":"error","loggers":{}}`, body)
+
+	code, _ = serve(http.MethodPut, `{"name":"cluster","level":"bad"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(http.MethodPut, `{`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(http.MethodDelete, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -25,7 +25,12 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-var logger Logger
+var (
+	logger Logger
+	// callerLogger is logger with one more caller skip for the package-level
+	// functions of StructuredLogger, which call the methods of it
+	callerLogger StructuredLogger
+)
 
 func init() {
 	InitLogger(nil)
@@ -35,12 +40,23 @@ func init() {
 type DubboLogger struct {
 	Logger
 	DynamicLevel zap.AtomicLevel
+
+	name string // the name of the sub-logger
 }
 
 type Config struct {
 	LumberjackConfig *lumberjack.Logger `yaml:"lumberjack-config"`
 	ZapConfig        *zap.Config        `yaml:"zap-config"`
 	CallerSkip       int
+	// Async writes the entries in a background goroutine if it is not nil
+	Async *AsyncConfig `yaml:"async"`
+	// Sampling samples the entries by the level and message if it is not nil
+	Sampling *SamplingConfig `yaml:"sampling"`
+	// RateLimit limits the entries per second if it is not nil
+	RateLimit *RateLimitConfig `yaml:"rate-limit"`
+	// Sinks are the outputs with their own levels and encoders, the output
+	// paths of ZapConfig and LumberjackConfig are ignored if it is not empty
+	Sinks []SinkConfig `yaml:"sinks"`
 }
 
 // Logger is the interface for Logger types
@@ -91,25 +107,58 @@ func InitLogger(conf *Config) {
 
 	if conf != nil {
 		config.CallerSkip = conf.CallerSkip
+		config.Async = conf.Async
+		config.Sampling = conf.Sampling
+		config.RateLimit = conf.RateLimit
+		config.Sinks = conf.Sinks
+	}
+
+	// the default level should not filter the entries of the sinks
+	if len(config.Sinks) > 0 &&
+		(conf == nil || conf.ZapConfig == nil || conf.ZapConfig.Level == (zap.AtomicLevel{})) {
+		zapConfig := *config.ZapConfig
+		zapConfig.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+		if lv, ok := minSinkLevel(config.Sinks); ok {
+			zapConfig.Level = zap.NewAtomicLevelAt(lv)
+		}
+		config.ZapConfig = &zapConfig
 	}
 
 	if config.CallerSkip == 0 {
 		config.CallerSkip = 1
 	}
 
-	if conf == nil || conf.LumberjackConfig == nil {
-		zapLogger, _ = config.ZapConfig.Build(zap.AddCaller(), zap.AddCallerSkip(config.CallerSkip))
-	} else {
+	// the cores enable all the levels, and the entries are filtered by the
+	// level of the logger or the named loggers in namedLevelCore
+	root := config.ZapConfig.Level
+	zapConfig := *config.ZapConfig
+	zapConfig.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	config.ZapConfig = &zapConfig
+
+	closer := func() {}
+	wrapCore := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core, closer = config.wrapCore(core)
+		return &namedLevelCore{Core: core, root: root}
+	})
+	switch {
+	case len(config.Sinks) > 0:
+		zapLogger = initZapLoggerWithSinks(config, wrapCore)
+	case conf == nil || conf.LumberjackConfig == nil:
+		zapLogger, _ = config.ZapConfig.Build(zap.AddCaller(), zap.AddCallerSkip(config.CallerSkip), wrapCore)
+	default:
 		config.LumberjackConfig = conf.LumberjackConfig
-		zapLogger = initZapLoggerWithSyncer(config)
+		zapLogger = initZapLoggerWithSyncer(config, wrapCore)
 	}
 
-	logger = &DubboLogger{Logger: zapLogger.Sugar(), DynamicLevel: config.ZapConfig.Level}
+	SetLogger(&DubboLogger{Logger: zapLogger.Sugar(), DynamicLevel: root})
+	// flush and stop the output of the previous logger
+	setOutputCloser(closer)
 }
 
 // SetLogger sets logger for dubbo and getty
 func SetLogger(log Logger) {
 	logger = log
+	callerLogger = skipCaller(log)
 }
 
 // GetLogger gets the logger
@@ -144,6 +193,13 @@ func (dl *DubboLogger) SetLoggerLevel(level string) bool {
 			logger.SetLevel(lv)
 			return true
 		}
+	case *logrus.Entry:
+		if lv, err := logrus.ParseLevel(level); err == nil {
+			logger.Logger.SetLevel(lv)
+			return true
+		}
+	case OpsLogger:
+		return logger.SetLoggerLevel(level)
 	default:
 		// Handle other logger types or unsupported cases
 		return false
@@ -152,14 +208,14 @@ func (dl *DubboLogger) SetLoggerLevel(level string) bool {
 }
 
 // initZapLoggerWithSyncer init zap Logger with syncer
-func initZapLoggerWithSyncer(conf *Config) *zap.Logger {
+func initZapLoggerWithSyncer(conf *Config, opts ...zap.Option) *zap.Logger {
 	core := zapcore.NewCore(
 		conf.getEncoder(),
 		conf.getLogWriter(),
-		zap.NewAtomicLevelAt(conf.ZapConfig.Level.Level()),
+		conf.ZapConfig.Level,
 	)
 
-	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(conf.CallerSkip))
+	return zap.New(core, append([]zap.Option{zap.AddCaller(), zap.AddCallerSkip(conf.CallerSkip)}, opts...)...)
 }
 
 // getEncoder get encoder by config, zapcore support json and console encoder
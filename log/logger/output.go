@@ -0,0 +1,377 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// OverflowBlock blocks the caller until the async buffer has room
+	OverflowBlock = "block"
+	// OverflowDrop drops the entry if the async buffer is full
+	OverflowDrop = "drop"
+
+	defaultAsyncBufferSize = 4096
+	defaultSamplingTick    = time.Second
+)
+
+// AsyncConfig makes the entries written by a background goroutine
+type AsyncConfig struct {
+	// BufferSize is the max number of the entries waiting to be written, 4096 by default
+	BufferSize int `yaml:"buffer-size"`
+	// Overflow is the policy when the buffer is full, OverflowBlock by default
+	Overflow string `yaml:"overflow"`
+}
+
+// SamplingConfig logs the first @Initial entries with the same level and
+// message in every @Tick, and every @Thereafter entry after that
+type SamplingConfig struct {
+	Tick       time.Duration `yaml:"tick"`
+	Initial    int           `yaml:"initial"`
+	Thereafter int           `yaml:"thereafter"`
+}
+
+// RateLimitConfig limits the entries by a token bucket of @Rate entries per
+// second and @Burst entries at most. The entries above the error level are
+// never limited.
+type RateLimitConfig struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// outputCloser flushes and stops the background goroutine of the current logger
+var (
+	outputLock   sync.Mutex
+	outputCloser func()
+)
+
+// wrapCore wraps @core by the sampling, rate limit and async configs, and
+// returns the function to close the wrapped core
+func (c *Config) wrapCore(core zapcore.Core) (zapcore.Core, func()) {
+	closer := func() {}
+	if c.Async != nil {
+		ac := newAsyncCore(core, c.Async)
+		core, closer = ac, ac.close
+	}
+	if c.RateLimit != nil && c.RateLimit.Rate > 0 {
+		core = newRateLimitCore(core, c.RateLimit)
+	}
+	if c.Sampling != nil && c.Sampling.Initial > 0 {
+		tick := c.Sampling.Tick
+		if tick <= 0 {
+			tick = defaultSamplingTick
+		}
+		core = zapcore.NewSamplerWithOptions(core, tick, c.Sampling.Initial, c.Sampling.Thereafter)
+	}
+	return core, closer
+}
+
+// Flush writes the buffered entries of the global logger, it should be called before exit
+func Flush() error {
+	if s, ok := logger.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Sync flushes the buffered entries
+func (dl *DubboLogger) Sync() error {
+	if s, ok := dl.Logger.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func setOutputCloser(closer func()) {
+	outputLock.Lock()
+	prev := outputCloser
+	outputCloser = closer
+	outputLock.Unlock()
+	if prev != nil {
+		prev()
+	}
+}
+
+// droppedEntry is the warning entry of the dropped entries
+func droppedEntry(reason string, dropped uint64) zapcore.Entry {
+	return zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("%d log entries are dropped by the %s", dropped, reason),
+	}
+}
+
+type asyncEntry struct {
+	// checked holds the cores which accept the entry
+	checked *zapcore.CheckedEntry
+	fields  []zapcore.Field
+	synced  chan struct{} // closed when the entries before it are written
+}
+
+type asyncQueue struct {
+	entries chan asyncEntry
+	block   bool
+	dropped uint64
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// asyncCore encodes and writes the entries in a background goroutine
+type asyncCore struct {
+	zapcore.Core
+	queue *asyncQueue
+}
+
+func newAsyncCore(core zapcore.Core, conf *AsyncConfig) *asyncCore {
+	size := conf.BufferSize
+	if size <= 0 {
+		size = defaultAsyncBufferSize
+	}
+	q := &asyncQueue{
+		entries: make(chan asyncEntry, size),
+		block:   conf.Overflow != OverflowDrop,
+		done:    make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run(core)
+	return &asyncCore{Core: core, queue: q}
+}
+
+func (q *asyncQueue) run(core zapcore.Core) {
+	defer q.wg.Done()
+	for {
+		select {
+		case e := <-q.entries:
+			q.write(core, e)
+		case <-q.done:
+			// drain the buffer
+			for {
+				select {
+				case e := <-q.entries:
+					q.write(core, e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *asyncQueue) write(core zapcore.Core, e asyncEntry) {
+	if dropped := atomic.SwapUint64(&q.dropped, 0); dropped > 0 {
+		ent := droppedEntry("async buffer", dropped)
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+	}
+	if e.synced != nil {
+		close(e.synced)
+		return
+	}
+	e.checked.Write(e.fields...)
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &asyncCore{Core: c.Core.With(fields), queue: c.queue}
+}
+
+// Check asks the inner core which of its cores accept @ent, like the sinks
+// of a tee with different levels, and only queues the entry to them
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if checked := c.Core.Check(ent, nil); checked != nil {
+		return ce.AddCore(ent, &asyncCheckedCore{asyncCore: c, checked: checked})
+	}
+	return ce
+}
+
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.enqueue((*zapcore.CheckedEntry)(nil).AddCore(ent, c.Core), fields)
+}
+
+// enqueue queues @checked to be written with @fields
+func (c *asyncCore) enqueue(checked *zapcore.CheckedEntry, fields []zapcore.Field) error {
+	// the process may exit after the entry above the error level
+	if checked.Level > zapcore.ErrorLevel {
+		_ = c.Sync()
+		checked.Write(fields...)
+		return nil
+	}
+
+	// the fields may be reused by the caller
+	e := asyncEntry{checked: checked, fields: append([]zapcore.Field(nil), fields...)}
+	select {
+	case <-c.queue.done:
+		checked.Write(fields...)
+		return nil
+	default:
+	}
+	if c.queue.block {
+		select {
+		case c.queue.entries <- e:
+		case <-c.queue.done:
+			checked.Write(fields...)
+		}
+		return nil
+	}
+	select {
+	case c.queue.entries <- e:
+	default:
+		atomic.AddUint64(&c.queue.dropped, 1)
+	}
+	return nil
+}
+
+// Sync waits until the buffered entries are written, then syncs the core
+func (c *asyncCore) Sync() error {
+	synced := make(chan struct{})
+	select {
+	case c.queue.entries <- asyncEntry{synced: synced}:
+		select {
+		case <-synced:
+		case <-c.queue.done:
+			c.queue.wg.Wait()
+		}
+	case <-c.queue.done:
+		c.queue.wg.Wait()
+	}
+	return c.Core.Sync()
+}
+
+// asyncCheckedCore queues the entry checked by the inner core of asyncCore
+type asyncCheckedCore struct {
+	*asyncCore
+	checked *zapcore.CheckedEntry
+}
+
+func (c *asyncCheckedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// the caller and stack are filled in after the entry is checked
+	c.checked.Entry = ent
+	return c.enqueue(c.checked, fields)
+}
+
+// close writes the buffered entries and stops the background goroutine
+func (c *asyncCore) close() {
+	c.queue.once.Do(func() {
+		close(c.queue.done)
+	})
+	c.queue.wg.Wait()
+	_ = c.Core.Sync()
+}
+
+// tokenBucket allows @rate events per second and @burst events at most
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type rateLimiter struct {
+	bucket  *tokenBucket
+	dropped uint64
+}
+
+// rateLimitCore drops the entries beyond the token bucket
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+}
+
+func newRateLimitCore(core zapcore.Core, conf *RateLimitConfig) *rateLimitCore {
+	return &rateLimitCore{
+		Core:    core,
+		limiter: &rateLimiter{bucket: newTokenBucket(conf.Rate, conf.Burst)},
+	}
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level <= zapcore.ErrorLevel && !c.limiter.bucket.allow() {
+		atomic.AddUint64(&c.limiter.dropped, 1)
+		return ce
+	}
+	c.reportDropped()
+	// the inner core decides which of its cores accept the entry, like the
+	// sinks of a tee with different levels
+	return c.Core.Check(ent, ce)
+}
+
+// reportDropped writes the number of the dropped entries to the inner cores
+// which accept the warning
+func (c *rateLimitCore) reportDropped() {
+	if dropped := atomic.SwapUint64(&c.limiter.dropped, 0); dropped > 0 {
+		if ce := c.Core.Check(droppedEntry("rate limiter", dropped), nil); ce != nil {
+			ce.Write()
+		}
+	}
+}
+
+func (c *rateLimitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.reportDropped()
+	return c.Core.Write(ent, fields)
+}
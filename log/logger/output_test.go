@@ -0,0 +1,182 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/natefinch/lumberjack"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// blockingCore blocks the writes until it is released
+type blockingCore struct {
+	zapcore.Core
+	release chan struct{}
+}
+
+func (c *blockingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *blockingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	<-c.release
+	return c.Core.Write(ent, fields)
+}
+
+func TestAsyncCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ac := newAsyncCore(core, &AsyncConfig{})
+	l := zap.New(ac)
+
+	for i := 0; i < 100; i++ {
+		l.Info("async", zap.Int("i", i))
+	}
+	assert.Nil(t, l.Sync())
+	entries := logs.AllUntimed()
+	assert.Equal(t, 100, len(entries))
+	for i, e := range entries {
+		assert.Equal(t, int64(i), e.ContextMap()["i"])
+	}
+
+	// written directly after closed
+	ac.close()
+	l.With(zap.String("k", "v")).Warn("closed")
+	assert.Equal(t, 101, logs.Len())
+	assert.Nil(t, l.Sync())
+}
+
+func TestAsyncCoreDrop(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := &blockingCore{Core: observed, release: make(chan struct{})}
+	ac := newAsyncCore(core, &AsyncConfig{BufferSize: 1, Overflow: OverflowDrop})
+	l := zap.New(ac)
+
+	// the first one is taken by the blocked background goroutine
+	l.Info("first")
+	assert.Eventually(t, func() bool {
+		return len(ac.queue.entries) == 0
+	}, time.Second, time.Millisecond)
+	// one is buffered and the others are dropped
+	for i := 0; i < 10; i++ {
+		l.Info("drop")
+	}
+	close(core.release)
+	ac.close()
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "first", entries[0].Message)
+	assert.Equal(t, "9 log entries are dropped by the async buffer", entries[1].Message)
+	assert.Equal(t, "drop", entries[2].Message)
+}
+
+func TestRateLimitCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	rc := newRateLimitCore(core, &RateLimitConfig{Rate: 10, Burst: 2})
+	now := time.Now()
+	rc.limiter.bucket.last = now
+	rc.limiter.bucket.now = func() time.Time { return now }
+	l := zap.New(rc)
+
+	for i := 0; i < 5; i++ {
+		l.Info("limited")
+	}
+	assert.Equal(t, 2, logs.Len())
+	// the entries above the error level are not limited, and the dropped
+	// entries are reported before the next entry
+	l.DPanic("dpanic")
+	entries := logs.AllUntimed()
+	assert.Equal(t, 4, len(entries))
+	assert.Equal(t, "3 log entries are dropped by the rate limiter", entries[2].Message)
+	assert.Equal(t, zapcore.WarnLevel, entries[2].Level)
+	assert.Equal(t, "dpanic", entries[3].Message)
+
+	// 1 token in 100ms
+	now = now.Add(100 * time.Millisecond)
+	l.Info("next")
+	l.Info("limited")
+	entries = logs.AllUntimed()
+	assert.Equal(t, 5, len(entries))
+	assert.Equal(t, "next", entries[4].Message)
+}
+
+func TestRateLimitCoreSinks(t *testing.T) {
+	debugCore, debugLogs := observer.New(zapcore.DebugLevel)
+	errorCore, errorLogs := observer.New(zapcore.ErrorLevel)
+	rc := newRateLimitCore(zapcore.NewTee(debugCore, errorCore), &RateLimitConfig{Rate: 10, Burst: 1})
+	now := time.Now()
+	rc.limiter.bucket.last = now
+	rc.limiter.bucket.now = func() time.Time { return now }
+	l := zap.New(rc)
+
+	// the sinks filter the entries by their own levels
+	l.Info("info")
+	l.Info("limited")
+	now = now.Add(100 * time.Millisecond)
+	l.Error("error")
+	entries := debugLogs.AllUntimed()
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "info", entries[0].Message)
+	assert.Equal(t, "1 log entries are dropped by the rate limiter", entries[1].Message)
+	assert.Equal(t, "error", entries[2].Message)
+	entries = errorLogs.AllUntimed()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "error", entries[0].Message)
+}
+
+func TestInitLoggerOutput(t *testing.T) {
+	defer InitLogger(nil)
+
+	file := filepath.Join(t.TempDir(), "test.log")
+	InitLogger(&Config{
+		LumberjackConfig: &lumberjack.Logger{Filename: file},
+		ZapConfig: &zap.Config{
+			Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+			Encoding:      "json",
+			EncoderConfig: zap.NewProductionEncoderConfig(),
+		},
+		Async:     &AsyncConfig{BufferSize: 16},
+		Sampling:  &SamplingConfig{Initial: 2, Thereafter: 0},
+		RateLimit: &RateLimitConfig{Rate: 1000, Burst: 1000},
+	})
+	for i := 0; i < 10; i++ {
+		Info("sampled")
+	}
+	Warn("warn")
+	assert.Nil(t, Flush())
+
+	content, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), `"msg":"sampled"`))
+	assert.Equal(t, 1, strings.Count(string(content), `"msg":"warn"`))
+}
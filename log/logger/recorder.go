@@ -0,0 +1,242 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"fmt"
+	"sync"
+)
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// NopLogger discards all the entries, Fatal and Fatalf do not exit either
+type NopLogger struct {
+	level *dynamicLevel
+}
+
+// NewNopLogger returns a NopLogger at the info level
+func NewNopLogger() *NopLogger {
+	return &NopLogger{level: newDynamicLevel(zapcore.InfoLevel)}
+}
+
+// SetLoggerLevel implements OpsLogger, the level only affects Level
+func (l *NopLogger) SetLoggerLevel(level string) bool {
+	return l.level.set(level)
+}
+
+// Level returns the current level, eg: "info"
+func (l *NopLogger) Level() string {
+	return l.level.get().String()
+}
+
+func (l *NopLogger) Debug(args ...interface{}) {}
+
+func (l *NopLogger) Info(args ...interface{}) {}
+
+func (l *NopLogger) Warn(args ...interface{}) {}
+
+func (l *NopLogger) Error(args ...interface{}) {}
+
+func (l *NopLogger) Fatal(args ...interface{}) {}
+
+func (l *NopLogger) Debugf(format string, args ...interface{}) {}
+
+func (l *NopLogger) Infof(format string, args ...interface{}) {}
+
+func (l *NopLogger) Warnf(format string, args ...interface{}) {}
+
+func (l *NopLogger) Errorf(format string, args ...interface{}) {}
+
+func (l *NopLogger) Fatalf(format string, args ...interface{}) {}
+
+func (l *NopLogger) DebugKV(msg string, keysAndValues ...interface{}) {}
+
+func (l *NopLogger) InfoKV(msg string, keysAndValues ...interface{}) {}
+
+func (l *NopLogger) WarnKV(msg string, keysAndValues ...interface{}) {}
+
+func (l *NopLogger) ErrorKV(msg string, keysAndValues ...interface{}) {}
+
+func (l *NopLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	return l
+}
+
+func (l *NopLogger) Named(name string) StructuredLogger {
+	return l
+}
+
+// RecordedEntry is an entry captured by Recorder
+type RecordedEntry struct {
+	Level      string
+	LoggerName string
+	Message    string
+	Fields     map[string]interface{}
+}
+
+// recorderStore is the entries shared by a Recorder and its children
+type recorderStore struct {
+	lock    sync.Mutex
+	entries []RecordedEntry
+}
+
+// Recorder captures the entries in memory for the assertions in tests, eg:
+//
+//	rec := logger.NewRecorder()
+//	logger.SetLogger(rec)
+//	...
+//	assert.Equal(t, "connected", rec.Entries()[0].Message)
+//
+// Fatal and Fatalf record the entries at the fatal level and do not exit.
+type Recorder struct {
+	store  *recorderStore
+	level  *dynamicLevel
+	name   string
+	fields []interface{}
+}
+
+// NewRecorder returns a Recorder at the debug level
+func NewRecorder() *Recorder {
+	return &Recorder{store: &recorderStore{}, level: newDynamicLevel(zapcore.DebugLevel)}
+}
+
+// SetLoggerLevel implements OpsLogger, the entries below the level are not recorded
+func (r *Recorder) SetLoggerLevel(level string) bool {
+	return r.level.set(level)
+}
+
+// Level returns the current level, eg: "debug"
+func (r *Recorder) Level() string {
+	return r.level.get().String()
+}
+
+// Entries returns a copy of the recorded entries in order
+func (r *Recorder) Entries() []RecordedEntry {
+	r.store.lock.Lock()
+	defer r.store.lock.Unlock()
+	entries := make([]RecordedEntry, len(r.store.entries))
+	copy(entries, r.store.entries)
+	return entries
+}
+
+// Len returns the number of the recorded entries
+func (r *Recorder) Len() int {
+	r.store.lock.Lock()
+	defer r.store.lock.Unlock()
+	return len(r.store.entries)
+}
+
+// Reset removes all the recorded entries
+func (r *Recorder) Reset() {
+	r.store.lock.Lock()
+	r.store.entries = nil
+	r.store.lock.Unlock()
+}
+
+func (r *Recorder) record(level zapcore.Level, msg string, keysAndValues []interface{}) {
+	if !r.level.enabled(level) {
+		return
+	}
+	fields := make(map[string]interface{}, (len(r.fields)+len(keysAndValues))/2)
+	for key, value := range logrusFields(r.fields) {
+		fields[key] = value
+	}
+	for key, value := range logrusFields(keysAndValues) {
+		fields[key] = value
+	}
+	entry := RecordedEntry{Level: level.String(), LoggerName: r.name, Message: msg, Fields: fields}
+
+	r.store.lock.Lock()
+	r.store.entries = append(r.store.entries, entry)
+	r.store.lock.Unlock()
+}
+
+func (r *Recorder) Debug(args ...interface{}) {
+	r.record(zapcore.DebugLevel, fmt.Sprint(args...), nil)
+}
+
+func (r *Recorder) Info(args ...interface{}) {
+	r.record(zapcore.InfoLevel, fmt.Sprint(args...), nil)
+}
+
+func (r *Recorder) Warn(args ...interface{}) {
+	r.record(zapcore.WarnLevel, fmt.Sprint(args...), nil)
+}
+
+func (r *Recorder) Error(args ...interface{}) {
+	r.record(zapcore.ErrorLevel, fmt.Sprint(args...), nil)
+}
+
+func (r *Recorder) Fatal(args ...interface{}) {
+	r.record(zapcore.FatalLevel, fmt.Sprint(args...), nil)
+}
+
+func (r *Recorder) Debugf(format string, args ...interface{}) {
+	r.record(zapcore.DebugLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (r *Recorder) Infof(format string, args ...interface{}) {
+	r.record(zapcore.InfoLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (r *Recorder) Warnf(format string, args ...interface{}) {
+	r.record(zapcore.WarnLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (r *Recorder) Errorf(format string, args ...interface{}) {
+	r.record(zapcore.ErrorLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (r *Recorder) Fatalf(format string, args ...interface{}) {
+	r.record(zapcore.FatalLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (r *Recorder) DebugKV(msg string, keysAndValues ...interface{}) {
+	r.record(zapcore.DebugLevel, msg, keysAndValues)
+}
+
+func (r *Recorder) InfoKV(msg string, keysAndValues ...interface{}) {
+	r.record(zapcore.InfoLevel, msg, keysAndValues)
+}
+
+func (r *Recorder) WarnKV(msg string, keysAndValues ...interface{}) {
+	r.record(zapcore.WarnLevel, msg, keysAndValues)
+}
+
+func (r *Recorder) ErrorKV(msg string, keysAndValues ...interface{}) {
+	r.record(zapcore.ErrorLevel, msg, keysAndValues)
+}
+
+// With implements StructuredLogger, the children share the entries and the
+// level. A key without value is ignored like the zap logger, so it does not
+// pair with the fields of the entries.
+func (r *Recorder) With(keysAndValues ...interface{}) StructuredLogger {
+	if len(keysAndValues)%2 != 0 {
+		keysAndValues = keysAndValues[:len(keysAndValues)-1]
+	}
+	fields := make([]interface{}, 0, len(r.fields)+len(keysAndValues))
+	fields = append(fields, r.fields...)
+	fields = append(fields, keysAndValues...)
+	return &Recorder{store: r.store, level: r.level, name: r.name, fields: fields}
+}
+
+// Named implements StructuredLogger, the children share the entries and the level
+func (r *Recorder) Named(name string) StructuredLogger {
+	return &Recorder{store: r.store, level: r.level, name: joinName(r.name, name), fields: r.fields}
+}
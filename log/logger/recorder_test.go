@@ -0,0 +1,89 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	assert.Equal(t, "debug", rec.Level())
+
+	rec.Debug("debug ", "message")
+	rec.Fatalf("fatal %d", 1)
+	rec.Named("cluster").With("method", "Echo").ErrorKV("call failed", "retries", 2)
+	assert.Equal(t, []RecordedEntry{
+		{Level: "debug", Message: "debug message", Fields: map[string]interface{}{}},
+		{Level: "fatal", Message: "fatal 1", Fields: map[string]interface{}{}},
+		{
+			Level:      "error",
+			LoggerName: "cluster",
+			Message:    "call failed",
+			Fields:     map[string]interface{}{"method": "Echo", "retries": 2},
+		},
+	}, rec.Entries())
+
+	// the children share the entries and the level
+	child := rec.Named("registry")
+	assert.True(t, child.(OpsLogger).SetLoggerLevel("warn"))
+	rec.Info("ignored")
+	child.Warn("warn message")
+	assert.Equal(t, 4, rec.Len())
+	assert.Equal(t, "registry", rec.Entries()[3].LoggerName)
+	assert.False(t, rec.SetLoggerLevel("bad"))
+	assert.Equal(t, "warn", rec.Level())
+
+	rec.Reset()
+	assert.Equal(t, 0, rec.Len())
+
+	// the key without value does not pair with the fields of the entry
+	child.With("dangling").WarnKV("odd", "k", "v")
+	assert.Equal(t, map[string]interface{}{"k": "v"}, rec.Entries()[0].Fields)
+}
+
+func TestRecorderGlobal(t *testing.T) {
+	defer InitLogger(nil)
+
+	rec := NewRecorder()
+	SetLogger(rec)
+	Infof("hello %s", "world")
+	Named("getty").InfoKV("connected", "peer", "127.0.0.1")
+	assert.True(t, SetLoggerLevel("error"))
+	assert.Equal(t, "error", GetNamedLoggerLevel(""))
+	Warn("ignored")
+
+	entries := rec.Entries()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "hello world", entries[0].Message)
+	assert.Equal(t, "getty", entries[1].LoggerName)
+	assert.Equal(t, "127.0.0.1", entries[1].Fields["peer"])
+}
+
+func TestNopLogger(t *testing.T) {
+	var l StructuredLogger = NewNopLogger()
+	l.Fatal("not exit")
+	l.Named("n").With("k", "v").InfoKV("msg")
+	assert.True(t, l.(OpsLogger).SetLoggerLevel("error"))
+	assert.False(t, l.(OpsLogger).SetLoggerLevel("bad"))
+	assert.Equal(t, "error", l.(*NopLogger).Level())
+}
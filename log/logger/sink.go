@@ -0,0 +1,172 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/natefinch/lumberjack"
+
+	perrors "github.com/pkg/errors"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+import (
+	gxyaml "github.com/dubbogo/gost/encoding/yaml"
+)
+
+const (
+	encodingConsole = "console"
+	encodingJSON    = "json"
+)
+
+// SinkConfig is an output of the logger, eg:
+//
+//	sinks:
+//	  - output: stdout
+//	    level: debug
+//	    color: true
+//	  - level: info
+//	    encoding: json
+//	    rotation:
+//	      filename: logs/app.log
+//	      maxsize: 100
+//	  - level: error
+//	    rotation:
+//	      filename: logs/error.log
+type SinkConfig struct {
+	// Level is the min level of the sink, the entry should pass the level of
+	// the logger or the named logger too, which is set by SetLoggerLevel or
+	// SetNamedLoggerLevel
+	Level string `yaml:"level"`
+	// Encoding is "console" or "json", it is the encoding of ZapConfig by default
+	Encoding string `yaml:"encoding"`
+	// Color colors the level of the console encoding
+	Color bool `yaml:"color"`
+	// Output is "stdout", "stderr" or a file path, it is ignored if Rotation is set
+	Output string `yaml:"output"`
+	// Rotation writes to a rotating file
+	Rotation *lumberjack.Logger `yaml:"rotation"`
+}
+
+// LoadConfig loads the Config from the yml file @file
+func LoadConfig(file string) (*Config, error) {
+	conf := &Config{}
+	if _, err := gxyaml.UnmarshalYMLConfig(file, conf); err != nil {
+		return nil, perrors.WithMessagef(err, "load logger config %s", file)
+	}
+	return conf, nil
+}
+
+// minSinkLevel returns the lowest level of the sinks
+func minSinkLevel(sinks []SinkConfig) (zapcore.Level, bool) {
+	var (
+		min zapcore.Level
+		ok  bool
+	)
+	for _, sink := range sinks {
+		if sink.Level == "" {
+			continue
+		}
+		lv, err := zapcore.ParseLevel(sink.Level)
+		if err != nil {
+			continue
+		}
+		if !ok || lv < min {
+			min, ok = lv, true
+		}
+	}
+	return min, ok
+}
+
+// initZapLoggerWithSinks init zap Logger writing to all the sinks
+func initZapLoggerWithSinks(conf *Config, opts ...zap.Option) *zap.Logger {
+	cores := make([]zapcore.Core, 0, len(conf.Sinks))
+	for _, sink := range conf.Sinks {
+		core, err := conf.newSinkCore(sink)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gost/logger: skip the sink %+v: %v\n", sink, err)
+			continue
+		}
+		cores = append(cores, core)
+	}
+
+	zapOpts := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(conf.CallerSkip)}
+	if !conf.ZapConfig.DisableStacktrace {
+		stackLevel := zapcore.ErrorLevel
+		if conf.ZapConfig.Development {
+			stackLevel = zapcore.WarnLevel
+		}
+		zapOpts = append(zapOpts, zap.AddStacktrace(stackLevel))
+	}
+	for key, value := range conf.ZapConfig.InitialFields {
+		zapOpts = append(zapOpts, zap.Fields(zap.Any(key, value)))
+	}
+	return zap.New(zapcore.NewTee(cores...), append(zapOpts, opts...)...)
+}
+
+func (c *Config) newSinkCore(sink SinkConfig) (zapcore.Core, error) {
+	var level zapcore.LevelEnabler = c.ZapConfig.Level
+	if sink.Level != "" {
+		lv, err := zapcore.ParseLevel(sink.Level)
+		if err != nil {
+			return nil, err
+		}
+		level = lv
+	}
+
+	encoding := sink.Encoding
+	if encoding == "" {
+		encoding = c.ZapConfig.Encoding
+	}
+	encoderConfig := c.ZapConfig.EncoderConfig
+	var encoder zapcore.Encoder
+	switch encoding {
+	case encodingJSON:
+		encoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case encodingConsole, "":
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		if sink.Color {
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, perrors.Errorf("unknown encoding %q", encoding)
+	}
+
+	var writer zapcore.WriteSyncer
+	if sink.Rotation != nil {
+		writer = zapcore.AddSync(sink.Rotation)
+	} else {
+		output := sink.Output
+		if output == "" {
+			output = "stdout"
+		}
+		var err error
+		if writer, _, err = zap.Open(output); err != nil {
+			return nil, err
+		}
+	}
+	return zapcore.NewCore(encoder, writer, level), nil
+}
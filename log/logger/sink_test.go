@@ -0,0 +1,122 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoadConfig(t *testing.T) {
+	conf, err := LoadConfig("file_log.yml")
+	assert.Nil(t, err)
+	assert.Equal(t, "logs.log", conf.LumberjackConfig.Filename)
+	assert.Equal(t, 1, conf.LumberjackConfig.MaxSize)
+	assert.Equal(t, zapcore.DebugLevel, conf.ZapConfig.Level.Level())
+	assert.Equal(t, "console", conf.ZapConfig.Encoding)
+
+	conf, err = LoadConfig("testdata/sinks_log.yml")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(conf.Sinks))
+	assert.Equal(t, SinkConfig{Output: "stdout", Level: "debug", Color: true}, conf.Sinks[0])
+	assert.Equal(t, "json", conf.Sinks[1].Encoding)
+	assert.Equal(t, "logs/app.log", conf.Sinks[1].Rotation.Filename)
+	assert.Equal(t, 100, conf.Sinks[1].Rotation.MaxSize)
+	assert.Equal(t, "error", conf.Sinks[2].Level)
+
+	_, err = LoadConfig("testdata/absent.yml")
+	assert.NotNil(t, err)
+}
+
+func TestInitLoggerWithSinks(t *testing.T) {
+	t.Run("sync", func(t *testing.T) {
+		testInitLoggerWithSinks(t, nil)
+	})
+	// the async core writes the entries to the sinks which accept them
+	t.Run("async", func(t *testing.T) {
+		testInitLoggerWithSinks(t, &AsyncConfig{BufferSize: 16})
+	})
+}
+
+func testInitLoggerWithSinks(t *testing.T, async *AsyncConfig) {
+	defer InitLogger(nil)
+
+	conf, err := LoadConfig("testdata/sinks_log.yml")
+	assert.Nil(t, err)
+	conf.Async = async
+	dir := t.TempDir()
+	console := filepath.Join(dir, "console.log")
+	conf.Sinks[0].Output = console
+	conf.Sinks[1].Rotation.Filename = filepath.Join(dir, "app.log")
+	conf.Sinks[2].Rotation.Filename = filepath.Join(dir, "error.log")
+	InitLogger(conf)
+
+	Debug("debug message")
+	Info("info message")
+	Error("error message")
+	assert.Nil(t, Flush())
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		assert.Nil(t, err)
+		return string(content)
+	}
+	content := read("console.log")
+	assert.Equal(t, 3, strings.Count(content, " message"))
+	assert.Contains(t, content, "\x1b[35mDEBUG\x1b[0m")
+
+	content = read("app.log")
+	assert.Equal(t, 2, strings.Count(content, " message"))
+	assert.Contains(t, content, `"level":"info","time"`)
+	assert.Contains(t, content, `"message":"error message"`)
+	assert.NotContains(t, content, "debug message")
+
+	content = read("error.log")
+	assert.True(t, strings.HasPrefix(content, "20"))
+	assert.Contains(t, content, "ERROR")
+	assert.NotContains(t, content, "info message")
+
+	// the level of the logger filters all the sinks
+	assert.True(t, SetLoggerLevel("warn"))
+	Info("ignored")
+	assert.Nil(t, Flush())
+	assert.NotContains(t, read("console.log"), "ignored")
+}
+
+func TestSinkLevel(t *testing.T) {
+	lv, ok := minSinkLevel([]SinkConfig{{Level: "error"}, {Level: "bad"}, {Level: "info"}, {}})
+	assert.True(t, ok)
+	assert.Equal(t, zapcore.InfoLevel, lv)
+	_, ok = minSinkLevel([]SinkConfig{{}})
+	assert.False(t, ok)
+
+	conf := &Config{ZapConfig: &zap.Config{Level: zap.NewAtomicLevel()}}
+	_, err := conf.newSinkCore(SinkConfig{Level: "bad"})
+	assert.NotNil(t, err)
+	_, err = conf.newSinkCore(SinkConfig{Encoding: "xml"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,205 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelFatal is the slog level of the fatal entries
+const LevelFatal = slog.LevelError + 4
+
+// slogCallerSkip skips the frames of the adapter and the package-level
+// function, like the default CallerSkip of InitLogger
+const slogCallerSkip = 1
+
+// dynamicLevel is the level shared by a logger and its children
+type dynamicLevel struct {
+	level int32 // zapcore.Level
+}
+
+func newDynamicLevel(level zapcore.Level) *dynamicLevel {
+	return &dynamicLevel{level: int32(level)}
+}
+
+func (l *dynamicLevel) enabled(level zapcore.Level) bool {
+	return level >= zapcore.Level(atomic.LoadInt32(&l.level))
+}
+
+func (l *dynamicLevel) get() zapcore.Level {
+	return zapcore.Level(atomic.LoadInt32(&l.level))
+}
+
+// set parses @level like SetLoggerLevel, eg: "debug", "info", "warn", "error"
+func (l *dynamicLevel) set(level string) bool {
+	if level == "" {
+		return false
+	}
+	lv, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return false
+	}
+	atomic.StoreInt32(&l.level, int32(lv))
+	return true
+}
+
+func slogLevel(level zapcore.Level) slog.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return slog.LevelDebug
+	case zapcore.InfoLevel:
+		return slog.LevelInfo
+	case zapcore.WarnLevel:
+		return slog.LevelWarn
+	case zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// SlogLogger adapts a slog.Handler to StructuredLogger and OpsLogger, the
+// entries below its level are dropped before the handler. Fatal logs at
+// LevelFatal and then calls os.Exit(1).
+type SlogLogger struct {
+	handler slog.Handler
+	level   *dynamicLevel
+	name    string
+}
+
+// NewSlogLogger returns a SlogLogger of @handler at the info level
+func NewSlogLogger(handler slog.Handler) *SlogLogger {
+	return &SlogLogger{handler: handler, level: newDynamicLevel(zapcore.InfoLevel)}
+}
+
+// SetLoggerLevel implements OpsLogger
+func (l *SlogLogger) SetLoggerLevel(level string) bool {
+	return l.level.set(level)
+}
+
+// Level returns the current level, eg: "info"
+func (l *SlogLogger) Level() string {
+	return l.level.get().String()
+}
+
+func (l *SlogLogger) log(level zapcore.Level, msg string, keysAndValues []interface{}) {
+	if !l.level.enabled(level) {
+		return
+	}
+	lv := slogLevel(level)
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, lv) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and the method of SlogLogger
+	runtime.Callers(3+slogCallerSkip, pcs[:])
+	r := slog.NewRecord(time.Now(), lv, msg, pcs[0])
+	if l.name != "" {
+		r.AddAttrs(slog.String(nameKey, l.name))
+	}
+	r.Add(keysAndValues...)
+	_ = l.handler.Handle(ctx, r)
+}
+
+func (l *SlogLogger) Debug(args ...interface{}) {
+	l.log(zapcore.DebugLevel, fmt.Sprint(args...), nil)
+}
+
+func (l *SlogLogger) Info(args ...interface{}) {
+	l.log(zapcore.InfoLevel, fmt.Sprint(args...), nil)
+}
+
+func (l *SlogLogger) Warn(args ...interface{}) {
+	l.log(zapcore.WarnLevel, fmt.Sprint(args...), nil)
+}
+
+func (l *SlogLogger) Error(args ...interface{}) {
+	l.log(zapcore.ErrorLevel, fmt.Sprint(args...), nil)
+}
+
+func (l *SlogLogger) Fatal(args ...interface{}) {
+	l.log(zapcore.FatalLevel, fmt.Sprint(args...), nil)
+	os.Exit(1)
+}
+
+func (l *SlogLogger) Debugf(format string, args ...interface{}) {
+	l.log(zapcore.DebugLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *SlogLogger) Infof(format string, args ...interface{}) {
+	l.log(zapcore.InfoLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *SlogLogger) Warnf(format string, args ...interface{}) {
+	l.log(zapcore.WarnLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *SlogLogger) Errorf(format string, args ...interface{}) {
+	l.log(zapcore.ErrorLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (l *SlogLogger) Fatalf(format string, args ...interface{}) {
+	l.log(zapcore.FatalLevel, fmt.Sprintf(format, args...), nil)
+	os.Exit(1)
+}
+
+func (l *SlogLogger) DebugKV(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.DebugLevel, msg, keysAndValues)
+}
+
+func (l *SlogLogger) InfoKV(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.InfoLevel, msg, keysAndValues)
+}
+
+func (l *SlogLogger) WarnKV(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.WarnLevel, msg, keysAndValues)
+}
+
+func (l *SlogLogger) ErrorKV(msg string, keysAndValues ...interface{}) {
+	l.log(zapcore.ErrorLevel, msg, keysAndValues)
+}
+
+// With implements StructuredLogger, the children share the level
+func (l *SlogLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(keysAndValues...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return &SlogLogger{handler: l.handler.WithAttrs(attrs), level: l.level, name: l.name}
+}
+
+// Named implements StructuredLogger, the name is added to every record as
+// the "logger" attribute, so a sub-logger has only its full name
+func (l *SlogLogger) Named(name string) StructuredLogger {
+	return &SlogLogger{handler: l.handler, level: l.level, name: joinName(l.name, name)}
+}
@@ -0,0 +1,109 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func newBufferedSlogLogger() (*SlogLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true})
+	return NewSlogLogger(handler), buf
+}
+
+func decodeSlogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	buf.Reset()
+	return lines
+}
+
+func TestSlogLogger(t *testing.T) {
+	l, buf := newBufferedSlogLogger()
+	assert.Equal(t, "info", l.Level())
+
+	l.Debug("ignored")
+	l.Infof("hello %s", "slog")
+	l.Named("registry").Named("zk").With("addr", "127.0.0.1:2181").WarnKV("session expired", "timeout", 3)
+	// the name of the sub-logger is written once
+	assert.Equal(t, 1, strings.Count(buf.String(), `"logger":`))
+	lines := decodeSlogLines(t, buf)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "hello slog", lines[0]["msg"])
+	assert.NotNil(t, lines[0]["source"])
+	assert.Equal(t, "WARN", lines[1]["level"])
+	assert.Equal(t, "registry.zk", lines[1][nameKey])
+	assert.Equal(t, "127.0.0.1:2181", lines[1]["addr"])
+	assert.Equal(t, float64(3), lines[1]["timeout"])
+
+	// the children share the level
+	child := l.With("k", "v")
+	assert.True(t, l.SetLoggerLevel("debug"))
+	child.Debug("debug message")
+	assert.Equal(t, "debug message", decodeSlogLines(t, buf)[0]["msg"])
+
+	assert.True(t, l.SetLoggerLevel("error"))
+	child.Warn("ignored")
+	l.ErrorKV("failed", "err", "timeout")
+	lines = decodeSlogLines(t, buf)
+	assert.Equal(t, 1, len(lines))
+	assert.Equal(t, "ERROR", lines[0]["level"])
+	assert.Equal(t, "error", l.Level())
+
+	assert.False(t, l.SetLoggerLevel("bad"))
+	assert.False(t, l.SetLoggerLevel(""))
+	assert.Equal(t, "error", l.Level())
+}
+
+func TestSlogLoggerGlobal(t *testing.T) {
+	defer InitLogger(nil)
+
+	l, buf := newBufferedSlogLogger()
+	SetLogger(l)
+	assert.True(t, SetLoggerLevel("warn"))
+	assert.Equal(t, "warn", GetNamedLoggerLevel(""))
+	Info("ignored")
+	Warn("warn message")
+	lines := decodeSlogLines(t, buf)
+	assert.Equal(t, 1, len(lines))
+	// the source is the caller of the package-level function
+	source := lines[0]["source"].(map[string]interface{})
+	assert.True(t, strings.HasSuffix(source["file"].(string), "slog_test.go"))
+
+	// DubboLogger passes the level to the adapter
+	SetLogger(&DubboLogger{Logger: l})
+	assert.True(t, SetLoggerLevel("debug"))
+	assert.Equal(t, "debug", l.Level())
+}
@@ -0,0 +1,342 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"go.uber.org/zap"
+)
+
+// nameKey is the field of the logger name if the backend has no native name support
+const nameKey = "logger"
+
+// StructuredLogger is the Logger with key-value fields and named sub-loggers
+type StructuredLogger interface {
+	Logger
+
+	// With returns a child logger with the fields @keysAndValues, which are
+	// pairs like "key1", value1, "key2", value2
+	With(keysAndValues ...interface{}) StructuredLogger
+	// Named returns a sub-logger named @name under the name of the current
+	// logger, the names are joined by "."
+	Named(name string) StructuredLogger
+
+	DebugKV(msg string, keysAndValues ...interface{})
+	InfoKV(msg string, keysAndValues ...interface{})
+	WarnKV(msg string, keysAndValues ...interface{})
+	ErrorKV(msg string, keysAndValues ...interface{})
+}
+
+// Structured returns @l as a StructuredLogger, the Logger without the
+// structured api is wrapped to append the fields to the message.
+func Structured(l Logger) StructuredLogger {
+	if sl, ok := l.(StructuredLogger); ok {
+		return sl
+	}
+	return &kvLogger{Logger: l}
+}
+
+// With returns a child logger of the global logger with the fields @keysAndValues
+func With(keysAndValues ...interface{}) StructuredLogger {
+	return Structured(logger).With(keysAndValues...)
+}
+
+// Named returns a sub-logger of the global logger named @name
+func Named(name string) StructuredLogger {
+	return Structured(logger).Named(name)
+}
+
+// DebugKV is debug level with fields
+func DebugKV(msg string, keysAndValues ...interface{}) {
+	callerLogger.DebugKV(msg, keysAndValues...)
+}
+
+// InfoKV is info level with fields
+func InfoKV(msg string, keysAndValues ...interface{}) {
+	callerLogger.InfoKV(msg, keysAndValues...)
+}
+
+// WarnKV is warning level with fields
+func WarnKV(msg string, keysAndValues ...interface{}) {
+	callerLogger.WarnKV(msg, keysAndValues...)
+}
+
+// ErrorKV is error level with fields
+func ErrorKV(msg string, keysAndValues ...interface{}) {
+	callerLogger.ErrorKV(msg, keysAndValues...)
+}
+
+// skipCaller returns @l as a StructuredLogger which skips one more frame
+// when reporting the caller, as the package-level functions call it
+func skipCaller(l Logger) StructuredLogger {
+	sl := Structured(l)
+	if dl, ok := sl.(*DubboLogger); ok {
+		if zl, ok := dl.Logger.(*zap.SugaredLogger); ok {
+			return &DubboLogger{
+				Logger:       zl.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar(),
+				DynamicLevel: dl.DynamicLevel,
+				name:         dl.name,
+			}
+		}
+	}
+	return sl
+}
+
+// With implements StructuredLogger
+func (dl *DubboLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	child := &DubboLogger{DynamicLevel: dl.DynamicLevel, name: dl.name}
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		child.Logger = l.With(keysAndValues...)
+	case *logrus.Logger:
+		child.Logger = l.WithFields(logrusFields(keysAndValues))
+	case *logrus.Entry:
+		child.Logger = l.WithFields(logrusFields(keysAndValues))
+	default:
+		child.Logger = Structured(dl.Logger).With(keysAndValues...)
+	}
+	return child
+}
+
+// Named implements StructuredLogger
+func (dl *DubboLogger) Named(name string) StructuredLogger {
+	child := &DubboLogger{DynamicLevel: dl.DynamicLevel, name: joinName(dl.name, name)}
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		child.Logger = l.Named(name)
+	case *logrus.Logger:
+		child.Logger = l.WithField(nameKey, child.name)
+	case *logrus.Entry:
+		child.Logger = l.WithField(nameKey, child.name)
+	default:
+		child.Logger = Structured(dl.Logger).Named(name)
+	}
+	return child
+}
+
+// DebugKV implements StructuredLogger
+func (dl *DubboLogger) DebugKV(msg string, keysAndValues ...interface{}) {
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		l.Debugw(msg, keysAndValues...)
+	case *logrus.Logger:
+		l.WithFields(logrusFields(keysAndValues)).Debug(msg)
+	case *logrus.Entry:
+		l.WithFields(logrusFields(keysAndValues)).Debug(msg)
+	default:
+		Structured(dl.Logger).DebugKV(msg, keysAndValues...)
+	}
+}
+
+// InfoKV implements StructuredLogger
+func (dl *DubboLogger) InfoKV(msg string, keysAndValues ...interface{}) {
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		l.Infow(msg, keysAndValues...)
+	case *logrus.Logger:
+		l.WithFields(logrusFields(keysAndValues)).Info(msg)
+	case *logrus.Entry:
+		l.WithFields(logrusFields(keysAndValues)).Info(msg)
+	default:
+		Structured(dl.Logger).InfoKV(msg, keysAndValues...)
+	}
+}
+
+// WarnKV implements StructuredLogger
+func (dl *DubboLogger) WarnKV(msg string, keysAndValues ...interface{}) {
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		l.Warnw(msg, keysAndValues...)
+	case *logrus.Logger:
+		l.WithFields(logrusFields(keysAndValues)).Warn(msg)
+	case *logrus.Entry:
+		l.WithFields(logrusFields(keysAndValues)).Warn(msg)
+	default:
+		Structured(dl.Logger).WarnKV(msg, keysAndValues...)
+	}
+}
+
+// ErrorKV implements StructuredLogger
+func (dl *DubboLogger) ErrorKV(msg string, keysAndValues ...interface{}) {
+	switch l := dl.Logger.(type) {
+	case *zap.SugaredLogger:
+		l.Errorw(msg, keysAndValues...)
+	case *logrus.Logger:
+		l.WithFields(logrusFields(keysAndValues)).Error(msg)
+	case *logrus.Entry:
+		l.WithFields(logrusFields(keysAndValues)).Error(msg)
+	default:
+		Structured(dl.Logger).ErrorKV(msg, keysAndValues...)
+	}
+}
+
+func joinName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "." + name
+}
+
+func logrusFields(keysAndValues []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields[fmt.Sprint(keysAndValues[i])] = nil
+			break
+		}
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return fields
+}
+
+// kvLogger appends the name and the fields to the messages of a Logger
+// without the structured api, like "[name] message key1=value1 key2=value2"
+type kvLogger struct {
+	Logger
+	name   string
+	fields []interface{}
+}
+
+func (l *kvLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keysAndValues))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keysAndValues...)
+	return &kvLogger{Logger: l.Logger, name: l.name, fields: fields}
+}
+
+func (l *kvLogger) Named(name string) StructuredLogger {
+	return &kvLogger{Logger: l.Logger, name: joinName(l.name, name), fields: l.fields}
+}
+
+func (l *kvLogger) format(msg string, keysAndValues []interface{}) string {
+	var b strings.Builder
+	if l.name != "" {
+		b.WriteString("[")
+		b.WriteString(l.name)
+		b.WriteString("] ")
+	}
+	b.WriteString(msg)
+	for _, kvs := range [][]interface{}{l.fields, keysAndValues} {
+		for i := 0; i < len(kvs); i += 2 {
+			b.WriteString(" ")
+			if i+1 == len(kvs) {
+				fmt.Fprint(&b, kvs[i])
+				break
+			}
+			fmt.Fprintf(&b, "%v=%v", kvs[i], kvs[i+1])
+		}
+	}
+	return b.String()
+}
+
+func (l *kvLogger) plain() bool {
+	return l.name == "" && len(l.fields) == 0
+}
+
+func (l *kvLogger) Info(args ...interface{}) {
+	if l.plain() {
+		l.Logger.Info(args...)
+		return
+	}
+	l.Logger.Info(l.format(fmt.Sprint(args...), nil))
+}
+
+func (l *kvLogger) Warn(args ...interface{}) {
+	if l.plain() {
+		l.Logger.Warn(args...)
+		return
+	}
+	l.Logger.Warn(l.format(fmt.Sprint(args...), nil))
+}
+
+func (l *kvLogger) Error(args ...interface{}) {
+	if l.plain() {
+		l.Logger.Error(args...)
+		return
+	}
+	l.Logger.Error(l.format(fmt.Sprint(args...), nil))
+}
+
+func (l *kvLogger) Debug(args ...interface{}) {
+	if l.plain() {
+		l.Logger.Debug(args...)
+		return
+	}
+	l.Logger.Debug(l.format(fmt.Sprint(args...), nil))
+}
+
+func (l *kvLogger) Fatal(args ...interface{}) {
+	if l.plain() {
+		l.Logger.Fatal(args...)
+		return
+	}
+	l.Logger.Fatal(l.format(fmt.Sprint(args...), nil))
+}
+
+func (l *kvLogger) Infof(format string, args ...interface{}) {
+	l.Logger.Info(l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *kvLogger) Warnf(format string, args ...interface{}) {
+	l.Logger.Warn(l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *kvLogger) Errorf(format string, args ...interface{}) {
+	l.Logger.Error(l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *kvLogger) Debugf(format string, args ...interface{}) {
+	l.Logger.Debug(l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *kvLogger) Fatalf(format string, args ...interface{}) {
+	l.Logger.Fatal(l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *kvLogger) DebugKV(msg string, keysAndValues ...interface{}) {
+	l.Logger.Debug(l.format(msg, keysAndValues))
+}
+
+func (l *kvLogger) InfoKV(msg string, keysAndValues ...interface{}) {
+	l.Logger.Info(l.format(msg, keysAndValues))
+}
+
+func (l *kvLogger) WarnKV(msg string, keysAndValues ...interface{}) {
+	l.Logger.Warn(l.format(msg, keysAndValues))
+}
+
+func (l *kvLogger) ErrorKV(msg string, keysAndValues ...interface{}) {
+	l.Logger.Error(l.format(msg, keysAndValues))
+}
+
+// SetLoggerLevel passes through to the wrapped OpsLogger
+func (l *kvLogger) SetLoggerLevel(level string) bool {
+	if ol, ok := l.Logger.(OpsLogger); ok {
+		return ol.SetLoggerLevel(level)
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newObservedLogger() (*DubboLogger, *observer.ObservedLogs) {
+	level := zap.NewAtomicLevelAt(zap.DebugLevel)
+	core, logs := observer.New(level)
+	return &DubboLogger{Logger: zap.New(core).Sugar(), DynamicLevel: level}, logs
+}
+
+// printLogger records the messages of a Logger without the structured api
+type printLogger struct {
+	messages []string
+}
+
+func (l *printLogger) Info(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *printLogger) Warn(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *printLogger) Error(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *printLogger) Debug(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *printLogger) Fatal(args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(args...))
+}
+
+func (l *printLogger) Infof(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *printLogger) Warnf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *printLogger) Errorf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *printLogger) Debugf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *printLogger) Fatalf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestStructuredZap(t *testing.T) {
+	dl, logs := newObservedLogger()
+
+	l := dl.Named("registry").With("addr", "127.0.0.1").Named("zk")
+	l.InfoKV("connected", "session", 1)
+	l.Infof("hello %s", "world")
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "registry.zk", entries[0].LoggerName)
+	assert.Equal(t, "connected", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"addr": "127.0.0.1", "session": int64(1)}, entries[0].ContextMap())
+	assert.Equal(t, "hello world", entries[1].Message)
+
+	// the level is shared with the sub-loggers
+	assert.True(t, l.(OpsLogger).SetLoggerLevel("error"))
+	assert.Equal(t, zapcore.ErrorLevel, dl.DynamicLevel.Level())
+	l.WarnKV("ignored")
+	l.ErrorKV("failed", "err", "timeout")
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, zapcore.ErrorLevel, logs.AllUntimed()[2].Level)
+}
+
+func TestStructuredLogrus(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(buf)
+	ll.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableColors: true})
+	dl := &DubboLogger{Logger: ll}
+
+	l := dl.Named("cluster").With("id", 7)
+	l.InfoKV("elected", "leader", true)
+	assert.Equal(t, "level=info msg=elected id=7 leader=true logger=cluster\n", buf.String())
+
+	assert.True(t, l.(OpsLogger).SetLoggerLevel("warn"))
+	assert.Equal(t, logrus.WarnLevel, ll.GetLevel())
+	buf.Reset()
+	l.InfoKV("ignored")
+	assert.Equal(t, "", buf.String())
+}
+
+func TestStructuredFallback(t *testing.T) {
+	pl := &printLogger{}
+	l := Structured(pl)
+	l.Info("plain")
+	l.Named("a").Named("b").With("k", "v").InfoKV("msg", "n", 1, "odd")
+	l.With("k", "v").Infof("%d", 1)
+	assert.Equal(t, []string{"plain", "[a.b] msg k=v n=1 odd", "1 k=v"}, pl.messages)
+
+	// the DubboLogger wrapping a Logger without the structured api
+	dl := &DubboLogger{Logger: pl}
+	dl.Named("c").ErrorKV("failed", "err", "eof")
+	assert.Equal(t, "[c] failed err=eof", pl.messages[3])
+	assert.False(t, dl.SetLoggerLevel("info"))
+}
+
+func TestStructuredGlobal(t *testing.T) {
+	defer InitLogger(nil)
+
+	dl, logs := newObservedLogger()
+	SetLogger(dl)
+	InfoKV("started", "port", 20000)
+	Named("getty").DebugKV("read", "bytes", 10)
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, map[string]interface{}{"port": int64(20000)}, entries[0].ContextMap())
+	assert.Equal(t, "getty", entries[1].LoggerName)
+}
+
+func TestStructuredGlobalCaller(t *testing.T) {
+	defer InitLogger(nil)
+
+	level := zap.NewAtomicLevelAt(zap.DebugLevel)
+	core, logs := observer.New(level)
+	dl := &DubboLogger{Logger: zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1)).Sugar(), DynamicLevel: level}
+	SetLogger(dl)
+	InfoKV("global")
+	dl.InfoKV("method")
+	Info("plain")
+
+	entries := logs.AllUntimed()
+	assert.Equal(t, 3, len(entries))
+	for _, e := range entries {
+		assert.True(t, strings.HasSuffix(e.Caller.File, "structured_test.go"), e.Caller.String())
+	}
+}
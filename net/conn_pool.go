@@ -0,0 +1,322 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxnet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+const defaultConnPoolMaxIdle = 2
+
+// ErrConnPoolClosed is returned by ConnPool.Get if the pool is closed
+var ErrConnPoolClosed = perrors.New("conn pool: closed")
+
+// Dialer dials a connection to @addr
+type Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+// ConnPoolOption configures a ConnPool
+type ConnPoolOption func(*connPoolOptions)
+
+type connPoolOptions struct {
+	maxIdle     int
+	maxActive   int
+	idleTimeout time.Duration
+	dial        Dialer
+}
+
+// WithConnPoolMaxIdle keeps @n idle connections at most for each address, it is 2 by default
+func WithConnPoolMaxIdle(n int) ConnPoolOption {
+	return func(o *connPoolOptions) {
+		o.maxIdle = n
+	}
+}
+
+// WithConnPoolMaxActive limits the number of the idle and borrowed connections
+// of each address to @n, Get waits if the limit is reached. 0 means no limit.
+func WithConnPoolMaxActive(n int) ConnPoolOption {
+	return func(o *connPoolOptions) {
+		o.maxActive = n
+	}
+}
+
+// WithConnPoolIdleTimeout closes the connections idle longer than @d. 0 means no limit.
+func WithConnPoolIdleTimeout(d time.Duration) ConnPoolOption {
+	return func(o *connPoolOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// WithConnPoolDialer dials the connections by @dial, the default one dials by tcp
+func WithConnPoolDialer(dial Dialer) ConnPoolOption {
+	return func(o *connPoolOptions) {
+		o.dial = dial
+	}
+}
+
+// ConnPoolStat is the number of the connections of an address
+type ConnPoolStat struct {
+	Idle   int
+	Active int // the number of the idle and borrowed connections
+}
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+type addrConnPool struct {
+	idle   []idleConn // the last one is the most recently returned
+	active int
+	// changed is closed and renewed to wake up the waiters of Get once a
+	// connection is returned or closed
+	changed chan struct{}
+}
+
+// notify should be called with the lock of ConnPool held
+func (ap *addrConnPool) notify() {
+	close(ap.changed)
+	ap.changed = make(chan struct{})
+}
+
+// ConnPool is a client-side pool of net.Conn keyed by the address. The idle
+// connections are checked by ConnCheck before being handed out.
+type ConnPool struct {
+	opts connPoolOptions
+
+	lock   sync.Mutex
+	pools  map[string]*addrConnPool
+	closed bool
+}
+
+// NewConnPool creates a ConnPool
+func NewConnPool(opts ...ConnPoolOption) *ConnPool {
+	options := connPoolOptions{maxIdle: defaultConnPoolMaxIdle}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.dial == nil {
+		dialer := &net.Dialer{}
+		options.dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}
+	}
+	return &ConnPool{opts: options, pools: make(map[string]*addrConnPool)}
+}
+
+// addrPool should be called with the lock held
+func (p *ConnPool) addrPool(addr string) *addrConnPool {
+	ap, ok := p.pools[addr]
+	if !ok {
+		ap = &addrConnPool{changed: make(chan struct{})}
+		p.pools[addr] = ap
+	}
+	return ap
+}
+
+// Get returns a connection to @addr, which is an idle one passing ConnCheck
+// or a new one. It waits for a connection to be returned until @ctx is done
+// if the max active is reached. Close the returned connection to put it back.
+func (p *ConnPool) Get(ctx context.Context, addr string) (*PooledConn, error) {
+	p.lock.Lock()
+	for {
+		if p.closed {
+			p.lock.Unlock()
+			return nil, ErrConnPoolClosed
+		}
+
+		ap := p.addrPool(addr)
+		expired := p.removeExpired(ap)
+		if n := len(ap.idle); n > 0 {
+			ic := ap.idle[n-1]
+			ap.idle[n-1] = idleConn{}
+			ap.idle = ap.idle[:n-1]
+			p.lock.Unlock()
+			closeConns(expired)
+
+			if err := ConnCheck(ic.conn); err == nil {
+				return &PooledConn{Conn: ic.conn, pool: p, addr: addr}, nil
+			}
+			_ = ic.conn.Close()
+			p.lock.Lock()
+			p.release(addr)
+			continue
+		}
+
+		if p.opts.maxActive <= 0 || ap.active < p.opts.maxActive {
+			ap.active++
+			p.lock.Unlock()
+			closeConns(expired)
+
+			conn, err := p.opts.dial(ctx, addr)
+			if err != nil {
+				p.lock.Lock()
+				p.release(addr)
+				p.lock.Unlock()
+				return nil, perrors.WithMessagef(err, "dial %s", addr)
+			}
+			return &PooledConn{Conn: conn, pool: p, addr: addr}, nil
+		}
+
+		changed := ap.changed
+		p.lock.Unlock()
+		closeConns(expired)
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		p.lock.Lock()
+	}
+}
+
+// removeExpired removes the connections idle longer than the idle timeout,
+// it should be called with the lock held
+func (p *ConnPool) removeExpired(ap *addrConnPool) []net.Conn {
+	if p.opts.idleTimeout <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	i := 0
+	for i < len(ap.idle) && now.Sub(ap.idle[i].since) >= p.opts.idleTimeout {
+		i++
+	}
+	if i == 0 {
+		return nil
+	}
+	expired := make([]net.Conn, 0, i)
+	for j := 0; j < i; j++ {
+		expired = append(expired, ap.idle[j].conn)
+	}
+	ap.idle = append(ap.idle[:0], ap.idle[i:]...)
+	ap.active -= i
+	ap.notify()
+	return expired
+}
+
+// release frees the slot of a closed connection to @addr, it should be
+// called with the lock held
+func (p *ConnPool) release(addr string) {
+	ap := p.addrPool(addr)
+	ap.active--
+	ap.notify()
+	if ap.active <= 0 && len(ap.idle) == 0 {
+		delete(p.pools, addr)
+	}
+}
+
+func (p *ConnPool) put(c *PooledConn, unusable bool) error {
+	// the deadlines set by the previous user should not affect the next one
+	if !unusable && c.Conn.SetDeadline(time.Time{}) != nil {
+		unusable = true
+	}
+
+	p.lock.Lock()
+	ap := p.addrPool(c.addr)
+	if unusable || p.closed || len(ap.idle) >= p.opts.maxIdle {
+		p.release(c.addr)
+		p.lock.Unlock()
+		return c.Conn.Close()
+	}
+	ap.idle = append(ap.idle, idleConn{conn: c.Conn, since: time.Now()})
+	ap.notify()
+	p.lock.Unlock()
+	return nil
+}
+
+// Stat returns the number of the connections to @addr
+func (p *ConnPool) Stat(addr string) ConnPoolStat {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	ap, ok := p.pools[addr]
+	if !ok {
+		return ConnPoolStat{}
+	}
+	return ConnPoolStat{Idle: len(ap.idle), Active: ap.active}
+}
+
+// Close closes the idle connections and wakes up the waiters of Get, the
+// borrowed connections are closed once they are put back.
+func (p *ConnPool) Close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	var conns []net.Conn
+	for _, ap := range p.pools {
+		for _, ic := range ap.idle {
+			conns = append(conns, ic.conn)
+		}
+		ap.active -= len(ap.idle)
+		ap.idle = nil
+		ap.notify()
+	}
+	p.lock.Unlock()
+
+	closeConns(conns)
+}
+
+func closeConns(conns []net.Conn) {
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// PooledConn is a connection borrowed from ConnPool, Close puts it back to
+// the pool instead of closing it.
+type PooledConn struct {
+	net.Conn
+	pool *ConnPool
+	addr string
+
+	lock     sync.Mutex
+	unusable bool
+	closed   bool
+}
+
+// MarkUnusable makes Close close the connection, eg: after an io error
+func (c *PooledConn) MarkUnusable() {
+	c.lock.Lock()
+	c.unusable = true
+	c.lock.Unlock()
+}
+
+// Close puts the connection back to the pool, or closes it if it is marked unusable
+func (c *PooledConn) Close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	unusable := c.unusable
+	c.lock.Unlock()
+
+	return c.pool.put(c, unusable)
+}
@@ -0,0 +1,225 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+// echoServer echoes the data of the accepted connections, which can be
+// closed by closeConns
+type echoServer struct {
+	listener *net.TCPListener
+	lock     sync.Mutex
+	conns    []net.Conn
+}
+
+func newEchoServer(t *testing.T) *echoServer {
+	l, err := ListenOnTCPRandomPort("127.0.0.1")
+	assert.Nil(t, err)
+	s := &echoServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.conns = append(s.conns, conn)
+			s.lock.Unlock()
+			go func() {
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *echoServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *echoServer) accepted() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.conns)
+}
+
+func (s *echoServer) closeConns() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *echoServer) close() {
+	_ = s.listener.Close()
+	s.closeConns()
+}
+
+func echo(t *testing.T, conn net.Conn, msg string) {
+	_, err := conn.Write([]byte(msg))
+	assert.Nil(t, err)
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(conn, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, string(buf))
+}
+
+func TestConnPool(t *testing.T) {
+	s := newEchoServer(t)
+	defer s.close()
+	p := NewConnPool(WithConnPoolMaxIdle(1))
+	defer p.Close()
+
+	c1, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	echo(t, c1, "hello")
+	c2, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	assert.Equal(t, ConnPoolStat{Active: 2}, p.Stat(s.addr()))
+
+	assert.Nil(t, c1.Close())
+	// the idle connections reach the max
+	assert.Nil(t, c2.Close())
+	assert.Nil(t, c2.Close())
+	assert.Equal(t, ConnPoolStat{Idle: 1, Active: 1}, p.Stat(s.addr()))
+
+	// the idle connection is reused
+	c3, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	assert.Equal(t, c1.Conn, c3.Conn)
+	echo(t, c3, "world")
+	assert.Equal(t, 2, s.accepted())
+
+	c3.MarkUnusable()
+	assert.Nil(t, c3.Close())
+	assert.Equal(t, ConnPoolStat{}, p.Stat(s.addr()))
+
+	_, err = p.Get(context.Background(), "127.0.0.1:0")
+	assert.NotNil(t, err)
+	assert.Equal(t, ConnPoolStat{}, p.Stat("127.0.0.1:0"))
+}
+
+func TestConnPoolCheck(t *testing.T) {
+	s := newEchoServer(t)
+	defer s.close()
+	p := NewConnPool()
+	defer p.Close()
+
+	c1, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	echo(t, c1, "hello")
+	assert.Nil(t, c1.Close())
+
+	// the idle connection closed by the server is dropped by ConnCheck
+	s.closeConns()
+	time.Sleep(20 * time.Millisecond)
+	c2, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	assert.NotEqual(t, c1.Conn, c2.Conn)
+	echo(t, c2, "world")
+	assert.Equal(t, ConnPoolStat{Active: 1}, p.Stat(s.addr()))
+	assert.Nil(t, c2.Close())
+}
+
+func TestConnPoolIdleTimeout(t *testing.T) {
+	s := newEchoServer(t)
+	defer s.close()
+	p := NewConnPool(WithConnPoolIdleTimeout(20 * time.Millisecond))
+	defer p.Close()
+
+	c1, _ := p.Get(context.Background(), s.addr())
+	c2, _ := p.Get(context.Background(), s.addr())
+	assert.Nil(t, c1.Close())
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, c2.Close())
+
+	// c1 is expired and c2 is reused
+	c3, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	assert.Equal(t, c2.Conn, c3.Conn)
+	assert.Equal(t, ConnPoolStat{Active: 1}, p.Stat(s.addr()))
+	assert.Nil(t, c3.Close())
+}
+
+func TestConnPoolMaxActive(t *testing.T) {
+	s := newEchoServer(t)
+	defer s.close()
+	p := NewConnPool(WithConnPoolMaxActive(1))
+
+	c1, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Get(ctx, s.addr())
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the waiter gets the returned connection
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = c1.Close()
+	}()
+	c2, err := p.Get(context.Background(), s.addr())
+	assert.Nil(t, err)
+	assert.Equal(t, c1.Conn, c2.Conn)
+
+	// Close wakes up the waiters and closes the connections put back
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Close()
+	}()
+	_, err = p.Get(context.Background(), s.addr())
+	assert.Equal(t, ErrConnPoolClosed, err)
+	assert.Nil(t, c2.Close())
+	assert.Equal(t, ConnPoolStat{}, p.Stat(s.addr()))
+}
+
+func TestConnPoolDialer(t *testing.T) {
+	var dialed []string
+	p := NewConnPool(WithConnPoolDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		client, server := net.Pipe()
+		go func() {
+			_, _ = io.Copy(server, server)
+		}()
+		return client, nil
+	}))
+	defer p.Close()
+
+	c, err := p.Get(context.Background(), "a:1")
+	assert.Nil(t, err)
+	echo(t, c, "pipe")
+	assert.Nil(t, c.Close())
+	_, err = p.Get(context.Background(), "b:1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a:1", "b:1"}, dialed)
+	assert.Equal(t, ConnPoolStat{Idle: 1, Active: 1}, p.Stat("a:1"))
+}
@@ -0,0 +1,329 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxnet
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// IPPatternError is the syntax error of an ip pattern
+type IPPatternError struct {
+	Index   int // the index of the pattern in the list
+	Pattern string
+	Reason  string
+}
+
+func (e *IPPatternError) Error() string {
+	return fmt.Sprintf("gost/net: invalid ip pattern #%d %q: %s", e.Index, e.Pattern, e.Reason)
+}
+
+// IPRule is the pattern matched by IPMatcher
+type IPRule struct {
+	Index   int // the index of the pattern in the list
+	Pattern string
+}
+
+type ipRule struct {
+	pattern string
+	port    string // empty means any port
+}
+
+// ipTrieNode is a node of the binary radix trie of the ip bits, the rules of
+// a node are the CIDRs whose prefix ends at it
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	rules    []int
+}
+
+func (n *ipTrieNode) insert(ip net.IP, ones int, rule int) {
+	node := n
+	for i := 0; i < ones; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &ipTrieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.rules = append(node.rules, rule)
+}
+
+// walk calls @fn with the rules of all the prefixes of @ip
+func (n *ipTrieNode) walk(ip net.IP, fn func(rule int)) {
+	node := n
+	for i := 0; node != nil; i++ {
+		for _, rule := range node.rules {
+			fn(rule)
+		}
+		if i == len(ip)*8 {
+			return
+		}
+		node = node.children[ip[i/8]>>(7-uint(i%8))&1]
+	}
+}
+
+// ipRangeRule is a pattern with wildcard or range segments, like
+// "192.168.1-10.*", each segment is a [min, max] range
+type ipRangeRule struct {
+	rule     int
+	segments [][2]uint16
+}
+
+func (r *ipRangeRule) match(segments []uint16) bool {
+	for i, seg := range segments {
+		if seg < r.segments[i][0] || seg > r.segments[i][1] {
+			return false
+		}
+	}
+	return true
+}
+
+// IPMatcher is the compiled form of the patterns of MatchIP, which are CIDRs
+// like "192.168.0.0/16", ips with optional ports like "192.168.0.1:8080" and
+// "[1fff::1]:8080", wildcards like "*" and ranges like "192.168.1-10.*".
+// The CIDRs and ips are matched by radix tries and the others by the range
+// tables, so it does not parse the patterns on every call.
+type IPMatcher struct {
+	rules    []ipRule
+	any      []int
+	v4, v6   *ipTrieNode
+	v4Ranges []*ipRangeRule
+	v6Ranges []*ipRangeRule
+}
+
+// CompileIPPatterns compiles @patterns into an IPMatcher, it returns an
+// *IPPatternError for the first invalid pattern.
+func CompileIPPatterns(patterns []string) (*IPMatcher, error) {
+	m := &IPMatcher{
+		rules: make([]ipRule, 0, len(patterns)),
+		v4:    &ipTrieNode{},
+		v6:    &ipTrieNode{},
+	}
+	for i, pattern := range patterns {
+		if reason := m.add(i, strings.TrimSpace(pattern)); reason != "" {
+			return nil, &IPPatternError{Index: i, Pattern: pattern, Reason: reason}
+		}
+	}
+	return m, nil
+}
+
+// add compiles the @index pattern, it returns the reason if it is invalid
+func (m *IPMatcher) add(index int, pattern string) string {
+	m.rules = append(m.rules, ipRule{pattern: pattern})
+	rule := &m.rules[index]
+
+	switch {
+	case pattern == "":
+		return "empty pattern"
+
+	case pattern == "*" || pattern == "*.*.*.*":
+		m.any = append(m.any, index)
+		return ""
+
+	case strings.Contains(pattern, "/"):
+		// the port is not allowed in the subnet like MatchIP
+		_, subnet, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return "invalid subnet"
+		}
+		ones, bits := subnet.Mask.Size()
+		if ip4 := subnet.IP.To4(); ip4 != nil {
+			// the ipv4-mapped subnet like ::ffff:0:0/96 covers the ipv4 hosts like net.IPNet
+			m.v4.insert(ip4, ones-(bits-8*net.IPv4len), index)
+		} else {
+			m.v6.insert(subnet.IP.To16(), ones, index)
+		}
+		return ""
+	}
+
+	host, port, reason := splitIPPattern(pattern)
+	if reason != "" {
+		return reason
+	}
+	rule.port = port
+
+	if ipPatternContains(host) {
+		if strings.Contains(host, ":") {
+			segments, reason := parseIPRangeSegments(host, Ipv6SplitCharacter, 8, 16, 0xffff)
+			if reason != "" {
+				return reason
+			}
+			m.v6Ranges = append(m.v6Ranges, &ipRangeRule{rule: index, segments: segments})
+			return ""
+		}
+		segments, reason := parseIPRangeSegments(host, Ipv4SplitCharacter, 4, 10, 0xff)
+		if reason != "" {
+			return reason
+		}
+		m.v4Ranges = append(m.v4Ranges, &ipRangeRule{rule: index, segments: segments})
+		return ""
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "invalid ip " + host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		m.v4.insert(ip4, net.IPv4len*8, index)
+	} else {
+		m.v6.insert(ip, net.IPv6len*8, index)
+	}
+	return ""
+}
+
+// splitIPPattern splits the pattern into the host and the optional port,
+// like "1.2.3.4:80" and "[1fff::1]:80"
+func splitIPPattern(pattern string) (host, port, reason string) {
+	hasPort := false
+	switch {
+	case strings.HasPrefix(pattern, "["):
+		end := strings.Index(pattern, "]")
+		if end < 0 {
+			return "", "", "missing ']'"
+		}
+		host = pattern[1:end]
+		rest := pattern[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return "", "", "unexpected " + rest + " after ']'"
+			}
+			port, hasPort = rest[1:], true
+		}
+	case strings.Count(pattern, ":") == 1:
+		end := strings.Index(pattern, ":")
+		host, port, hasPort = pattern[:end], pattern[end+1:], true
+	default:
+		host = pattern
+	}
+
+	if hasPort {
+		if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+			return "", "", "invalid port " + port
+		}
+	}
+	return host, port, ""
+}
+
+// parseIPRangeSegments parses the @num segments of @host split by @sep, each
+// segment is "*", a number or a range like "1-5"
+func parseIPRangeSegments(host, sep string, num, base int, max uint64) ([][2]uint16, string) {
+	parts := strings.Split(host, sep)
+	if len(parts) != num {
+		return nil, fmt.Sprintf("the pattern with '*' or '-' should have %d segments", num)
+	}
+
+	segments := make([][2]uint16, num)
+	for i, part := range parts {
+		if part == "*" {
+			segments[i] = [2]uint16{0, uint16(max)}
+			continue
+		}
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return nil, "invalid range " + part
+		}
+		var values [2]uint16
+		for j, bound := range bounds {
+			v, err := strconv.ParseUint(bound, base, 16)
+			if err != nil || v > max {
+				return nil, "invalid segment " + part
+			}
+			values[j] = uint16(v)
+		}
+		if len(bounds) == 1 {
+			values[1] = values[0]
+		}
+		if values[0] > values[1] {
+			return nil, "invalid range " + part
+		}
+		segments[i] = values
+	}
+	return segments, ""
+}
+
+// Len returns the number of the patterns
+func (m *IPMatcher) Len() int {
+	return len(m.rules)
+}
+
+// Match returns the first pattern in the list matching @host:@port, the port
+// is ignored if the pattern has no port.
+func (m *IPMatcher) Match(host, port string) (IPRule, bool) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return IPRule{}, false
+	}
+	return m.MatchIP(ip, port)
+}
+
+// MatchIP is Match with the parsed ip
+func (m *IPMatcher) MatchIP(ip net.IP, port string) (IPRule, bool) {
+	best := -1
+	consider := func(index int) {
+		if best >= 0 && index >= best {
+			return
+		}
+		if p := m.rules[index].port; p != "" && p != port {
+			return
+		}
+		best = index
+	}
+
+	if len(m.any) > 0 {
+		consider(m.any[0])
+	}
+
+	var (
+		segments []uint16
+		ranges   []*ipRangeRule
+	)
+	if ip4 := ip.To4(); ip4 != nil {
+		m.v4.walk(ip4, consider)
+		segments = make([]uint16, net.IPv4len)
+		for i, b := range ip4 {
+			segments[i] = uint16(b)
+		}
+		ranges = m.v4Ranges
+	} else if ip16 := ip.To16(); ip16 != nil {
+		m.v6.walk(ip16, consider)
+		segments = make([]uint16, net.IPv6len/2)
+		for i := range segments {
+			segments[i] = uint16(ip16[2*i])<<8 | uint16(ip16[2*i+1])
+		}
+		ranges = m.v6Ranges
+	} else {
+		return IPRule{}, false
+	}
+
+	// the ranges are in the order of the list
+	for _, r := range ranges {
+		if best >= 0 && r.rule >= best {
+			break
+		}
+		if r.match(segments) {
+			consider(r.rule)
+		}
+	}
+
+	if best < 0 {
+		return IPRule{}, false
+	}
+	return IPRule{Index: best, Pattern: m.rules[best].pattern}, true
+}
@@ -0,0 +1,217 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxnet
+
+import (
+	"fmt"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPMatcherIpv4(t *testing.T) {
+	m, err := CompileIPPatterns([]string{
+		"192.168.0.1:8080",
+		"206.0.68.0/23",
+		"206.0.68.100",
+		"10.*.68-69.0",
+		" 172.16.0.0/12 ",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, m.Len())
+
+	cases := []struct {
+		host, port string
+		index      int
+	}{
+		{"192.168.0.1", "8080", 0},
+		{"192.168.0.1", "8081", -1},
+		{"206.0.68.123", "", 1},
+		{"206.0.69.255", "8080", 1},
+		{"207.0.69.123", "8080", -1},
+		// the first pattern in the list wins
+		{"206.0.68.100", "8080", 1},
+		{"10.1.68.0", "80", 3},
+		{"10.255.69.0", "80", 3},
+		{"10.1.70.0", "80", -1},
+		{"172.31.1.1", "", 4},
+		{"::ffff:172.16.0.1", "", 4},
+		{"", "", -1},
+		{"localhost", "", -1},
+	}
+	for _, c := range cases {
+		rule, ok := m.Match(c.host, c.port)
+		if c.index < 0 {
+			assert.False(t, ok, c.host)
+			continue
+		}
+		assert.True(t, ok, c.host)
+		assert.Equal(t, c.index, rule.Index, c.host)
+	}
+
+	rule, ok := m.Match("10.0.68.0", "")
+	assert.True(t, ok)
+	assert.Equal(t, IPRule{Index: 3, Pattern: "10.*.68-69.0"}, rule)
+	rule, _ = m.Match("172.16.0.1", "")
+	assert.Equal(t, "172.16.0.0/12", rule.Pattern)
+}
+
+func TestIPMatcherIpv6(t *testing.T) {
+	m, err := CompileIPPatterns([]string{
+		"[1fff:0:a88:85a3::ac1f]:8080",
+		"1fff:0:a88:85a3::ac1f/64",
+		"234e:0:4567:0:0:0:3d:*",
+		"[234e:0:4567:0:0:0:4d:1-a]:80",
+		"2001:db8::1",
+	})
+	assert.Nil(t, err)
+
+	cases := []struct {
+		host, port string
+		index      int
+	}{
+		{"1fff:0:a88:85a3::ac1f", "8080", 0},
+		{"1fff:0:a88:85a3::ac1f", "8081", 1},
+		{"1fff:0000:0a88:85a3:0000:0000:0000:0000", "", 1},
+		{"2fff:0000:0a88:85a3:0000:0000:0000:0000", "", -1},
+		{"234e:0:4567:0:0:0:3d:4", "", 2},
+		{"234e:0:4567::3d:ffff", "", 2},
+		{"234e:0:4567:0:0:0:2d:4", "", -1},
+		{"234e:0:4567:0:0:0:4d:a", "80", 3},
+		{"234e:0:4567:0:0:0:4d:b", "80", -1},
+		{"234e:0:4567:0:0:0:4d:a", "81", -1},
+		{"2001:0db8:0:0:0:0:0:1", "", 4},
+		// the ipv4 patterns do not match the ipv6 hosts
+		{"::1", "", -1},
+	}
+	for _, c := range cases {
+		rule, ok := m.Match(c.host, c.port)
+		if c.index < 0 {
+			assert.False(t, ok, c.host)
+			continue
+		}
+		assert.True(t, ok, c.host)
+		assert.Equal(t, c.index, rule.Index, c.host)
+	}
+}
+
+func TestIPMatcherIpv4Mapped(t *testing.T) {
+	m, err := CompileIPPatterns([]string{"::ffff:10.0.0.0/104", "::ffff:0:0/96"})
+	assert.Nil(t, err)
+
+	cases := []struct {
+		host  string
+		index int
+	}{
+		{"10.1.2.3", 0},
+		{"::ffff:10.1.2.3", 0},
+		{"192.168.0.1", 1},
+		{"::ffff:192.168.0.1", 1},
+		{"2001:db8::1", -1},
+	}
+	for _, c := range cases {
+		rule, ok := m.Match(c.host, "")
+		if c.index < 0 {
+			assert.False(t, ok, c.host)
+			continue
+		}
+		assert.True(t, ok, c.host)
+		assert.Equal(t, c.index, rule.Index, c.host)
+	}
+}
+
+func TestIPMatcherAny(t *testing.T) {
+	m, err := CompileIPPatterns([]string{"192.168.0.1", "*.*.*.*", "*"})
+	assert.Nil(t, err)
+	rule, ok := m.Match("192.168.0.1", "")
+	assert.True(t, ok)
+	assert.Equal(t, 0, rule.Index)
+	rule, ok = m.Match("1fff:0:a88:85a3::ac1f", "8080")
+	assert.True(t, ok)
+	assert.Equal(t, 1, rule.Index)
+
+	m, err = CompileIPPatterns(nil)
+	assert.Nil(t, err)
+	_, ok = m.Match("192.168.0.1", "")
+	assert.False(t, ok)
+}
+
+func TestIPMatcherCompileError(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"206.0.68.0/33",
+		"1fff::1/129",
+		"192.168.0.1:http",
+		"192.168.0.1:70000",
+		"192.168.0.1:",
+		"[1fff::1",
+		"[1fff::1]8080",
+		"192.168.*",
+		"192.168.1-.1",
+		"192.168.10-1.1",
+		"192.168.1-2-3.1",
+		"192.168.256.*",
+		"234e:0:4567::3d:*",
+		"234e:0:4567:0:0:0:3d:g-*",
+		"localhost",
+		"1fff::zz",
+	} {
+		_, err := CompileIPPatterns([]string{"10.0.0.0/8", pattern})
+		if !assert.NotNil(t, err, pattern) {
+			continue
+		}
+		perr, ok := err.(*IPPatternError)
+		assert.True(t, ok)
+		assert.Equal(t, 1, perr.Index)
+		assert.Equal(t, pattern, perr.Pattern)
+		assert.Contains(t, err.Error(), fmt.Sprintf("#1 %q", pattern))
+	}
+
+	_, err := CompileIPPatterns([]string{"1fff::", "[1fff::]:0", "0.0.0.0/0", "::/0"})
+	assert.Nil(t, err)
+}
+
+func BenchmarkIPMatcher(b *testing.B) {
+	patterns := make([]string, 0, 3000)
+	for i := 0; i < 1000; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("10.%d.%d.0/24", i/256, i%256),
+			fmt.Sprintf("172.16.%d.%d:8080", i/256, i%256),
+			fmt.Sprintf("192.%d.*.1-10", i%256),
+		)
+	}
+	m, err := CompileIPPatterns(patterns)
+	assert.Nil(b, err)
+
+	b.Run("Compiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			m.Match("192.255.3.5", "8080")
+		}
+	})
+	b.Run("MatchIP", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, pattern := range patterns {
+				if MatchIP(pattern, "192.255.3.5", "8080") {
+					break
+				}
+			}
+		}
+	})
+}
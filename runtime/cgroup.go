@@ -0,0 +1,343 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	gxfilepath "github.com/dubbogo/gost/path/filepath"
+)
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+
+	// the v1 memory limit is a page aligned number close to math.MaxInt64 if there is no limit
+	cgroupV1MemoryUnlimited = uint64(1) << 62
+	cgroupMax               = "max"
+)
+
+var (
+	// ErrCgroupNotFound there is no cgroup hierarchy
+	ErrCgroupNotFound = perrors.New("cgroup not found")
+)
+
+// CgroupVersion is the version of the cgroup hierarchy
+type CgroupVersion int
+
+const (
+	// CgroupNone there is no cgroup hierarchy
+	CgroupNone CgroupVersion = iota
+	// CgroupV1 the legacy hierarchy with a directory per subsystem
+	CgroupV1
+	// CgroupV2 the unified hierarchy
+	CgroupV2
+)
+
+func (v CgroupVersion) String() string {
+	switch v {
+	case CgroupV1:
+		return "v1"
+	case CgroupV2:
+		return "v2"
+	default:
+		return "none"
+	}
+}
+
+// CPUStat is the cpu usage and throttling stats of the cgroup
+type CPUStat struct {
+	// Usage is the cpu time consumed by the cgroup
+	Usage time.Duration
+	// NrPeriods is the number of the enforcement periods
+	NrPeriods uint64
+	// NrThrottled is the number of the periods in which the cgroup is throttled
+	NrThrottled uint64
+	// ThrottledTime is the total time the cgroup is throttled
+	ThrottledTime time.Duration
+}
+
+// ThrottledRatio returns the ratio of the throttled periods
+func (s CPUStat) ThrottledRatio() float64 {
+	if s.NrPeriods == 0 {
+		return 0
+	}
+	return float64(s.NrThrottled) / float64(s.NrPeriods)
+}
+
+// Cgroup reads the resource files of a cgroup hierarchy
+type Cgroup struct {
+	version CgroupVersion
+	root    string
+	dir     string            // the cgroup directory of v2
+	dirs    map[string]string // subsystem -> the cgroup directory of v1
+}
+
+// NewCgroup returns the Cgroup of the hierarchy mounted at @root, the
+// version is detected by the files in it.
+func NewCgroup(root string) *Cgroup {
+	c := &Cgroup{root: root, dir: root, dirs: make(map[string]string)}
+	if exists(filepath.Join(root, "cgroup.controllers")) {
+		c.version = CgroupV2
+		return c
+	}
+	for _, subsystem := range []string{"memory", "cpu", "cpuacct"} {
+		for _, name := range []string{subsystem, "cpu,cpuacct", "cpuacct,cpu"} {
+			if subsystem == "memory" && name != subsystem {
+				break
+			}
+			if dir := filepath.Join(root, name); exists(dir) {
+				c.dirs[subsystem] = dir
+				c.version = CgroupV1
+				break
+			}
+		}
+	}
+	return c
+}
+
+// DefaultCgroup returns the Cgroup of the current process in /sys/fs/cgroup
+func DefaultCgroup() *Cgroup {
+	return newProcessCgroup(defaultCgroupRoot, _cgroupPath)
+}
+
+// newProcessCgroup returns the Cgroup in @root of the process whose cgroup
+// file is @procCgroup, like /proc/self/cgroup. The cgroup path of the process
+// is ignored if it is absent in @root, which is the case in a container.
+func newProcessCgroup(root, procCgroup string) *Cgroup {
+	c := NewCgroup(root)
+	for _, line := range readLinesFromFile(procCgroup) {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 || fields[2] == "/" {
+			continue
+		}
+		switch c.version {
+		case CgroupV2:
+			if fields[0] == "0" && fields[1] == "" {
+				if dir := filepath.Join(root, fields[2]); exists(dir) {
+					c.dir = dir
+				}
+			}
+		case CgroupV1:
+			for _, subsystem := range strings.Split(fields[1], ",") {
+				if base, ok := c.dirs[subsystem]; ok {
+					if dir := filepath.Join(base, fields[2]); exists(dir) {
+						c.dirs[subsystem] = dir
+					}
+				}
+			}
+		}
+	}
+	return c
+}
+
+func exists(path string) bool {
+	ok, _ := gxfilepath.Exists(path)
+	return ok
+}
+
+// Version returns the version of the hierarchy
+func (c *Cgroup) Version() CgroupVersion {
+	return c.version
+}
+
+func (c *Cgroup) file(subsystem, name string) string {
+	if c.version == CgroupV2 {
+		return filepath.Join(c.dir, name)
+	}
+	if dir, ok := c.dirs[subsystem]; ok {
+		return filepath.Join(dir, name)
+	}
+	return filepath.Join(c.root, subsystem, name)
+}
+
+// MemoryLimit returns the memory limit in bytes, it is math.MaxUint64 if there is no limit
+func (c *Cgroup) MemoryLimit() (uint64, error) {
+	switch c.version {
+	case CgroupV2:
+		return readMax(c.file("memory", "memory.max"))
+	case CgroupV1:
+		limit, err := readUint(c.file("memory", "memory.limit_in_bytes"))
+		if err != nil {
+			return 0, err
+		}
+		if limit >= cgroupV1MemoryUnlimited {
+			return math.MaxUint64, nil
+		}
+		return limit, nil
+	default:
+		return 0, ErrCgroupNotFound
+	}
+}
+
+// MemoryUsage returns the current memory usage in bytes
+func (c *Cgroup) MemoryUsage() (uint64, error) {
+	switch c.version {
+	case CgroupV2:
+		return readUint(c.file("memory", "memory.current"))
+	case CgroupV1:
+		return readUint(c.file("memory", "memory.usage_in_bytes"))
+	default:
+		return 0, ErrCgroupNotFound
+	}
+}
+
+// CPUQuota returns the cpu quota in cores, @limited is false if there is no quota
+func (c *Cgroup) CPUQuota() (cpus float64, limited bool, err error) {
+	var quota, period uint64
+	switch c.version {
+	case CgroupV2:
+		// $MAX $PERIOD
+		content, err := os.ReadFile(c.file("cpu", "cpu.max"))
+		if err != nil {
+			return 0, false, err
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) == 0 || len(fields) > 2 {
+			return 0, false, perrors.Errorf("invalid cpu.max %q", content)
+		}
+		if fields[0] == cgroupMax {
+			return 0, false, nil
+		}
+		if quota, err = parseUint(fields[0], 10, 64); err != nil {
+			return 0, false, err
+		}
+		period = 100000 // the default period in microseconds
+		if len(fields) == 2 {
+			if period, err = parseUint(fields[1], 10, 64); err != nil {
+				return 0, false, err
+			}
+		}
+	case CgroupV1:
+		// the negative quota, which is -1, is read as 0
+		if quota, err = readUint(c.file("cpu", "cpu.cfs_quota_us")); err != nil {
+			return 0, false, err
+		}
+		if period, err = readUint(c.file("cpu", "cpu.cfs_period_us")); err != nil {
+			return 0, false, err
+		}
+	default:
+		return 0, false, ErrCgroupNotFound
+	}
+
+	if quota == 0 || period == 0 {
+		return 0, false, nil
+	}
+	return float64(quota) / float64(period), true, nil
+}
+
+// CPUStat returns the cpu usage and throttling stats
+func (c *Cgroup) CPUStat() (CPUStat, error) {
+	var stat CPUStat
+	switch c.version {
+	case CgroupV2:
+		kvs, err := readKeyValues(c.file("cpu", "cpu.stat"))
+		if err != nil {
+			return stat, err
+		}
+		stat.Usage = time.Duration(kvs["usage_usec"]) * time.Microsecond
+		stat.NrPeriods = kvs["nr_periods"]
+		stat.NrThrottled = kvs["nr_throttled"]
+		stat.ThrottledTime = time.Duration(kvs["throttled_usec"]) * time.Microsecond
+	case CgroupV1:
+		kvs, err := readKeyValues(c.file("cpu", "cpu.stat"))
+		if err != nil {
+			return stat, err
+		}
+		stat.NrPeriods = kvs["nr_periods"]
+		stat.NrThrottled = kvs["nr_throttled"]
+		stat.ThrottledTime = time.Duration(kvs["throttled_time"])
+		// cpuacct may be not mounted
+		if usage, err := readUint(c.file("cpuacct", "cpuacct.usage")); err == nil {
+			stat.Usage = time.Duration(usage)
+		}
+	default:
+		return stat, ErrCgroupNotFound
+	}
+	return stat, nil
+}
+
+// EffectiveGOMAXPROCS returns the GOMAXPROCS fitting the cpu quota, which is
+// the quota rounded down to at least 1 and at most runtime.NumCPU(). It is
+// runtime.NumCPU() if there is no quota.
+func (c *Cgroup) EffectiveGOMAXPROCS() int {
+	numCPU := runtime.NumCPU()
+	cpus, limited, err := c.CPUQuota()
+	if err != nil || !limited {
+		return numCPU
+	}
+	procs := int(math.Floor(cpus))
+	if procs < 1 {
+		procs = 1
+	}
+	if procs > numCPU {
+		procs = numCPU
+	}
+	return procs
+}
+
+// EffectiveGOMAXPROCS returns the GOMAXPROCS fitting the cpu quota of the current process
+func EffectiveGOMAXPROCS() int {
+	return DefaultCgroup().EffectiveGOMAXPROCS()
+}
+
+// readMax reads a number or "max" which is returned as math.MaxUint64
+func readMax(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(content))
+	if s == cgroupMax {
+		return math.MaxUint64, nil
+	}
+	return parseUint(s, 10, 64)
+}
+
+// readKeyValues reads the lines like "key value" of a stat file
+func readKeyValues(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, perrors.WithMessagef(err, "parse %s of %s", fields[0], path)
+		}
+		kvs[fields[0]] = v
+	}
+	return kvs, nil
+}
@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCgroupV1(t *testing.T) {
+	root := "testdata/cgroup/v1"
+	c := newProcessCgroup(root, filepath.Join(root, "proc_self_cgroup"))
+	assert.Equal(t, CgroupV1, c.Version())
+
+	limit, err := c.MemoryLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(512<<20), limit)
+	usage, err := c.MemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100<<20), usage)
+
+	cpus, limited, err := c.CPUQuota()
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 1.5, cpus)
+
+	stat, err := c.CPUStat()
+	assert.Nil(t, err)
+	assert.Equal(t, CPUStat{
+		Usage:         12 * time.Second,
+		NrPeriods:     200,
+		NrThrottled:   50,
+		ThrottledTime: 3 * time.Second,
+	}, stat)
+	assert.Equal(t, 0.25, stat.ThrottledRatio())
+
+	assert.Equal(t, 1, c.EffectiveGOMAXPROCS())
+}
+
+func TestCgroupV1Unlimited(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "memory"), 0o755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "cpu"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "memory", "memory.limit_in_bytes"), []byte("9223372036854771712\n"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"), []byte("-1\n"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"), []byte("100000\n"), 0o644))
+
+	c := NewCgroup(root)
+	assert.Equal(t, CgroupV1, c.Version())
+	limit, err := c.MemoryLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), limit)
+	_, limited, err := c.CPUQuota()
+	assert.Nil(t, err)
+	assert.False(t, limited)
+	assert.Equal(t, runtime.NumCPU(), c.EffectiveGOMAXPROCS())
+	// cpu.stat is absent
+	_, err = c.CPUStat()
+	assert.NotNil(t, err)
+}
+
+func TestCgroupV2(t *testing.T) {
+	root := "testdata/cgroup/v2"
+
+	// the root cgroup
+	c := NewCgroup(root)
+	assert.Equal(t, CgroupV2, c.Version())
+	limit, err := c.MemoryLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), limit)
+	_, limited, err := c.CPUQuota()
+	assert.Nil(t, err)
+	assert.False(t, limited)
+	stat, err := c.CPUStat()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Millisecond, stat.Usage)
+	assert.Equal(t, float64(0), stat.ThrottledRatio())
+
+	// the nested cgroup of the process
+	c = newProcessCgroup(root, filepath.Join(root, "proc_self_cgroup"))
+	assert.Equal(t, CgroupV2, c.Version())
+	limit, err = c.MemoryLimit()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1<<30), limit)
+	usage, err := c.MemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(200<<20), usage)
+
+	cpus, limited, err := c.CPUQuota()
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 0.5, cpus)
+	// at least 1
+	assert.Equal(t, 1, c.EffectiveGOMAXPROCS())
+
+	stat, err = c.CPUStat()
+	assert.Nil(t, err)
+	assert.Equal(t, CPUStat{
+		Usage:         2500 * time.Millisecond,
+		NrPeriods:     100,
+		NrThrottled:   25,
+		ThrottledTime: 1500 * time.Millisecond,
+	}, stat)
+}
+
+func TestCgroupV2CPUMax(t *testing.T) {
+	root := t.TempDir()
+	cpuMax := filepath.Join(root, "cpu.max")
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu\n"), 0o644))
+	c := NewCgroup(root)
+
+	assert.Nil(t, os.WriteFile(cpuMax, []byte("250000\n"), 0o644))
+	cpus, limited, err := c.CPUQuota()
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 2.5, cpus)
+
+	assert.Nil(t, os.WriteFile(cpuMax, []byte("\n"), 0o644))
+	_, _, err = c.CPUQuota()
+	assert.NotNil(t, err)
+
+	assert.Nil(t, os.WriteFile(cpuMax, []byte("abc 100000\n"), 0o644))
+	_, _, err = c.CPUQuota()
+	assert.NotNil(t, err)
+}
+
+func TestCgroupNone(t *testing.T) {
+	c := NewCgroup(t.TempDir())
+	assert.Equal(t, CgroupNone, c.Version())
+	assert.Equal(t, "none", c.Version().String())
+
+	_, err := c.MemoryLimit()
+	assert.Equal(t, ErrCgroupNotFound, err)
+	_, err = c.MemoryUsage()
+	assert.Equal(t, ErrCgroupNotFound, err)
+	_, _, err = c.CPUQuota()
+	assert.Equal(t, ErrCgroupNotFound, err)
+	_, err = c.CPUStat()
+	assert.Equal(t, ErrCgroupNotFound, err)
+	assert.Equal(t, runtime.NumCPU(), c.EffectiveGOMAXPROCS())
+}
@@ -18,19 +18,142 @@
 package gxruntime
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"runtime"
 	"runtime/debug"
+	"runtime/pprof"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// LabelName is the pprof label of the goroutine name
+	LabelName = "name"
+	// LabelOwner is the pprof label of the function which starts the goroutine
+	LabelOwner = "owner"
+	// LabelStartTime is the pprof label of the goroutine start time in RFC3339Nano
+	LabelStartTime = "start"
+)
+
+var (
+	goroutineSeq    uint64
+	goroutines      sync.Map // seq -> *GoroutineInfo
+	trackGoroutines int32
+)
+
+// EnableGoroutineTracking makes GoSafely and GoUnterminated label and track
+// their goroutines like GoNamed, by the name of the handler and its caller.
+// It is disabled by default, as looking up the names costs much more than
+// starting a goroutine.
+func EnableGoroutineTracking(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&trackGoroutines, v)
+}
+
+// GoroutineInfo describes a live goroutine started by GoNamed, or by GoSafely
+// if EnableGoroutineTracking is on
+type GoroutineInfo struct {
+	// Seq is the sequence number of the goroutine in this process, not the runtime goroutine id
+	Seq       uint64
+	Name      string
+	Owner     string
+	StartTime time.Time
+}
+
+// Age returns how long the goroutine has been running
+func (g GoroutineInfo) Age() time.Duration {
+	return time.Since(g.StartTime)
+}
+
+// Goroutines returns the live tracked goroutines, the oldest first
+func Goroutines() []GoroutineInfo {
+	var list []GoroutineInfo
+	goroutines.Range(func(_, v interface{}) bool {
+		list = append(list, *v.(*GoroutineInfo))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Seq < list[j].Seq
+	})
+	return list
+}
+
+// goroutineTag is the name and owner of a tracked goroutine, nil if it is not tracked
+type goroutineTag struct {
+	name  string
+	owner string
+}
+
+// handlerTag returns the tag of @handler started by the caller @skip frames
+// above the caller of handlerTag, or nil if the tracking is disabled
+func handlerTag(handler interface{}, skip int) *goroutineTag {
+	if atomic.LoadInt32(&trackGoroutines) == 0 {
+		return nil
+	}
+	return &goroutineTag{name: funcName(handler), owner: callerName(skip + 1)}
+}
+
+func funcName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
+
+// callerName returns the function name of the caller @skip frames above the caller of callerName
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	if f := runtime.FuncForPC(pc); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
+
 // GoSafely wraps a `go func()` with recover()
 func GoSafely(wg *sync.WaitGroup, ignoreRecover bool, handler func(), catchFunc func(r interface{})) {
+	goSafely(handlerTag(handler, 1), wg, ignoreRecover, handler, catchFunc)
+}
+
+// GoNamed is GoSafely with the pprof labels of @name, @owner and the start
+// time, and the goroutine is listed by Goroutines until it exits.
+func GoNamed(name, owner string, wg *sync.WaitGroup, ignoreRecover bool, handler func(), catchFunc func(r interface{})) {
+	goSafely(&goroutineTag{name: name, owner: owner}, wg, ignoreRecover, handler, catchFunc)
+}
+
+func goSafely(tag *goroutineTag, wg *sync.WaitGroup, ignoreRecover bool, handler func(), catchFunc func(r interface{})) {
+	var info *GoroutineInfo
+	if tag != nil {
+		info = &GoroutineInfo{
+			Seq:       atomic.AddUint64(&goroutineSeq, 1),
+			Name:      tag.name,
+			Owner:     tag.owner,
+			StartTime: time.Now(),
+		}
+		goroutines.Store(info.Seq, info)
+	}
 	if wg != nil {
 		wg.Add(1)
 	}
 	go func() {
+		if info != nil {
+			pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(
+				LabelName, info.Name,
+				LabelOwner, info.Owner,
+				LabelStartTime, info.StartTime.Format(time.RFC3339Nano),
+			)))
+			defer goroutines.Delete(info.Seq)
+		}
 		defer func() {
 			if r := recover(); r != nil {
 				if !ignoreRecover {
@@ -70,15 +193,21 @@ func GoSafely(wg *sync.WaitGroup, ignoreRecover bool, handler func(), catchFunc
 // @period: sleep time duration after panic to defeat @handle panic so frequently. if it is not positive,
 //
 //	the @handle will be invoked asap after panic.
+//
+// It can not be stopped, use Supervisor for the loop which should be stopped or backoff.
 func GoUnterminated(handle func(), wg *sync.WaitGroup, ignoreRecover bool, period time.Duration) {
-	GoSafely(wg,
+	goUnterminated(handlerTag(handle, 1), handle, wg, ignoreRecover, period)
+}
+
+func goUnterminated(tag *goroutineTag, handle func(), wg *sync.WaitGroup, ignoreRecover bool, period time.Duration) {
+	goSafely(tag, wg,
 		ignoreRecover,
 		handle,
 		func(r interface{}) {
 			if period > 0 {
 				time.Sleep(period)
 			}
-			GoUnterminated(handle, wg, ignoreRecover, period)
+			goUnterminated(tag, handle, wg, ignoreRecover, period)
 		},
 	)
 }
@@ -18,6 +18,8 @@
 package gxruntime
 
 import (
+	"bytes"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -84,3 +86,103 @@ func TestGoUnterminated(t *testing.T) {
 	time.Sleep(1e9)
 	assert.True(t, atomic.LoadUint64(&times) == 4)
 }
+
+func TestGoroutines(t *testing.T) {
+	defer CheckGoroutineLeaks(t)()
+	EnableGoroutineTracking(true)
+	defer EnableGoroutineTracking(false)
+
+	stop := make(chan struct{})
+	var wg, started sync.WaitGroup
+	started.Add(2)
+	GoNamed("first", "test", &wg, false, func() {
+		started.Done()
+		<-stop
+	}, nil)
+	GoSafely(&wg, false, func() {
+		started.Done()
+		<-stop
+	}, nil)
+	started.Wait()
+
+	var list []GoroutineInfo
+	for _, g := range Goroutines() {
+		if g.Owner == "test" || g.Owner == "github.com/dubbogo/gost/runtime.TestGoroutines" {
+			list = append(list, g)
+		}
+	}
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, "first", list[0].Name)
+	assert.Equal(t, "github.com/dubbogo/gost/runtime.TestGoroutines.func2", list[1].Name)
+	assert.True(t, list[0].Age() >= list[1].Age())
+
+	var buf bytes.Buffer
+	assert.Nil(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+	assert.Contains(t, buf.String(), `"name":"first"`)
+	assert.Contains(t, buf.String(), `"owner":"test"`)
+
+	close(stop)
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		for _, g := range Goroutines() {
+			if g.Seq == list[0].Seq || g.Seq == list[1].Seq {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func TestGoSafelyUntracked(t *testing.T) {
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	before := len(Goroutines())
+	GoSafely(&wg, false, func() {
+		<-stop
+	}, nil)
+	assert.Equal(t, before, len(Goroutines()))
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkGoSafely(b *testing.B) {
+	var wg sync.WaitGroup
+	handler := func() {}
+	b.Run("go", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			wg.Add(1)
+			go func() {
+				defer func() {
+					_ = recover()
+					wg.Done()
+				}()
+				handler()
+			}()
+		}
+		wg.Wait()
+	})
+	b.Run("GoSafely", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GoSafely(&wg, false, handler, nil)
+		}
+		wg.Wait()
+	})
+	b.Run("GoSafelyTracked", func(b *testing.B) {
+		EnableGoroutineTracking(true)
+		defer EnableGoroutineTracking(false)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GoSafely(&wg, false, handler, nil)
+		}
+		wg.Wait()
+	})
+	b.Run("GoNamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			GoNamed("bench", "bench", &wg, false, handler, nil)
+		}
+		wg.Wait()
+	})
+}
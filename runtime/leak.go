@@ -0,0 +1,181 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLeakTimeout = time.Second
+)
+
+// the goroutines of the test framework and the runtime, which are not leaks
+var defaultLeakIgnores = []string{
+	"testing.tRunner(",
+	"testing.(*T).Run(",
+	"testing.(*M).",
+	"testing.runTests(",
+	"testing.(*F).",
+	"os/signal.signal_recv(",
+	"os/signal.loop(",
+	"runtime.ensureSigM(",
+}
+
+// TestingT is the subset of testing.TB used by CheckGoroutineLeaks
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// LeakOption configures CheckGoroutineLeaks
+type LeakOption func(*leakOptions)
+
+type leakOptions struct {
+	timeout time.Duration
+	ignores []string
+}
+
+// WithLeakTimeout sets how long to wait for the goroutines to exit, it is 1s by default
+func WithLeakTimeout(timeout time.Duration) LeakOption {
+	return func(o *leakOptions) {
+		o.timeout = timeout
+	}
+}
+
+// IgnoreLeak ignores the goroutines whose stack contains @substr, eg: a
+// function name like "net/http.(*persistConn).readLoop"
+func IgnoreLeak(substr string) LeakOption {
+	return func(o *leakOptions) {
+		o.ignores = append(o.ignores, substr)
+	}
+}
+
+// CheckGoroutineLeaks snapshots the current goroutines, and the returned
+// function fails @t if the goroutines started after the snapshot are still
+// alive when it is called. It waits for them to exit for a while. Eg:
+//
+//	defer gxruntime.CheckGoroutineLeaks(t)()
+func CheckGoroutineLeaks(t TestingT, opts ...LeakOption) func() {
+	t.Helper()
+	o := &leakOptions{timeout: defaultLeakTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	before := make(map[int64]struct{})
+	for _, g := range goroutineStacks() {
+		before[g.id] = struct{}{}
+	}
+
+	return func() {
+		t.Helper()
+		var leaks []goroutineStack
+		deadline := time.Now().Add(o.timeout)
+		for {
+			leaks = leakedGoroutines(before, o.ignores)
+			if len(leaks) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(leaks) == 0 {
+			return
+		}
+		var b strings.Builder
+		for _, g := range leaks {
+			b.WriteString("\n")
+			b.WriteString(g.stack)
+		}
+		t.Errorf("found %d leaked goroutines:%s", len(leaks), b.String())
+	}
+}
+
+func leakedGoroutines(before map[int64]struct{}, ignores []string) []goroutineStack {
+	var leaks []goroutineStack
+	current := currentGoroutineID()
+	for _, g := range goroutineStacks() {
+		if _, ok := before[g.id]; ok || g.id == current {
+			continue
+		}
+		if containsAny(g.stack, defaultLeakIgnores) || containsAny(g.stack, ignores) {
+			continue
+		}
+		leaks = append(leaks, g)
+	}
+	return leaks
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+type goroutineStack struct {
+	id    int64
+	stack string
+}
+
+// goroutineStacks returns the stacks of all the goroutines
+func goroutineStacks() []goroutineStack {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var stacks []goroutineStack
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if id, ok := parseGoroutineID(stack); ok {
+			stacks = append(stacks, goroutineStack{id: id, stack: string(stack)})
+		}
+	}
+	return stacks
+}
+
+func currentGoroutineID() int64 {
+	buf := make([]byte, 64)
+	id, _ := parseGoroutineID(buf[:runtime.Stack(buf, false)])
+	return id
+}
+
+// parseGoroutineID parses the id in the stack header like "goroutine 18 [running]:"
+func parseGoroutineID(stack []byte) (int64, bool) {
+	const prefix = "goroutine "
+	if !bytes.HasPrefix(stack, []byte(prefix)) {
+		return 0, false
+	}
+	stack = stack[len(prefix):]
+	end := bytes.IndexByte(stack, ' ')
+	if end < 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(string(stack[:end]), 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type recordT struct {
+	errors []string
+}
+
+func (r *recordT) Helper() {}
+
+func (r *recordT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func leakedLoop(stop chan struct{}) {
+	<-stop
+}
+
+func TestCheckGoroutineLeaks(t *testing.T) {
+	r := &recordT{}
+	check := CheckGoroutineLeaks(r, WithLeakTimeout(50*time.Millisecond))
+	stop := make(chan struct{})
+	go leakedLoop(stop)
+	check()
+	assert.Equal(t, 1, len(r.errors))
+	assert.Contains(t, r.errors[0], "found 1 leaked goroutines")
+	assert.Contains(t, r.errors[0], "leakedLoop")
+
+	// the goroutine exits in the timeout
+	r = &recordT{}
+	check = CheckGoroutineLeaks(r)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+	}()
+	close(stop)
+	check()
+	assert.Equal(t, 0, len(r.errors))
+
+	stop = make(chan struct{})
+	defer close(stop)
+	check = CheckGoroutineLeaks(r, WithLeakTimeout(10*time.Millisecond), IgnoreLeak("leakedLoop"))
+	go leakedLoop(stop)
+	check()
+	assert.Equal(t, 0, len(r.errors))
+}
+
+func TestParseGoroutineID(t *testing.T) {
+	id, ok := parseGoroutineID([]byte("goroutine 18 [running]:\nmain.main()"))
+	assert.True(t, ok)
+	assert.Equal(t, int64(18), id)
+	_, ok = parseGoroutineID([]byte("main.main()"))
+	assert.False(t, ok)
+	assert.True(t, currentGoroutineID() > 0)
+}
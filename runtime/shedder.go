@@ -0,0 +1,372 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const (
+	defaultShedderCPUThreshold    = 80
+	defaultShedderMemoryThreshold = 90
+	defaultShedderWindow          = 5 * time.Second
+	defaultShedderBuckets         = 50
+	defaultShedderSampleInterval  = 250 * time.Millisecond
+	defaultShedderCoolOff         = time.Second
+
+	// the weight of the history in the moving average of the cpu usage
+	shedderCPUDecay = 0.8
+)
+
+var (
+	// ErrServiceOverloaded the work is rejected by the Shedder
+	ErrServiceOverloaded = perrors.New("service overloaded")
+)
+
+// Shedder decides whether to accept new work
+type Shedder interface {
+	// Allow returns ErrServiceOverloaded if the work should be rejected,
+	// otherwise the returned function must be called when the work is done.
+	Allow() (done func(), err error)
+}
+
+// SystemStat is the resource usage of the process in percent
+type SystemStat struct {
+	// CPU is the cpu usage divided by GOMAXPROCS, so it is at most 100
+	CPU float64
+	// Memory is the rss divided by the cgroup memory limit, or the total
+	// memory if there is no limit
+	Memory float64
+}
+
+// StatSampler samples the SystemStat, it is called periodically in the background
+type StatSampler func() (SystemStat, error)
+
+// ShedderStat is the snapshot of the AdaptiveShedder
+type ShedderStat struct {
+	SystemStat
+	// InFlight is the number of the accepted work not done
+	InFlight int64
+	// MaxInFlight is the estimated concurrency limit, it is 0 if unknown
+	MaxInFlight int64
+	// Dropped is the number of the rejected work
+	Dropped uint64
+}
+
+// ShedderOption configures AdaptiveShedder
+type ShedderOption func(*AdaptiveShedder)
+
+// WithShedderCPUThreshold sets the cpu usage in percent above which the
+// shedder starts to reject work, it is 80 by default.
+func WithShedderCPUThreshold(threshold float64) ShedderOption {
+	return func(s *AdaptiveShedder) {
+		s.cpuThreshold = threshold
+	}
+}
+
+// WithShedderMemoryThreshold sets the memory usage in percent above which
+// the shedder starts to reject work, it is 90 by default, 0 means no limit.
+func WithShedderMemoryThreshold(threshold float64) ShedderOption {
+	return func(s *AdaptiveShedder) {
+		s.memoryThreshold = threshold
+	}
+}
+
+// WithShedderWindow sets the sliding window of @buckets buckets in which
+// the throughput and the response time are measured, it is 5s of 50 buckets
+// by default.
+func WithShedderWindow(window time.Duration, buckets int) ShedderOption {
+	return func(s *AdaptiveShedder) {
+		s.window = window
+		s.bucketNum = buckets
+	}
+}
+
+// WithShedderSampleInterval sets the interval to sample the SystemStat, it is 250ms by default
+func WithShedderSampleInterval(interval time.Duration) ShedderOption {
+	return func(s *AdaptiveShedder) {
+		s.sampleInterval = interval
+	}
+}
+
+// WithStatSampler replaces the default sampler which reads the stats of the current process
+func WithStatSampler(sampler StatSampler) ShedderOption {
+	return func(s *AdaptiveShedder) {
+		s.sampler = sampler
+	}
+}
+
+type shedderBucket struct {
+	pass  int64
+	rtSum time.Duration
+	count int64
+}
+
+// AdaptiveShedder is a BBR-style load shedder. When the cpu or memory usage
+// exceeds the threshold, it rejects the work if the in-flight work exceeds
+// the concurrency limit estimated by the Little's law, which is the max
+// throughput multiplied by the min response time in the window. It keeps
+// rejecting for a cool-off second after the usage falls below the threshold,
+// so the in-flight work can drain.
+type AdaptiveShedder struct {
+	cpuThreshold    float64
+	memoryThreshold float64
+	window          time.Duration
+	bucketNum       int
+	sampleInterval  time.Duration
+	sampler         StatSampler
+	sampled         bool // only accessed by the sampling
+	now             func() time.Time
+
+	cpu      uint64 // math.Float64bits of the moving average
+	memory   uint64 // math.Float64bits
+	inFlight int64
+	dropped  uint64
+	prevDrop int64 // unix nano of the last drop
+
+	lock       sync.Mutex
+	bucketTime time.Duration
+	buckets    []shedderBucket
+	offset     int       // the index of the current bucket
+	lastTime   time.Time // the start of the current bucket
+
+	once sync.Once
+	done chan struct{}
+}
+
+// NewAdaptiveShedder returns an AdaptiveShedder which samples the stats in
+// the background until it is closed.
+func NewAdaptiveShedder(opts ...ShedderOption) *AdaptiveShedder {
+	s := &AdaptiveShedder{
+		cpuThreshold:    defaultShedderCPUThreshold,
+		memoryThreshold: defaultShedderMemoryThreshold,
+		window:          defaultShedderWindow,
+		bucketNum:       defaultShedderBuckets,
+		sampleInterval:  defaultShedderSampleInterval,
+		now:             time.Now,
+		done:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.bucketNum < 1 {
+		s.bucketNum = 1
+	}
+	if s.window < time.Duration(s.bucketNum) {
+		s.window = defaultShedderWindow
+	}
+	if s.sampleInterval <= 0 {
+		s.sampleInterval = defaultShedderSampleInterval
+	}
+	if s.sampler == nil {
+		s.sampler = newProcessStatSampler()
+	}
+	s.bucketTime = s.window / time.Duration(s.bucketNum)
+	s.buckets = make([]shedderBucket, s.bucketNum)
+	s.lastTime = s.now()
+
+	s.sample()
+	GoNamed("AdaptiveShedder.sampling", "gxruntime", nil, false, s.sampling, nil)
+	return s
+}
+
+func (s *AdaptiveShedder) sampling() {
+	ticker := time.NewTicker(s.sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *AdaptiveShedder) sample() {
+	stat, err := s.sampler()
+	if err != nil {
+		return
+	}
+	cpu := stat.CPU
+	if s.sampled {
+		cpu = math.Float64frombits(atomic.LoadUint64(&s.cpu))*shedderCPUDecay + stat.CPU*(1-shedderCPUDecay)
+	}
+	s.sampled = true
+	atomic.StoreUint64(&s.cpu, math.Float64bits(cpu))
+	atomic.StoreUint64(&s.memory, math.Float64bits(stat.Memory))
+}
+
+// Allow implements Shedder
+func (s *AdaptiveShedder) Allow() (func(), error) {
+	if s.shouldDrop() {
+		atomic.AddUint64(&s.dropped, 1)
+		atomic.StoreInt64(&s.prevDrop, s.now().UnixNano())
+		return nil, ErrServiceOverloaded
+	}
+
+	atomic.AddInt64(&s.inFlight, 1)
+	start := s.now()
+	var finished int32
+	return func() {
+		if !atomic.CompareAndSwapInt32(&finished, 0, 1) {
+			return
+		}
+		atomic.AddInt64(&s.inFlight, -1)
+		s.record(s.now().Sub(start))
+	}, nil
+}
+
+func (s *AdaptiveShedder) overloaded() bool {
+	if math.Float64frombits(atomic.LoadUint64(&s.cpu)) >= s.cpuThreshold {
+		return true
+	}
+	return s.memoryThreshold > 0 && math.Float64frombits(atomic.LoadUint64(&s.memory)) >= s.memoryThreshold
+}
+
+func (s *AdaptiveShedder) shouldDrop() bool {
+	if !s.overloaded() {
+		prevDrop := atomic.LoadInt64(&s.prevDrop)
+		if prevDrop == 0 || s.now().Sub(time.Unix(0, prevDrop)) > defaultShedderCoolOff {
+			return false
+		}
+	}
+	inFlight := atomic.LoadInt64(&s.inFlight)
+	maxInFlight := s.maxInFlight()
+	return maxInFlight > 0 && inFlight > 1 && inFlight >= maxInFlight
+}
+
+// maxInFlight returns max pass per bucket * min rt / bucket time, or 0 if
+// there is no completed bucket in the window
+func (s *AdaptiveShedder) maxInFlight() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.advance()
+
+	var maxPass int64
+	minRT := time.Duration(math.MaxInt64)
+	for i, b := range s.buckets {
+		// the current bucket is incomplete
+		if i == s.offset || b.count == 0 {
+			continue
+		}
+		if b.pass > maxPass {
+			maxPass = b.pass
+		}
+		if rt := b.rtSum / time.Duration(b.count); rt < minRT {
+			minRT = rt
+		}
+	}
+	if maxPass == 0 {
+		return 0
+	}
+	if minRT <= 0 {
+		minRT = 1
+	}
+	return int64(math.Ceil(float64(maxPass) * float64(minRT) / float64(s.bucketTime)))
+}
+
+func (s *AdaptiveShedder) record(rt time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.advance()
+	b := &s.buckets[s.offset]
+	b.pass++
+	b.rtSum += rt
+	b.count++
+}
+
+// advance moves the current bucket to now and resets the expired buckets,
+// it should be called with the lock held
+func (s *AdaptiveShedder) advance() {
+	passed := int(s.now().Sub(s.lastTime) / s.bucketTime)
+	if passed <= 0 {
+		return
+	}
+	s.lastTime = s.lastTime.Add(time.Duration(passed) * s.bucketTime)
+	if passed > s.bucketNum {
+		passed = s.bucketNum
+	}
+	for i := 1; i <= passed; i++ {
+		s.buckets[(s.offset+i)%s.bucketNum] = shedderBucket{}
+	}
+	s.offset = (s.offset + passed) % s.bucketNum
+}
+
+// Stat returns the snapshot of the shedder
+func (s *AdaptiveShedder) Stat() ShedderStat {
+	return ShedderStat{
+		SystemStat: SystemStat{
+			CPU:    math.Float64frombits(atomic.LoadUint64(&s.cpu)),
+			Memory: math.Float64frombits(atomic.LoadUint64(&s.memory)),
+		},
+		InFlight:    atomic.LoadInt64(&s.inFlight),
+		MaxInFlight: s.maxInFlight(),
+		Dropped:     atomic.LoadUint64(&s.dropped),
+	}
+}
+
+// Close stops sampling the stats, Allow keeps working with the last stats
+func (s *AdaptiveShedder) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// newProcessStatSampler returns a StatSampler of the current process, the
+// cpu usage is measured since the last sampling.
+func newProcessStatSampler() StatSampler {
+	var (
+		p   *process.Process
+		err error
+	)
+	return func() (SystemStat, error) {
+		if p == nil {
+			if p, err = process.NewProcess(int32(os.Getpid())); err != nil {
+				p = nil
+				return SystemStat{}, err
+			}
+		}
+
+		var stat SystemStat
+		cpu, err := p.Percent(0)
+		if err != nil {
+			return stat, err
+		}
+		stat.CPU = cpu / float64(runtime.GOMAXPROCS(-1))
+
+		if limit, err := GetCgroupMemoryLimit(); err == nil && limit != math.MaxUint64 {
+			stat.Memory, err = GetCgroupProcessMemoryPercent()
+			return stat, err
+		}
+		memory, err := p.MemoryPercent()
+		stat.Memory = float64(memory)
+		return stat, err
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	c.lock.Unlock()
+}
+
+type fakeSampler struct {
+	lock sync.Mutex
+	stat SystemStat
+}
+
+func (s *fakeSampler) Set(stat SystemStat) {
+	s.lock.Lock()
+	s.stat = stat
+	s.lock.Unlock()
+}
+
+func (s *fakeSampler) Sample() (SystemStat, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stat, nil
+}
+
+func newTestShedder(sampler *fakeSampler, clock *fakeClock, opts ...ShedderOption) *AdaptiveShedder {
+	opts = append([]ShedderOption{
+		WithStatSampler(sampler.Sample),
+		WithShedderSampleInterval(time.Hour),
+	}, opts...)
+	s := NewAdaptiveShedder(opts...)
+	s.lock.Lock()
+	s.now = clock.Now
+	s.lastTime = clock.Now()
+	s.lock.Unlock()
+	return s
+}
+
+func TestAdaptiveShedder(t *testing.T) {
+	defer CheckGoroutineLeaks(t)()
+
+	sampler := &fakeSampler{}
+	clock := &fakeClock{now: time.Now()}
+	s := newTestShedder(sampler, clock, WithShedderWindow(10*time.Second, 10))
+	defer s.Close()
+
+	// 10 works of 500ms in a bucket of 1s, so the max in-flight is 10 * 500ms / 1s
+	var dones []func()
+	for i := 0; i < 10; i++ {
+		done, err := s.Allow()
+		assert.Nil(t, err)
+		dones = append(dones, done)
+	}
+	assert.Equal(t, int64(10), s.Stat().InFlight)
+	clock.Add(500 * time.Millisecond)
+	for _, done := range dones {
+		done()
+		// done twice is ignored
+		done()
+	}
+	clock.Add(time.Second)
+	stat := s.Stat()
+	assert.Equal(t, int64(0), stat.InFlight)
+	assert.Equal(t, int64(5), stat.MaxInFlight)
+
+	// not overloaded
+	dones = dones[:0]
+	for i := 0; i < 10; i++ {
+		done, err := s.Allow()
+		assert.Nil(t, err)
+		dones = append(dones, done)
+	}
+	for _, done := range dones {
+		done()
+	}
+
+	// the cpu usage is smoothed
+	sampler.Set(SystemStat{CPU: 100})
+	s.sample()
+	assert.Equal(t, float64(20), s.Stat().CPU)
+	for s.Stat().CPU < 80 {
+		s.sample()
+	}
+	dones = dones[:0]
+	for i := 0; i < 5; i++ {
+		done, err := s.Allow()
+		assert.Nil(t, err)
+		dones = append(dones, done)
+	}
+	_, err := s.Allow()
+	assert.Equal(t, ErrServiceOverloaded, err)
+	assert.Equal(t, uint64(1), s.Stat().Dropped)
+
+	// keep dropping in the cool-off period
+	sampler.Set(SystemStat{CPU: 10})
+	for s.Stat().CPU >= 80 {
+		s.sample()
+	}
+	clock.Add(500 * time.Millisecond)
+	_, err = s.Allow()
+	assert.Equal(t, ErrServiceOverloaded, err)
+
+	clock.Add(1500 * time.Millisecond)
+	done, err := s.Allow()
+	assert.Nil(t, err)
+	done()
+	for _, done := range dones {
+		done()
+	}
+}
+
+func TestAdaptiveShedderMemory(t *testing.T) {
+	sampler := &fakeSampler{}
+	clock := &fakeClock{now: time.Now()}
+	s := newTestShedder(sampler, clock, WithShedderWindow(time.Second, 10), WithShedderMemoryThreshold(50))
+	defer s.Close()
+
+	done, err := s.Allow()
+	assert.Nil(t, err)
+	clock.Add(100 * time.Millisecond)
+	done()
+	clock.Add(100 * time.Millisecond)
+	assert.Equal(t, int64(1), s.Stat().MaxInFlight)
+
+	sampler.Set(SystemStat{Memory: 60})
+	s.sample()
+	// at least 2 in-flight works are allowed
+	done1, err := s.Allow()
+	assert.Nil(t, err)
+	done2, err := s.Allow()
+	assert.Nil(t, err)
+	_, err = s.Allow()
+	assert.Equal(t, ErrServiceOverloaded, err)
+	done1()
+	done2()
+
+	// the window is expired, so there is no limit
+	clock.Add(2 * time.Second)
+	assert.Equal(t, int64(0), s.Stat().MaxInFlight)
+	done, err = s.Allow()
+	assert.Nil(t, err)
+	done()
+}
+
+func TestProcessStatSampler(t *testing.T) {
+	sampler := newProcessStatSampler()
+	_, err := sampler()
+	assert.Nil(t, err)
+	stat, err := sampler()
+	assert.Nil(t, err)
+	assert.True(t, stat.CPU >= 0)
+	assert.True(t, stat.Memory > 0)
+}
@@ -0,0 +1,469 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+const (
+	defaultBackoffInitial  = 100 * time.Millisecond
+	defaultBackoffMax      = 10 * time.Second
+	defaultMaxRestarts     = 10
+	defaultRestartInterval = time.Minute
+)
+
+var (
+	// ErrTooManyRestarts the children restart more than the max restart intensity
+	ErrTooManyRestarts = perrors.New("too many restarts")
+	// ErrSupervisorRunning the supervisor is running
+	ErrSupervisorRunning = perrors.New("supervisor is running")
+	// ErrChildExists the name of the child has been used
+	ErrChildExists = perrors.New("child already exists")
+	// ErrChildNotFound there is no child of the name
+	ErrChildNotFound = perrors.New("child not found")
+)
+
+// ChildFunc is the function run by a child of Supervisor, it should return
+// when @ctx is done. Supervisor.Run is also a ChildFunc, so a supervisor can
+// be a child of another one to build a tree.
+type ChildFunc func(ctx context.Context) error
+
+// RestartStrategy decides which children are restarted when one of them exits
+type RestartStrategy int
+
+const (
+	// OneForOne only restarts the exited child
+	OneForOne RestartStrategy = iota
+	// OneForAll stops all the other children and restarts all of them
+	OneForAll
+)
+
+// RestartType decides whether a child is restarted when it exits
+type RestartType int
+
+const (
+	// Permanent child is always restarted
+	Permanent RestartType = iota
+	// Transient child is restarted only if it panics or returns an error
+	Transient
+	// Temporary child is never restarted
+	Temporary
+)
+
+// ChildState is the state of a child
+type ChildState int
+
+const (
+	// ChildPending the child is waiting for the supervisor to run
+	ChildPending ChildState = iota
+	// ChildRunning the child is running
+	ChildRunning
+	// ChildRestarting the child is waiting for the backoff to restart
+	ChildRestarting
+	// ChildStopped the child exits without error and is not restarted
+	ChildStopped
+	// ChildFailed the child exits with error and is not restarted
+	ChildFailed
+)
+
+func (s ChildState) String() string {
+	switch s {
+	case ChildPending:
+		return "pending"
+	case ChildRunning:
+		return "running"
+	case ChildRestarting:
+		return "restarting"
+	case ChildStopped:
+		return "stopped"
+	case ChildFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ChildStatus is the status report of a child
+type ChildStatus struct {
+	Name      string
+	State     ChildState
+	Restarts  int
+	StartedAt time.Time
+	LastError error
+}
+
+// SupervisorOption configures Supervisor
+type SupervisorOption func(*Supervisor)
+
+// WithRestartStrategy sets the restart strategy, it is OneForOne by default
+func WithRestartStrategy(strategy RestartStrategy) SupervisorOption {
+	return func(s *Supervisor) {
+		s.strategy = strategy
+	}
+}
+
+// WithBackoff sets the delay before restarting, it doubles from @initial up
+// to @max on the consecutive restarts, and is reset if the child has run for
+// @max. It is 100ms up to 10s by default.
+func WithBackoff(initial, max time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.backoffInitial = initial
+		s.backoffMax = max
+	}
+}
+
+// WithRestartIntensity stops the supervisor with ErrTooManyRestarts if there
+// are more than @maxRestarts restarts in @interval. It is 10 restarts in a
+// minute by default.
+func WithRestartIntensity(maxRestarts int, interval time.Duration) SupervisorOption {
+	return func(s *Supervisor) {
+		s.maxRestarts = maxRestarts
+		s.restartInterval = interval
+	}
+}
+
+// ChildOption configures a child
+type ChildOption func(*child)
+
+// WithRestartType sets the restart type of the child, it is Permanent by default
+func WithRestartType(restart RestartType) ChildOption {
+	return func(c *child) {
+		c.restart = restart
+	}
+}
+
+type child struct {
+	name    string
+	fn      ChildFunc
+	restart RestartType
+
+	// gen increases when the child is started or abandoned, so the stale exit is ignored
+	gen       int
+	state     ChildState
+	restarts  int
+	failures  int // consecutive failures for the backoff
+	startedAt time.Time
+	lastErr   error
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// Supervisor runs the named children and restarts them by the restart
+// strategy when they exit, like the supervisor of Erlang. The panic of a
+// child is recovered and treated as an error.
+type Supervisor struct {
+	name            string
+	strategy        RestartStrategy
+	backoffInitial  time.Duration
+	backoffMax      time.Duration
+	maxRestarts     int
+	restartInterval time.Duration
+
+	lock     sync.Mutex
+	children []*child
+	ctx      context.Context
+	running  bool
+	restarts []time.Time // the restart times in the restart interval
+	failed   chan error
+}
+
+// NewSupervisor returns a Supervisor named @name
+func NewSupervisor(name string, opts ...SupervisorOption) *Supervisor {
+	s := &Supervisor{
+		name:            name,
+		strategy:        OneForOne,
+		backoffInitial:  defaultBackoffInitial,
+		backoffMax:      defaultBackoffMax,
+		maxRestarts:     defaultMaxRestarts,
+		restartInterval: defaultRestartInterval,
+		failed:          make(chan error, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds a child named @name, it is started at once if the supervisor is running
+func (s *Supervisor) Add(name string, fn ChildFunc, opts ...ChildOption) error {
+	c := &child{name: name, fn: fn, restart: Permanent}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.find(name) != nil {
+		return perrors.WithMessagef(ErrChildExists, "add child %s", name)
+	}
+	s.children = append(s.children, c)
+	if s.running {
+		s.startChild(c)
+	}
+	return nil
+}
+
+// Remove stops the child named @name and removes it
+func (s *Supervisor) Remove(name string) error {
+	s.lock.Lock()
+	c := s.find(name)
+	if c == nil {
+		s.lock.Unlock()
+		return perrors.WithMessagef(ErrChildNotFound, "remove child %s", name)
+	}
+	for i := range s.children {
+		if s.children[i] == c {
+			s.children = append(s.children[:i], s.children[i+1:]...)
+			break
+		}
+	}
+	done := s.abandon(c)
+	s.lock.Unlock()
+
+	if done != nil {
+		<-done
+	}
+	return nil
+}
+
+func (s *Supervisor) find(name string) *child {
+	for _, c := range s.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Status returns the status of the children in the order they are added
+func (s *Supervisor) Status() []ChildStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	status := make([]ChildStatus, 0, len(s.children))
+	for _, c := range s.children {
+		status = append(status, ChildStatus{
+			Name:      c.name,
+			State:     c.state,
+			Restarts:  c.restarts,
+			StartedAt: c.startedAt,
+			LastError: c.lastErr,
+		})
+	}
+	return status
+}
+
+// Run starts the children and blocks until @ctx is done or the restart
+// intensity is exceeded, then all the children are stopped. It returns nil
+// if @ctx is done. It can be called again after it returns, so the parent
+// supervisor can restart it.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.lock.Lock()
+	if s.running {
+		s.lock.Unlock()
+		return ErrSupervisorRunning
+	}
+	s.running = true
+	s.ctx = ctx
+	s.restarts = nil
+	select {
+	case <-s.failed:
+	default:
+	}
+	for _, c := range s.children {
+		c.failures = 0
+		s.startChild(c)
+	}
+	s.lock.Unlock()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-s.failed:
+	}
+	s.shutdown()
+	return err
+}
+
+// shutdown stops all the children and waits for them to exit
+func (s *Supervisor) shutdown() {
+	var dones []chan struct{}
+	s.lock.Lock()
+	s.running = false
+	for _, c := range s.children {
+		if done := s.abandon(c); done != nil {
+			dones = append(dones, done)
+		}
+		if c.state == ChildRunning || c.state == ChildRestarting {
+			c.state = ChildStopped
+		}
+	}
+	s.lock.Unlock()
+
+	for _, done := range dones {
+		<-done
+	}
+}
+
+// abandon stops the child, and returns the done channel if it is running.
+// It should be called with the lock held.
+func (s *Supervisor) abandon(c *child) chan struct{} {
+	c.gen++
+	if c.state != ChildRunning {
+		return nil
+	}
+	c.cancel()
+	return c.done
+}
+
+// startChild should be called with the lock held
+func (s *Supervisor) startChild(c *child) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	c.gen++
+	gen := c.gen
+	done := make(chan struct{})
+	c.cancel = cancel
+	c.done = done
+	c.state = ChildRunning
+	c.startedAt = time.Now()
+
+	go func() {
+		err := s.runChild(ctx, c)
+		cancel()
+		close(done)
+		s.childExited(c, gen, err)
+	}()
+}
+
+func (s *Supervisor) runChild(ctx context.Context, c *child) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "%s supervisor %s child %s panic: %v\n%s\n",
+				time.Now(), s.name, c.name, r, string(debug.Stack()))
+			err = perrors.Errorf("panic: %v", r)
+		}
+	}()
+	return c.fn(ctx)
+}
+
+func (s *Supervisor) childExited(c *child, gen int, err error) {
+	s.lock.Lock()
+	if !s.running || c.gen != gen {
+		s.lock.Unlock()
+		return
+	}
+
+	c.lastErr = err
+	if c.restart == Temporary || (c.restart == Transient && err == nil) {
+		c.state = ChildStopped
+		if err != nil {
+			c.state = ChildFailed
+		}
+		s.lock.Unlock()
+		return
+	}
+
+	now := time.Now()
+	restarts := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.restartInterval {
+			restarts = append(restarts, t)
+		}
+	}
+	s.restarts = append(restarts, now)
+	if len(s.restarts) > s.maxRestarts {
+		c.state = ChildFailed
+		s.lock.Unlock()
+		s.fail(perrors.WithMessagef(ErrTooManyRestarts, "supervisor %s child %s exits with %v", s.name, c.name, err))
+		return
+	}
+
+	if now.Sub(c.startedAt) >= s.backoffMax {
+		c.failures = 0
+	}
+	c.failures++
+	delay := s.backoff(c.failures)
+
+	targets := []*child{c}
+	if s.strategy == OneForAll {
+		for _, t := range s.children {
+			if t != c && (t.state == ChildRunning || t.state == ChildRestarting) {
+				targets = append(targets, t)
+			}
+		}
+	}
+	var dones []chan struct{}
+	gens := make(map[*child]int, len(targets))
+	for _, t := range targets {
+		if done := s.abandon(t); done != nil {
+			dones = append(dones, done)
+		}
+		t.state = ChildRestarting
+		t.restarts++
+		gens[t] = t.gen
+	}
+	s.lock.Unlock()
+
+	for _, done := range dones {
+		<-done
+	}
+
+	time.AfterFunc(delay, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if !s.running {
+			return
+		}
+		for _, t := range targets {
+			// it is not removed or restarted by others
+			if t.gen == gens[t] {
+				s.startChild(t)
+			}
+		}
+	})
+}
+
+func (s *Supervisor) backoff(failures int) time.Duration {
+	delay := s.backoffInitial
+	for i := 1; i < failures && delay < s.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.backoffMax {
+		delay = s.backoffMax
+	}
+	return delay
+}
+
+func (s *Supervisor) fail(err error) {
+	select {
+	case s.failed <- err:
+	default:
+	}
+}
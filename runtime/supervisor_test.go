@@ -0,0 +1,192 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gxruntime
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func childStatus(s *Supervisor, name string) ChildStatus {
+	for _, status := range s.Status() {
+		if status.Name == name {
+			return status
+		}
+	}
+	return ChildStatus{}
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	defer CheckGoroutineLeaks(t)()
+
+	s := NewSupervisor("test", WithBackoff(time.Millisecond, 10*time.Millisecond))
+	var panics, stable int32
+	assert.Nil(t, s.Add("panic", func(ctx context.Context) error {
+		if atomic.AddInt32(&panics, 1) <= 2 {
+			panic("boom")
+		}
+		<-ctx.Done()
+		return nil
+	}))
+	assert.Nil(t, s.Add("stable", func(ctx context.Context) error {
+		atomic.AddInt32(&stable, 1)
+		<-ctx.Done()
+		return nil
+	}))
+	assert.True(t, perrors.Is(s.Add("stable", nil), ErrChildExists))
+	assert.Equal(t, ChildPending, childStatus(s, "stable").State)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return childStatus(s, "panic").State == ChildRunning && atomic.LoadInt32(&panics) == 3
+	}, time.Second, time.Millisecond)
+	status := childStatus(s, "panic")
+	assert.Equal(t, 2, status.Restarts)
+	assert.NotNil(t, status.LastError)
+	// the other child is not restarted
+	assert.Equal(t, int32(1), atomic.LoadInt32(&stable))
+	assert.Equal(t, 0, childStatus(s, "stable").Restarts)
+
+	// the child added later is started at once
+	var added int32
+	assert.Nil(t, s.Add("added", func(ctx context.Context) error {
+		atomic.AddInt32(&added, 1)
+		return nil
+	}, WithRestartType(Transient)))
+	assert.Eventually(t, func() bool {
+		return childStatus(s, "added").State == ChildStopped
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&added))
+	assert.Nil(t, s.Remove("added"))
+	assert.True(t, perrors.Is(s.Remove("added"), ErrChildNotFound))
+
+	cancel()
+	assert.Nil(t, <-result)
+	for _, status := range s.Status() {
+		assert.Equal(t, ChildStopped, status.State)
+	}
+
+	// run again
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		result <- s.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool {
+		return childStatus(s, "stable").State == ChildRunning
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, ErrSupervisorRunning, s.Run(ctx))
+	cancel()
+	assert.Nil(t, <-result)
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	s := NewSupervisor("test",
+		WithRestartStrategy(OneForAll),
+		WithBackoff(time.Millisecond, 10*time.Millisecond))
+	var fails, other int32
+	assert.Nil(t, s.Add("fail", func(ctx context.Context) error {
+		if atomic.AddInt32(&fails, 1) == 1 {
+			return perrors.New("fail")
+		}
+		<-ctx.Done()
+		return nil
+	}))
+	assert.Nil(t, s.Add("other", func(ctx context.Context) error {
+		atomic.AddInt32(&other, 1)
+		<-ctx.Done()
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = s.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&fails) == 2 && atomic.LoadInt32(&other) == 2
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return childStatus(s, "other").State == ChildRunning
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, childStatus(s, "other").Restarts)
+}
+
+func TestSupervisorRestartIntensity(t *testing.T) {
+	s := NewSupervisor("test",
+		WithBackoff(time.Millisecond, time.Millisecond),
+		WithRestartIntensity(3, time.Minute))
+	var runs int32
+	assert.Nil(t, s.Add("fail", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return perrors.New("fail")
+	}))
+	assert.Nil(t, s.Add("temporary", func(ctx context.Context) error {
+		return perrors.New("fail")
+	}, WithRestartType(Temporary)))
+
+	err := s.Run(context.Background())
+	assert.True(t, perrors.Is(err, ErrTooManyRestarts))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&runs))
+	assert.Equal(t, ChildFailed, childStatus(s, "fail").State)
+	assert.Equal(t, ChildFailed, childStatus(s, "temporary").State)
+}
+
+func TestSupervisorTree(t *testing.T) {
+	defer CheckGoroutineLeaks(t)()
+
+	var runs int32
+	child := NewSupervisor("child", WithBackoff(time.Millisecond, time.Millisecond), WithRestartIntensity(1, time.Minute))
+	assert.Nil(t, child.Add("fail", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return perrors.New("fail")
+	}))
+	parent := NewSupervisor("parent", WithBackoff(time.Millisecond, time.Millisecond), WithRestartIntensity(2, time.Minute))
+	assert.Nil(t, parent.Add("child", child.Run))
+
+	// the parent restarts the failed child supervisor until its own intensity is exceeded
+	err := parent.Run(context.Background())
+	assert.True(t, perrors.Is(err, ErrTooManyRestarts))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&runs))
+	status := childStatus(parent, "child")
+	assert.Equal(t, ChildFailed, status.State)
+	assert.Equal(t, 2, status.Restarts)
+	assert.True(t, perrors.Is(status.LastError, ErrTooManyRestarts))
+}
+
+func TestBackoff(t *testing.T) {
+	s := NewSupervisor("test", WithBackoff(10*time.Millisecond, 50*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, s.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, s.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, s.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, s.backoff(4))
+	assert.Equal(t, 50*time.Millisecond, s.backoff(100))
+}
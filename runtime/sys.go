@@ -32,30 +32,17 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 )
 
-import (
-	"github.com/dubbogo/gost/path/filepath"
-)
-
 // CurrentPID returns the process id of the caller.
 var CurrentPID = os.Getpid()
 
 const (
-	cgroupMemLimitPath = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
-
-	_cgroupPath    = "/proc/self/cgroup"
-	_dockerPath    = "/docker"
-	_kubepodsPath  = "/kubepods"
-	_cpuPeriodPath = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
-	_cpuQuotaPath  = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	_cgroupPath = "/proc/self/cgroup"
 )
 
-// GetCPUNum gets current os's cpu number
+// GetCPUNum gets current os's cpu number, which is limited by the cgroup cpu quota
 func GetCPUNum() int {
-	if isContainer() {
-		cpus, _ := numCPU()
-		return cpus
-	}
-	return runtime.NumCPU()
+	cpus, _ := numCPU()
+	return cpus
 }
 
 // GetMemoryStat gets current os's memory size in bytes
@@ -68,15 +55,15 @@ func GetMemoryStat() (total, used, free uint64, usedPercent float64) {
 	return stat.Total, stat.Used, stat.Free, stat.UsedPercent
 }
 
-// IsCgroup checks whether current os is a container or not
+// IsCgroup checks whether the cgroup v1 or v2 hierarchy is mounted
 func IsCgroup() bool {
-	ok, _ := gxfilepath.Exists(cgroupMemLimitPath)
-	return ok
+	return DefaultCgroup().Version() != CgroupNone
 }
 
-// GetCgroupMemoryLimit returns a container's total memory in bytes
+// GetCgroupMemoryLimit returns a container's total memory in bytes, it is
+// math.MaxUint64 if there is no limit
 func GetCgroupMemoryLimit() (uint64, error) {
-	return readUint(cgroupMemLimitPath)
+	return DefaultCgroup().MemoryLimit()
 }
 
 // GetThreadNum gets current process's thread number
@@ -211,40 +198,13 @@ func readLinesFromFile(filepath string) []string {
 	}
 }
 
-// isContainer returns true if the process is running in a container.
-func isContainer() bool {
-	lines := readLinesFromFile(_cgroupPath)
-	for _, line := range lines {
-		if strings.Contains(line, _dockerPath) ||
-			strings.Contains(line, _kubepodsPath) {
-			return true
-		}
-	}
-	return false
-}
-
 // numCPU returns the CPU quota
 func numCPU() (num int, err error) {
-	if !isContainer() {
-		return runtime.NumCPU(), nil
-	}
-
-	// If the container is running in a cgroup, we can use the cgroup cpu
-	// quota to limit the number of CPUs.
-	period, err := readUint(_cpuPeriodPath)
-	if err != nil {
-		return runtime.NumCPU(), err
-	}
-	quota, err := readUint(_cpuQuotaPath)
-	if err != nil {
-		return runtime.NumCPU(), err
-	}
-
 	// The number of CPUs is the quota divided by the period.
 	// See https://www.kernel.org/doc/Documentation/scheduler/sched-bwc.txt
-	if quota <= 0 || period <= 0 {
+	c := DefaultCgroup()
+	if _, _, err = c.CPUQuota(); err != nil {
 		return runtime.NumCPU(), err
 	}
-
-	return int(quota) / int(period), nil
+	return c.EffectiveGOMAXPROCS(), nil
 }
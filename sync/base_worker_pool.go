@@ -29,6 +29,7 @@ import (
 
 import (
 	gxlog "github.com/dubbogo/gost/log"
+	gxruntime "github.com/dubbogo/gost/runtime"
 )
 
 type WorkerPoolConfig struct {
@@ -37,6 +38,8 @@ type WorkerPoolConfig struct {
 	QueueSize  int
 	Logger     gxlog.Logger
 	Enable     bool
+	// Shedder is consulted before accepting a task if it is not nil
+	Shedder gxruntime.Shedder
 }
 
 // baseWorkerPool is a worker pool with multiple queues.
@@ -73,6 +76,7 @@ type baseWorkerPool struct {
 
 	numWorkers *atomic.Int32
 	enable     bool
+	shedder    gxruntime.Shedder
 
 	wg *sync.WaitGroup
 }
@@ -99,6 +103,7 @@ func newBaseWorkerPool(config WorkerPoolConfig) *baseWorkerPool {
 		numWorkers: new(atomic.Int32),
 		wg:         new(sync.WaitGroup),
 		enable:     config.Enable,
+		shedder:    config.Shedder,
 	}
 
 	if !config.Enable {
@@ -40,11 +40,29 @@ type ConnectionPool struct {
 	*baseWorkerPool
 }
 
-func (p *ConnectionPool) Submit(t task) error {
+func (p *ConnectionPool) Submit(t task) (err error) {
 	if t == nil {
 		return perrors.New("task shouldn't be nil")
 	}
 
+	if p.shedder != nil {
+		done, shedErr := p.shedder.Allow()
+		if shedErr != nil {
+			return shedErr
+		}
+		fn := t
+		t = func() {
+			defer done()
+			fn()
+		}
+		// the task is rejected by the pool
+		defer func() {
+			if err != nil {
+				done()
+			}
+		}()
+	}
+
 	if !p.enable {
 		go t()
 		return nil
@@ -29,6 +29,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+import (
+	gxruntime "github.com/dubbogo/gost/runtime"
+)
+
 func TestConnectionPool(t *testing.T) {
 	t.Run("Count", func(t *testing.T) {
 		p := NewConnectionPool(WorkerPoolConfig{
@@ -55,6 +59,39 @@ func TestConnectionPool(t *testing.T) {
 		p.Close()
 	})
 
+	t.Run("Shedder", func(t *testing.T) {
+		shedder := &testShedder{}
+		p := NewConnectionPool(WorkerPoolConfig{
+			NumWorkers: 1,
+			NumQueues:  1,
+			QueueSize:  0,
+			Enable:     true,
+			Shedder:    shedder,
+		})
+		defer p.Close()
+
+		assert.Nil(t, p.SubmitSync(func() {
+			assert.Equal(t, int32(1), atomic.LoadInt32(&shedder.inFlight))
+		}))
+		assert.Eventually(t, func() bool {
+			return atomic.LoadInt32(&shedder.inFlight) == 0
+		}, time.Second, time.Millisecond)
+
+		// the task rejected by the busy pool is done at once
+		release := make(chan struct{})
+		assert.Eventually(t, func() bool {
+			return p.Submit(func() {
+				<-release
+			}) == nil
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, PoolBusyErr, p.Submit(func() {}))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&shedder.inFlight))
+		close(release)
+
+		atomic.StoreInt32(&shedder.reject, 1)
+		assert.Equal(t, gxruntime.ErrServiceOverloaded, p.Submit(func() {}))
+	})
+
 	t.Run("PoolBusyErr", func(t *testing.T) {
 		p := NewConnectionPool(WorkerPoolConfig{
 			NumWorkers: 1,
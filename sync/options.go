@@ -21,6 +21,10 @@ import (
 	"fmt"
 )
 
+import (
+	gxruntime "github.com/dubbogo/gost/runtime"
+)
+
 const (
 	defaultTaskQNumber = 10
 	defaultTaskQLen    = 128
@@ -35,6 +39,8 @@ type TaskPoolOptions struct {
 	tQLen      int // task queue length. buffer size per queue
 	tQNumber   int // task queue number. number of queue
 	tQPoolSize int // task pool size. number of workers
+
+	shedder gxruntime.Shedder // consulted by AddTask, nil means no shedding
 }
 
 func (o *TaskPoolOptions) validate() {
@@ -77,3 +83,10 @@ func WithTaskPoolTaskQueueNumber(number int) TaskPoolOption {
 		o.tQNumber = number
 	}
 }
+
+// WithTaskPoolShedder set @shedder which is consulted by AddTask before accepting the task
+func WithTaskPoolShedder(shedder gxruntime.Shedder) TaskPoolOption {
+	return func(o *TaskPoolOptions) {
+		o.shedder = shedder
+	}
+}
@@ -149,18 +149,30 @@ func (p *TaskPool) run(id int, q chan task) error {
 	}
 }
 
-// return false when the pool is stop
+// return false when the pool is stop or the task is rejected by the shedder
 func (p *TaskPool) AddTask(t task) (ok bool) {
-	idx := atomic.AddUint32(&p.idx, 1)
-	id := idx % uint32(p.tQNumber)
-
 	select {
 	case <-p.done:
 		return false
 	default:
-		p.qArray[id] <- t
-		return true
 	}
+
+	if p.shedder != nil {
+		done, err := p.shedder.Allow()
+		if err != nil {
+			return false
+		}
+		fn := t
+		t = func() {
+			defer done()
+			fn()
+		}
+	}
+
+	idx := atomic.AddUint32(&p.idx, 1)
+	id := idx % uint32(p.tQNumber)
+	p.qArray[id] <- t
+	return true
 }
 
 func (p *TaskPool) AddTaskAlways(t task) {
@@ -26,6 +26,14 @@ import (
 	"time"
 )
 
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	gxruntime "github.com/dubbogo/gost/runtime"
+)
+
 func newCountTask() (func(), *int64) {
 	var cnt int64
 	return func() {
@@ -228,6 +236,46 @@ func TestTaskPool(t *testing.T) {
 	//}
 }
 
+type testShedder struct {
+	reject   int32
+	inFlight int32
+}
+
+func (s *testShedder) Allow() (func(), error) {
+	if atomic.LoadInt32(&s.reject) == 1 {
+		return nil, gxruntime.ErrServiceOverloaded
+	}
+	atomic.AddInt32(&s.inFlight, 1)
+	return func() {
+		atomic.AddInt32(&s.inFlight, -1)
+	}, nil
+}
+
+func TestTaskPoolShedder(t *testing.T) {
+	shedder := &testShedder{}
+	tp := NewTaskPool(
+		WithTaskPoolTaskPoolSize(1),
+		WithTaskPoolShedder(shedder),
+	)
+	defer tp.Close()
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	assert.True(t, tp.AddTask(func() {
+		<-release
+		close(finished)
+	}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&shedder.inFlight))
+	close(release)
+	<-finished
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&shedder.inFlight) == 0
+	}, time.Second, time.Millisecond)
+
+	atomic.StoreInt32(&shedder.reject, 1)
+	assert.False(t, tp.AddTask(func() {}))
+}
+
 func BenchmarkTaskPool_CountTask(b *testing.B) {
 	tp := NewTaskPool(
 		WithTaskPoolTaskPoolSize(100),
@@ -32,6 +32,7 @@ type Wheel struct {
 	index  int
 	ring   []chan struct{}
 	once   sync.Once
+	done   chan struct{}
 	now    time.Time
 }
 
@@ -51,12 +52,22 @@ func NewWheel(span time.Duration, buckets int) *Wheel {
 		ticker: time.NewTicker(span),
 		index:  0,
 		ring:   make([]chan struct{}, buckets),
+		done:   make(chan struct{}),
 		now:    time.Now(),
 	}
 
 	go func() {
-		var notify chan struct{}
-		for t := range w.ticker.C {
+		var (
+			t      time.Time
+			notify chan struct{}
+		)
+		for {
+			// the ticker channel is not closed by Stop, so exit on done
+			select {
+			case <-w.done:
+				return
+			case t = <-w.ticker.C:
+			}
 			w.Lock()
 			w.now = t
 
@@ -76,7 +87,10 @@ func NewWheel(span time.Duration, buckets int) *Wheel {
 }
 
 func (w *Wheel) Stop() {
-	w.once.Do(func() { w.ticker.Stop() })
+	w.once.Do(func() {
+		w.ticker.Stop()
+		close(w.done)
+	})
 }
 
 func (w *Wheel) After(timeout time.Duration) <-chan struct{} {
@@ -23,6 +23,19 @@ import (
 	"time"
 )
 
+import (
+	gxruntime "github.com/dubbogo/gost/runtime"
+)
+
+func TestWheelStop(t *testing.T) {
+	defer gxruntime.CheckGoroutineLeaks(t)()
+
+	wheel := NewWheel(TimeMillisecondDuration(10), 20)
+	<-wheel.After(TimeMillisecondDuration(20))
+	wheel.Stop()
+	wheel.Stop()
+}
+
 // output:
 // timer costs: 30001 ms
 // --- PASS: TestNewWheel (100.00s)